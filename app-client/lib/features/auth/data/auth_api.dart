@@ -33,10 +33,10 @@ class AuthApi {
     });
   }
 
-  Future<void> deleteFcmToken() async {
-    // Send empty string to clear token on backend
-    await _dio.post('/api/users/fcm-token', data: {
-      'fcm_token': ''
+  Future<void> deleteFcmToken(String token) async {
+    // Unregister only this device; the user's other devices keep receiving alerts
+    await _dio.delete('/api/users/me/devices', data: {
+      'token': token,
     });
   }
 }
@@ -83,8 +83,10 @@ class AuthRepoImpl implements AuthRepo {
 
   @override
   Future<void> deleteFcmToken() async {
+    final token = _fcm;
     _fcm = null;
-    await api.deleteFcmToken();
+    if (token == null) return;
+    await api.deleteFcmToken(token);
   }
 }
 
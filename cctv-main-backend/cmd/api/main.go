@@ -1,16 +1,26 @@
 package main
 
 import (
+	acl "cctv-main-backend/internal/access"
 	"cctv-main-backend/internal/anomaly"
 	"cctv-main-backend/internal/camera"
 	"cctv-main-backend/internal/company"
 	"cctv-main-backend/internal/domain"
 	"cctv-main-backend/internal/handlers"
+	"cctv-main-backend/internal/notification"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/internal/schedule"
+	"cctv-main-backend/internal/session"
+	"cctv-main-backend/internal/site"
 	"cctv-main-backend/internal/storage"
 	"cctv-main-backend/internal/user"
+	"cctv-main-backend/internal/webhook"
 	"cctv-main-backend/pkg/auth"
 	"cctv-main-backend/pkg/database"
+	"cctv-main-backend/pkg/events"
+	"cctv-main-backend/pkg/mediamtx"
 	"cctv-main-backend/pkg/notifier"
+	"cctv-main-backend/pkg/push"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -20,28 +30,67 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
-    "golang.org/x/crypto/bcrypt"
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
-	db := database.NewConnection()
-	database.Migrate(db)
+	dbCfg, err := database.LoadConfig()
+	if err != nil {
+		log.Fatalf("Konfigurasi database tidak valid: %v", err)
+	}
+	db, err := database.NewConnection(context.Background(), dbCfg)
+	if err != nil {
+		log.Fatalf("Gagal terhubung ke database: %v", err)
+	}
 	defer db.Close()
 
+	// Subcommand: ./main migrate status|up|down [N]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrateCommand(db, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
+	// Migrasi otomatis saat startup (aman untuk banyak replika karena advisory lock).
+	// Set DB_AUTO_MIGRATE=false untuk menjalankannya manual via subcommand.
+	if getEnv("DB_AUTO_MIGRATE", "true") != "false" {
+		if err := database.Migrate(db); err != nil {
+			log.Fatalf("Migrasi database gagal: %v", err)
+		}
+	}
+
 	// Seed optional superadmin if env provided
 	ensureSuperadmin(db)
 
 	mux := http.NewServeMux()
 
+	// Zona waktu perusahaan untuk jadwal deteksi dan waktu di email.
+	appLoc := appLocation()
+
 	// repos
 	anomalyRepo := anomaly.NewRepository(db)
 	userRepo := user.NewRepository(db)
 	companyRepo := company.NewRepository(db)
 	cameraRepo := camera.NewRepository(db)
+	sessionRepo := session.NewRepository(db)
+	policyRepo := policy.NewRepository(db)
+
+	// Penerima push/email disaring preferensi notifikasi tiap user (jam tenang memakai APP_TZ).
+	recipients := user.NewRecipients(userRepo, appLoc)
+
+	// Judul/isi push per bahasa user; PUSH_TEMPLATE_DIR (id.tmpl, en.tmpl) menggantikan template bawaan.
+	pushTemplates, err := notifier.NewPushTemplates(os.Getenv("PUSH_TEMPLATE_DIR"), appLoc)
+	if err != nil {
+		log.Printf("template push: %v; pakai template bawaan", err)
+		if pushTemplates, err = notifier.NewPushTemplates("", appLoc); err != nil {
+			log.Fatalf("template push bawaan: %v", err)
+		}
+	}
 
 	// notifier: jika ada PUSH_SERVICE_URL gunakan HTTPNotifier, kalau tidak fallback FCM/log
 	var n notifier.Notifier
@@ -51,27 +100,27 @@ func main() {
 		if err != nil {
 			log.Println("HTTPNotifier init error, fallback ke FCM/log:", err)
 		} else {
-			httpN.GetAdminTokens = userRepo.GetFCMTokensByCompanyAllRoles
+			httpN.GetRecipientTargets = recipients.PushTargets
 			httpN.GetCompanyIDByCameraID = cameraRepo.GetCompanyIDByCameraID
+			httpN.DeleteToken = userRepo.DeleteFCMTokenByValue
+			httpN.GetCamera = cameraRepo.GetCameraByID
+			httpN.Templates = pushTemplates
 			n = httpN
 			log.Println("Notifier: HTTP push-service")
 		}
 	}
 	if n == nil {
-		if cred := os.Getenv("FIREBASE_CREDENTIALS"); cred != "" {
-			fcm, err := notifier.NewFCM(context.Background(), cred)
-			if err != nil {
-				log.Println("FCM init error, fallback ke log:", err)
-				n = notifier.NewLogNotifier()
-			} else {
-				fcm.GetAdminTokens = userRepo.GetFCMTokensByCompanyAllRoles
-				fcm.GetCompanyIDByCameraID = cameraRepo.GetCompanyIDByCameraID
-				fcm.DeleteToken = userRepo.DeleteFCMTokenByValue
-				fcm.UseTopic = false
-				fcm.TopicPrefix = "alerts"
-				n = fcm
-				log.Println("Notifier: FCM (direct-to-token)")
-			}
+		if senders := pushSenders(); len(senders) > 0 {
+			pushN := notifier.NewPush(senders)
+			pushN.GetRecipientTargets = recipients.PushTargets
+			pushN.GetCompanyIDByCameraID = cameraRepo.GetCompanyIDByCameraID
+			pushN.DeleteToken = userRepo.DeleteFCMTokenByValue
+			pushN.GetCamera = cameraRepo.GetCameraByID
+			pushN.Templates = pushTemplates
+			pushN.UseTopic = false
+			pushN.TopicPrefix = "alerts"
+			n = pushN
+			log.Println("Notifier: push langsung (direct-to-token)")
 		} else {
 			n = notifier.NewLogNotifier()
 			log.Println("Notifier: LOG (fallback)")
@@ -93,50 +142,224 @@ func main() {
 	if err := s3u.EnsureBucket(context.Background(), bucketArchive); err != nil {
 		log.Printf("ensure bucket %s: %v", bucketArchive, err)
 	}
-	recHandler := handlers.NewRecordingHandler(db, s3u, bucketArchive)
+	accessService := acl.NewService(acl.NewRepository(db))
+	accessHandler := acl.NewHandler(accessService)
+
+	recHandler := handlers.NewRecordingHandler(db, s3u, bucketArchive, accessService)
 	clipsBucket := getEnv("MINIO_BUCKET", "video-clips")
 
 	// services + handlers
-	anomalyService := anomaly.NewService(anomalyRepo, n)
-	anomalyHandler := anomaly.NewHandler(anomalyService, s3u, clipsBucket)
+	// Hub pub/sub in-process untuk /api/events (SSE)
+	eventHub := events.NewHub(64)
+
+	// Webhook keluar: notifier hanya mengantrekan, dispatcher mengirim di background.
+	webhookRepo := webhook.NewRepository(db)
+	webhookService := webhook.NewService(webhookRepo)
+	webhookHandler := webhook.NewHandler(webhookService)
+	go webhook.NewDispatcher(webhookRepo, getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true").Run(context.Background())
+	webhookNotifier := &notifier.WebhookNotifier{
+		Enqueue:                webhookService.Enqueue,
+		GetCompanyIDByCameraID: cameraRepo.GetCompanyIDByCameraID,
+	}
+
+	// Semua channel dikirim paralel; aturan per perusahaan memilih channel mana yang dipakai.
+	multi := notifier.NewMultiNotifier(getEnvDuration("NOTIFY_CHANNEL_TIMEOUT", 10*time.Second))
+	multi.Add("push", n)
+	multi.Add("webhook", webhookNotifier)
+	notificationRepo := notification.NewRepository(db)
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		if emailN, err := newEmailNotifier(host, appLoc, s3u, clipsBucket); err != nil {
+			log.Println("Email notifier init error, channel email dinonaktifkan:", err)
+		} else {
+			emailN.GetRecipients = recipients.EmailRecipients
+			emailN.GetCompanyIDByCameraID = cameraRepo.GetCompanyIDByCameraID
+			emailN.GetCamera = cameraRepo.GetCameraByID
+			emailN.Digests = notificationRepo
+			go emailN.RunDigest(context.Background())
+			multi.Add("email", emailN)
+			log.Println("Notifier: email via SMTP", host)
+		}
+	}
+	notificationService := notification.NewService(notificationRepo, multi.Channels())
+	notificationHandler := notification.NewHandler(notificationService)
+	multi.Route = notificationService.Route
+
+	// Outbox: anomali dan baris outbox ditulis dalam satu transaksi, lalu dikirim worker di background.
+	outbox := notification.NewDispatcher(notificationRepo, multi, func(ctx context.Context, id int64) (*domain.AnomalyReport, error) {
+		return anomalyRepo.GetByIDForCompany(0, domain.CameraScope{All: true}, id)
+	})
+	outbox.Workers = getEnvInt("NOTIFY_WORKERS", 4)
+	go outbox.Run(context.Background())
+
+	// Control API MediaMTX (opsional) untuk monitor status dan provisioning path kamera;
+	// cukup satu replika yang menjalankannya.
+	var mtx *mediamtx.Client
+	if apiURL := os.Getenv("MEDIAMTX_API_URL"); apiURL != "" {
+		mtx = mediamtx.NewClient(apiURL, os.Getenv("MEDIAMTX_API_USER"), os.Getenv("MEDIAMTX_API_PASS"))
+		monitor := camera.NewMonitor(cameraRepo, mtx)
+		monitor.Interval = getEnvDuration("CAMERA_MONITOR_INTERVAL", 15*time.Second)
+		monitor.Grace = getEnvDuration("CAMERA_OFFLINE_GRACE", 2*time.Minute)
+		monitor.Publish = eventHub
+		monitor.Wake = outbox.Wake
+		monitor.Enqueue = webhookService.Enqueue
+		go monitor.Run(context.Background())
+		log.Println("Camera monitor: MediaMTX", apiURL)
+	}
+
+	// Laporan berulang per kamera + tipe dalam cooldown dilipat ke satu insiden (tanpa notifikasi baru).
+	cooldown := anomaly.Cooldown{
+		Default: getEnvDuration("ALERT_COOLDOWN", 5*time.Minute),
+		ByType:  getEnvDurationMap("ALERT_COOLDOWN_BY_TYPE"),
+	}
+	scheduleService := schedule.NewService(schedule.NewRepository(db), appLoc)
+	scheduleHandler := schedule.NewHandler(scheduleService)
+	anomalyService := anomaly.NewService(anomalyRepo, outbox, eventHub, cooldown, scheduleService, webhookService)
+	anomalyHandler := anomaly.NewHandler(anomalyService, s3u, clipsBucket, accessService)
+
+	jwtKeys, err := auth.LoadKeySet()
+	if err != nil {
+		log.Fatalf("Kunci JWT tidak valid: %v", err)
+	}
+	sessionService := session.NewService(sessionRepo, getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour))
+	policyService := policy.NewService(policyRepo)
+	policyHandler := policy.NewHandler(policyService)
+	authMiddleware := newAuthMiddleware(jwtKeys, sessionService, policyService)
+	streamTickets := auth.NewTickets(getEnvDuration("STREAM_TICKET_TTL", 30*time.Second))
+	streamAuthMiddleware := newStreamAuthMiddleware(streamTickets, sessionService, policyService, authMiddleware)
+	eventsHandler := handlers.NewEventsHandler(eventHub, accessService, sessionService, streamTickets)
 
-	userService := user.NewService(userRepo)
-	userHandler := user.NewHandler(userService)
+	userService := user.NewService(userRepo, sessionService, jwtKeys, getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute))
+	userHandler := user.NewHandler(userService, policyService)
 
 	companyService := company.NewService(companyRepo)
 	companyHandler := company.NewHandler(companyService)
 
-	cameraService := camera.NewService(cameraRepo)
-	cameraHandler := camera.NewHandler(cameraService)
+	var paths camera.PathProvisioner
+	if mtx != nil {
+		provisioner := camera.NewProvisioner(cameraRepo, mtx)
+		provisioner.Interval = getEnvDuration("MEDIAMTX_SYNC_INTERVAL", time.Minute)
+		go provisioner.Run(context.Background())
+		paths = provisioner
+	}
+	cameraService := camera.NewService(cameraRepo, paths)
+	// Callback authMethod http MediaMTX: kredensial per kamera dari database.
+	streamAuth := camera.NewStreamAuth(cameraRepo)
+	streamAuth.APIUser = os.Getenv("MEDIAMTX_API_USER")
+	streamAuth.APIPass = os.Getenv("MEDIAMTX_API_PASS")
+	streamAuth.InternalUser = os.Getenv("MEDIAMTX_INTERNAL_USER")
+	streamAuth.InternalPass = os.Getenv("MEDIAMTX_INTERNAL_PASS")
+	streamAuth.Secret = os.Getenv("MEDIAMTX_AUTH_SECRET")
+	cameraHandler := camera.NewHandler(cameraService, accessService, scheduleService)
+
+	siteHandler := site.NewHandler(site.NewService(site.NewRepository(db)))
 
 	// routes (sama seperti punyamu)
-	// Protect register: only authenticated callers can create users (enforced per-role in handler)
-	mux.HandleFunc("/api/register", authMiddleware(userHandler.Register))
+	// Protect register: only callers with user:manage can create users (company scoping in handler)
+	mux.HandleFunc("/api/register", authMiddleware(RequirePermission(policy.UserManage, userHandler.Register)))
 	mux.HandleFunc("/api/login", userHandler.Login)
-	mux.HandleFunc("/api/users", authMiddleware(userHandler.GetAllUsers))
+	mux.HandleFunc("/api/auth/refresh", userHandler.Refresh)
+	mux.HandleFunc("/api/auth/logout", authMiddleware(userHandler.Logout))
+	// Kunci publik (RS256/EdDSA) untuk service lain yang memverifikasi access token
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(jwtKeys.JWKS())
+	})
+	mux.HandleFunc("/api/users", authMiddleware(RequirePermission(policy.UserRead, userHandler.GetAllUsers)))
 	mux.HandleFunc("/api/users/fcm-token", authMiddleware(userHandler.UpdateFCMToken))
+	mux.HandleFunc("/api/users/me/email-alerts", authMiddleware(userHandler.EmailAlerts))
+	mux.HandleFunc("/api/users/me/locale", authMiddleware(userHandler.Locale))
+	mux.HandleFunc("/api/users/me/notification-settings", authMiddleware(userHandler.NotificationSettings))
+	mux.HandleFunc("/api/users/me/devices", authMiddleware(userHandler.Devices))
+	mux.HandleFunc("/api/users/me/devices/", authMiddleware(userHandler.DeleteDevice))
 	mux.HandleFunc("/api/users/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// /api/users/{id}/cameras → akses kamera per user
+		if strings.HasSuffix(r.URL.Path, "/cameras") {
+			switch r.Method {
+			case http.MethodGet:
+				RequirePermission(policy.UserManage, accessHandler.GetUserAccess)(w, r)
+			case http.MethodPut:
+				RequirePermission(policy.UserManage, accessHandler.SetUserAccess)(w, r)
+			default:
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		switch r.Method {
 		case http.MethodPut:
-			userHandler.UpdateUserRole(w, r)
+			RequirePermission(policy.UserManage, userHandler.UpdateUserRole)(w, r)
 		case http.MethodDelete:
-			userHandler.DeleteUser(w, r)
+			RequirePermission(policy.UserManage, userHandler.DeleteUser)(w, r)
 		default:
 			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
 		}
 	}))
 
 	mux.HandleFunc("/api/report-anomaly", anomalyHandler.CreateReport)
-	mux.HandleFunc("/api/anomalies", authMiddleware(anomalyHandler.GetAllReports))
-	mux.HandleFunc("/api/anomalies/", authMiddleware(anomalyHandler.GetDetail))
-	mux.HandleFunc("/api/anomalies/recent", authMiddleware(anomalyHandler.GetRecent))
+	mux.HandleFunc("/api/anomalies", authMiddleware(RequirePermission(policy.AnomalyRead, anomalyHandler.GetAllReports)))
+	mux.HandleFunc("/api/anomalies/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// /api/anomalies/{id}/notifications → status kirim notifikasi per penerima
+		if strings.HasSuffix(r.URL.Path, "/notifications") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+				return
+			}
+			RequirePermission(policy.NotificationManage, notificationHandler.GetAnomalyNotifications)(w, r)
+			return
+		}
+		// /api/anomalies/{id}/history → riwayat penanganan
+		if strings.HasSuffix(r.URL.Path, "/history") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+				return
+			}
+			RequirePermission(policy.AnomalyRead, anomalyHandler.History)(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.AnomalyRead, anomalyHandler.GetDetail)(w, r)
+		case http.MethodPost:
+			// /api/anomalies/{id}/{aksi} → acknowledge, start, resolve, false-positive, reopen, assign
+			RequirePermission(policy.AnomalyWrite, anomalyHandler.Transition)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/anomalies/recent", authMiddleware(RequirePermission(policy.AnomalyRead, anomalyHandler.GetRecent)))
+	mux.HandleFunc("/api/events", streamAuthMiddleware(RequirePermission(policy.AnomalyRead, eventsHandler.Stream)))
+	mux.HandleFunc("/api/events/ticket", authMiddleware(RequirePermission(policy.AnomalyRead, eventsHandler.Ticket)))
+	mux.HandleFunc("/api/anomalies/stats", authMiddleware(RequirePermission(policy.AnomalyRead, anomalyHandler.GetStats)))
+
+	mux.HandleFunc("/api/roles", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.RoleManage, policyHandler.ListRoles)(w, r)
+		case http.MethodPost:
+			RequirePermission(policy.RoleManage, policyHandler.CreateRole)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/roles/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			RequirePermission(policy.RoleManage, policyHandler.UpdateRole)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.RoleManage, policyHandler.DeleteRole)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
 
 	mux.HandleFunc("/api/companies", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
-			RequireRole("superadmin", companyHandler.CreateCompany)(w, r)
+			RequirePermission(policy.CompanyManage, companyHandler.CreateCompany)(w, r)
 		case http.MethodGet:
-			RequireRole("superadmin", companyHandler.GetAllCompanies)(w, r)
+			RequirePermission(policy.CompanyManage, companyHandler.GetAllCompanies)(w, r)
 		default:
 			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
 		}
@@ -145,9 +368,9 @@ func main() {
 	mux.HandleFunc("/api/companies/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPut:
-			RequireRole("superadmin", companyHandler.UpdateCompany)(w, r)
+			RequirePermission(policy.CompanyManage, companyHandler.UpdateCompany)(w, r)
 		case http.MethodDelete:
-			RequireRole("superadmin", companyHandler.DeleteCompany)(w, r)
+			RequirePermission(policy.CompanyManage, companyHandler.DeleteCompany)(w, r)
 		default:
 			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
 		}
@@ -160,16 +383,16 @@ func main() {
 				http.Error(w, "Method not allowed for recordings", http.StatusMethodNotAllowed)
 				return
 			}
-			recHandler.ListRecordings(w, r) // handler yang kita buat sebelumnya
+			RequirePermission(policy.RecordingRead, recHandler.ListRecordings)(w, r)
 			return
 		}
 
 		// /api/cameras/{id} → update/hapus kamera
 		switch r.Method {
 		case http.MethodPut:
-			cameraHandler.UpdateCamera(w, r)
+			RequirePermission(policy.CameraWrite, cameraHandler.UpdateCamera)(w, r)
 		case http.MethodDelete:
-			cameraHandler.DeleteCamera(w, r)
+			RequirePermission(policy.CameraDelete, cameraHandler.DeleteCamera)(w, r)
 		default:
 			http.Error(w, "Metode tidak diizinkan di rute ini", http.StatusMethodNotAllowed)
 		}
@@ -178,16 +401,167 @@ func main() {
 	mux.HandleFunc("/api/cameras", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
-			cameraHandler.CreateCamera(w, r)
+			RequirePermission(policy.CameraWrite, cameraHandler.CreateCamera)(w, r)
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, cameraHandler.GetCameras)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/camera-groups", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, accessHandler.ListGroups)(w, r)
+		case http.MethodPost:
+			RequirePermission(policy.CameraWrite, accessHandler.CreateGroup)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/camera-groups/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			RequirePermission(policy.CameraWrite, accessHandler.UpdateGroup)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.CameraWrite, accessHandler.DeleteGroup)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/detection-schedules", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, scheduleHandler.List)(w, r)
+		case http.MethodPost:
+			RequirePermission(policy.CameraWrite, scheduleHandler.Create)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/detection-schedules/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, scheduleHandler.Get)(w, r)
+		case http.MethodPut:
+			RequirePermission(policy.CameraWrite, scheduleHandler.Update)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.CameraWrite, scheduleHandler.Delete)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/sites", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, siteHandler.ListSites)(w, r)
+		case http.MethodPost:
+			RequirePermission(policy.CameraWrite, siteHandler.CreateSite)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/sites/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// /api/sites/{id}/zones → tambah zone
+		if strings.HasSuffix(r.URL.Path, "/zones") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+				return
+			}
+			RequirePermission(policy.CameraWrite, siteHandler.CreateZone)(w, r)
+			return
+		}
+		// /api/sites/{id}/staff → staf penerima notifikasi site
+		if strings.HasSuffix(r.URL.Path, "/staff") {
+			switch r.Method {
+			case http.MethodGet:
+				RequirePermission(policy.UserRead, siteHandler.GetStaff)(w, r)
+			case http.MethodPut:
+				RequirePermission(policy.UserManage, siteHandler.SetStaff)(w, r)
+			default:
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.CameraRead, siteHandler.GetSite)(w, r)
+		case http.MethodPut:
+			RequirePermission(policy.CameraWrite, siteHandler.UpdateSite)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.CameraWrite, siteHandler.DeleteSite)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/zones/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			RequirePermission(policy.CameraWrite, siteHandler.UpdateZone)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.CameraWrite, siteHandler.DeleteZone)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/notification-routes", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.NotificationManage, notificationHandler.GetRoutes)(w, r)
+		case http.MethodPut:
+			RequirePermission(policy.NotificationManage, notificationHandler.SetRoutes)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/webhooks", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			RequirePermission(policy.NotificationManage, webhookHandler.List)(w, r)
+		case http.MethodPost:
+			RequirePermission(policy.NotificationManage, webhookHandler.Create)(w, r)
+		default:
+			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/webhooks/", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// /api/webhooks/{id}/deliveries/{deliveryID}/redeliver → kirim ulang
+		if strings.HasSuffix(r.URL.Path, "/redeliver") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+				return
+			}
+			RequirePermission(policy.NotificationManage, webhookHandler.Redeliver)(w, r)
+			return
+		}
+		// /api/webhooks/{id}/deliveries → log pengiriman
+		if strings.HasSuffix(r.URL.Path, "/deliveries") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+				return
+			}
+			RequirePermission(policy.NotificationManage, webhookHandler.ListDeliveries)(w, r)
+			return
+		}
+
+		switch r.Method {
 		case http.MethodGet:
-			cameraHandler.GetCameras(w, r)
+			RequirePermission(policy.NotificationManage, webhookHandler.Get)(w, r)
+		case http.MethodPut:
+			RequirePermission(policy.NotificationManage, webhookHandler.Update)(w, r)
+		case http.MethodDelete:
+			RequirePermission(policy.NotificationManage, webhookHandler.Delete)(w, r)
 		default:
 			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
 		}
 	}))
 
 	// Test notification endpoint: send push for given anomaly_id or latest anomaly in company
-    mux.HandleFunc("/api/notifications/test", authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/notifications/test", authMiddleware(RequirePermission(policy.NotificationManage, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
 			return
@@ -202,8 +576,7 @@ func main() {
 			return
 		}
 		var companyID int64
-		role, _ := claims["role"].(string)
-		if strings.EqualFold(role, "superadmin") {
+		if policy.Has(r.Context(), policy.CompanyManage) {
 			companyID = 0
 		} else {
 			cID, ok := claims["company_id"].(float64)
@@ -213,59 +586,59 @@ func main() {
 			}
 			companyID = int64(cID)
 		}
-		var payload struct{
+		var payload struct {
 			AnomalyID int64 `json:"anomaly_id"`
 		}
 		_ = json.NewDecoder(r.Body).Decode(&payload)
-        var rep *domain.AnomalyReport
-        if payload.AnomalyID > 0 {
-            if x, err := anomalyService.GetDetail(companyID, payload.AnomalyID); err == nil {
-                rep = x
-            } else {
-                http.Error(w, "Anomali tidak ditemukan", http.StatusNotFound)
-                return
-            }
-        } else {
-            if list, err := anomalyService.ListRecent(companyID, 1); err == nil && len(list) > 0 {
-                rep = &list[0]
-            }
-            if rep == nil {
-                http.Error(w, "Tidak ada anomaly untuk perusahaan ini", http.StatusBadRequest)
-                return
-            }
-        }
-        if rep.AnomalyType == "" {
-            rep.AnomalyType = "anomaly"
-        }
-        // Debug: tentukan target company berdasarkan camera id
-        targetCompanyID := companyID
-        if rep.CameraID > 0 {
-            if cid, err := cameraRepo.GetCompanyIDByCameraID(r.Context(), rep.CameraID); err == nil {
-                targetCompanyID = cid
-            }
-        }
-        // Ambil tokens untuk info
-        tokens := []string{}
-        if userRepo != nil {
-            if list, err := userRepo.GetFCMTokensByCompanyAllRoles(r.Context(), targetCompanyID); err == nil {
-                tokens = list
-            }
-        }
-        ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-        defer cancel()
-        if err := n.NotifyAnomaly(ctx, rep); err != nil {
-            http.Error(w, "Gagal mengirim notifikasi", http.StatusBadGateway)
-            return
-        }
-        w.Header().Set("Content-Type", "application/json")
-        _ = json.NewEncoder(w).Encode(map[string]any{
-            "ok": true,
-            "anomaly_id": rep.ID,
-            "camera_id": rep.CameraID,
-            "company_id": targetCompanyID,
-            "tokens_count": len(tokens),
-        })
-    }))
+		var rep *domain.AnomalyReport
+		if payload.AnomalyID > 0 {
+			if x, err := anomalyService.GetDetail(companyID, domain.CameraScope{All: true}, payload.AnomalyID); err == nil {
+				rep = x
+			} else {
+				http.Error(w, "Anomali tidak ditemukan", http.StatusNotFound)
+				return
+			}
+		} else {
+			if list, err := anomalyService.ListRecent(companyID, domain.CameraScope{All: true}, domain.LocationFilter{}, 1); err == nil && len(list) > 0 {
+				rep = &list[0]
+			}
+			if rep == nil {
+				http.Error(w, "Tidak ada anomaly untuk perusahaan ini", http.StatusBadRequest)
+				return
+			}
+		}
+		if rep.AnomalyType == "" {
+			rep.AnomalyType = "anomaly"
+		}
+		// Debug: tentukan target company berdasarkan camera id
+		targetCompanyID := companyID
+		if rep.CameraID > 0 {
+			if cid, err := cameraRepo.GetCompanyIDByCameraID(r.Context(), rep.CameraID); err == nil {
+				targetCompanyID = cid
+			}
+		}
+		// Jumlah perangkat yang akan menerima push, dengan aturan yang sama seperti
+		// notifier (ACL kamera, staf site, preferensi notifikasi).
+		targets, err := recipients.PushTargets(r.Context(), targetCompanyID, rep)
+		if err != nil {
+			http.Error(w, "Gagal mengambil penerima notifikasi", http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if err := n.NotifyAnomaly(ctx, rep); err != nil {
+			http.Error(w, "Gagal mengirim notifikasi", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"ok":           true,
+			"anomaly_id":   rep.ID,
+			"camera_id":    rep.CameraID,
+			"company_id":   targetCompanyID,
+			"tokens_count": len(targets),
+		})
+	})))
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -387,9 +760,9 @@ func main() {
 			"mem_alloc":  msnap.Alloc,
 			"mem_sys":    msnap.Sys,
 		}
-        if d, ok := getDiskStats(); ok {
-            resp["disk"] = d
-        }
+		if d, ok := getDiskStats(); ok {
+			resp["disk"] = d
+		}
 
 		// Build info from env
 		resp["build"] = map[string]string{
@@ -416,6 +789,18 @@ func main() {
 		json.NewEncoder(w).Encode(resp)
 	})
 
+	// Listener internal untuk callback MediaMTX; hanya untuk jaringan docker,
+	// jangan dipublikasikan ke host.
+	internalMux := http.NewServeMux()
+	internalMux.HandleFunc("/internal/mediamtx/auth", streamAuth.Authorize)
+	internalAddr := getEnv("INTERNAL_ADDR", ":8082")
+	go func() {
+		if err := http.ListenAndServe(internalAddr, internalMux); err != nil {
+			log.Fatal("Gagal memulai listener internal:", err)
+		}
+	}()
+	log.Println("Listener internal (callback MediaMTX) di", internalAddr)
+
 	port := "8080"
 	fmt.Printf("Server berjalan di http://localhost:%s\n", port)
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
@@ -423,6 +808,92 @@ func main() {
 	}
 }
 
+// pushSenders menyiapkan transport push langsung per provider. PUSH_SINK
+// ("memory" atau path file JSON Lines) menggantikan FCM dan APNs untuk
+// pengembangan lokal; selain itu FCM aktif bila FIREBASE_CREDENTIALS diisi dan
+// APNs bila APNS_KEY_FILE diisi.
+func pushSenders() map[string]push.Sender {
+	senders := map[string]push.Sender{}
+	if sink := os.Getenv("PUSH_SINK"); sink != "" {
+		path := sink
+		if sink == "memory" {
+			path = ""
+		}
+		s := push.NewSink(path)
+		senders[domain.PushProviderFCM] = s
+		senders[domain.PushProviderAPNs] = s
+		log.Printf("Push transport: sink (%s)", sink)
+		return senders
+	}
+	if cred := os.Getenv("FIREBASE_CREDENTIALS"); cred != "" {
+		fcm, err := push.NewFCM(context.Background(), cred)
+		if err != nil {
+			log.Println("FCM init error:", err)
+		} else {
+			senders[domain.PushProviderFCM] = fcm
+			log.Println("Push transport: FCM")
+		}
+	}
+	if keyFile := os.Getenv("APNS_KEY_FILE"); keyFile != "" {
+		apns, err := push.NewAPNs(push.APNsConfig{
+			KeyFile: keyFile,
+			KeyID:   os.Getenv("APNS_KEY_ID"),
+			TeamID:  os.Getenv("APNS_TEAM_ID"),
+			Topic:   os.Getenv("APNS_TOPIC"),
+			Sandbox: getEnv("APNS_SANDBOX", "false") == "true",
+		})
+		if err != nil {
+			log.Println("APNs init error:", err)
+		} else {
+			senders[domain.PushProviderAPNs] = apns
+			log.Println("Push transport: APNs")
+		}
+	}
+	return senders
+}
+
+// newEmailNotifier membaca konfigurasi SMTP dari env. Tautan klip di email
+// dipresign lebih lama (EMAIL_CLIP_TTL) karena email sering dibuka belakangan.
+func newEmailNotifier(host string, loc *time.Location, s3u *storage.S3Util, clipsBucket string) (*notifier.Email, error) {
+	port, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	emailN, err := notifier.NewEmail(notifier.EmailConfig{
+		Host:           host,
+		Port:           port,
+		Username:       os.Getenv("SMTP_USERNAME"),
+		Password:       os.Getenv("SMTP_PASSWORD"),
+		From:           os.Getenv("SMTP_FROM"),
+		TemplateDir:    os.Getenv("EMAIL_TEMPLATE_DIR"),
+		DigestInterval: getEnvDuration("EMAIL_DIGEST_INTERVAL", 15*time.Minute),
+		Location:       loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	clipTTL := getEnvDuration("EMAIL_CLIP_TTL", 24*time.Hour)
+	emailN.PresignClip = func(clipURL string) (string, error) {
+		bkt, key, ok := storage.SplitObjectURL(clipURL)
+		if !ok {
+			return "", fmt.Errorf("url klip tidak dikenali: %s", clipURL)
+		}
+		if bkt == "" {
+			bkt = clipsBucket
+		}
+		return s3u.Presign(bkt, key, clipTTL)
+	}
+	return emailN, nil
+}
+
+// appLocation memuat APP_TZ (nama zona IANA, mis. Asia/Jakarta); tidak valid → UTC.
+func appLocation() *time.Location {
+	name := getEnv("APP_TZ", "UTC")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("APP_TZ tidak valid (%q), pakai UTC: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
 func getEnv(key, def string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -430,13 +901,54 @@ func getEnv(key, def string) string {
 	return def
 }
 
+func getEnvInt(key string, def int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("%s tidak valid (%q), pakai default %d", key, val, def)
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+		log.Printf("%s tidak valid (%q), pakai default %s", key, val, def)
+	}
+	return def
+}
+
+// getEnvDurationMap membaca daftar "key=durasi" dipisah koma, mis. "fight=1m,loitering=15m".
+func getEnvDurationMap(key string) map[string]time.Duration {
+	m := map[string]time.Duration{}
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			if strings.TrimSpace(item) != "" {
+				log.Printf("%s: entri %q diabaikan", key, item)
+			}
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			log.Printf("%s: durasi %q tidak valid, entri %q diabaikan", key, v, k)
+			continue
+		}
+		m[strings.TrimSpace(k)] = d
+	}
+	return m
+}
+
 // ensureSuperadmin creates or elevates a superadmin account if env vars are set
 func ensureSuperadmin(db *sql.DB) {
-    email := os.Getenv("SUPERADMIN_EMAIL")
-    password := os.Getenv("SUPERADMIN_PASSWORD")
-    if email == "" || password == "" {
-        return
-    }
+	email := os.Getenv("SUPERADMIN_EMAIL")
+	password := os.Getenv("SUPERADMIN_PASSWORD")
+	if email == "" || password == "" {
+		return
+	}
 
 	// Check if user exists
 	var exists bool
@@ -445,20 +957,20 @@ func ensureSuperadmin(db *sql.DB) {
 		log.Println("seed superadmin check error:", err)
 		return
 	}
-    if exists {
-        // Elevate role to superadmin and optionally reset password if env provided
-        hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-        if err != nil {
-            log.Println("bcrypt error:", err)
-            return
-        }
-        if _, err := db.Exec("UPDATE users SET role='superadmin', password_hash=$2 WHERE email=$1", email, string(hash)); err != nil {
-            log.Println("seed superadmin elevate/update error:", err)
-        } else {
-            log.Println("Superadmin elevated & password updated:", email)
-        }
-        return
-    }
+	if exists {
+		// Elevate role to superadmin and optionally reset password if env provided
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Println("bcrypt error:", err)
+			return
+		}
+		if _, err := db.Exec("UPDATE users SET role='superadmin', password_hash=$2 WHERE email=$1", email, string(hash)); err != nil {
+			log.Println("seed superadmin elevate/update error:", err)
+		} else {
+			log.Println("Superadmin elevated & password updated:", email)
+		}
+		return
+	}
 
 	// Create a system company if not exists
 	var companyID int64
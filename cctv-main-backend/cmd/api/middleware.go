@@ -1,6 +1,8 @@
 package main
 
 import (
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/internal/session"
 	"cctv-main-backend/pkg/auth"
 	"context"
 	"net/http"
@@ -9,50 +11,99 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("kunci-rahasia-yang-sangat-aman-dan-panjang")
-
-func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-			return
-		}
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			http.Error(w, "Token format is invalid", http.StatusUnauthorized)
-			return
-		}
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrAbortHandler
+// newAuthMiddleware memvalidasi JWT terhadap KeySet (berdasarkan "kid"), memastikan
+// sesi (claim "sid") belum dicabut lewat logout, perubahan peran, atau penghapusan
+// user, lalu memasang permission peran pemanggil ke context.
+func newAuthMiddleware(keys *auth.KeySet, sessions session.Service, roles policy.Service) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				http.Error(w, "Authorization header missing", http.StatusUnauthorized)
+				return
 			}
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			ctx := context.WithValue(r.Context(), auth.UserClaimsKey, claims)
-			next(w, r.WithContext(ctx))
-			return
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				http.Error(w, "Token format is invalid", http.StatusUnauthorized)
+				return
+			}
+			claims, err := keys.Parse(tokenString)
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			authorize(w, r, claims, sessions, roles, next)
 		}
-		http.Error(w, "Invalid token claims", http.StatusUnauthorized)
 	}
 }
 
-func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+// authorize memastikan sesi (claim "sid") belum dicabut lewat logout, perubahan
+// peran, atau penghapusan user, lalu memasang claims dan permission peran
+// pemanggil ke context sebelum memanggil next.
+func authorize(w http.ResponseWriter, r *http.Request, claims jwt.MapClaims, sessions session.Service, roles policy.Service, next http.HandlerFunc) {
+	// Token tanpa sid berasal dari sebelum ada sesi dan tidak bisa dicabut: tolak.
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	active, err := sessions.IsActive(r.Context(), int64(sid))
+	if err != nil {
+		http.Error(w, "Gagal memeriksa sesi", http.StatusInternalServerError)
+		return
+	}
+	if !active {
+		http.Error(w, "Session revoked", http.StatusUnauthorized)
+		return
+	}
+	// Resolve peran (bawaan atau kustom perusahaan) menjadi permission untuk RequirePermission.
+	role, _ := claims["role"].(string)
+	companyID, _ := claims["company_id"].(float64)
+	perms, err := roles.Resolve(r.Context(), role, int64(companyID))
+	if err != nil {
+		http.Error(w, "Gagal memeriksa peran", http.StatusInternalServerError)
+		return
+	}
+	ctx := context.WithValue(r.Context(), auth.UserClaimsKey, claims)
+	ctx = policy.WithPermissions(ctx, perms)
+	next(w, r.WithContext(ctx))
+}
+
+// RequirePermission menolak request bila peran pemanggil tidak punya perm.
+// Harus dipasang di dalam authMiddleware.
+func RequirePermission(perm policy.Permission, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, ok := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
-		if !ok {
+		if _, ok := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims); !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		if claims["role"] != role {
-			http.Error(w, "Forbidden (role)", http.StatusForbidden)
+		if !policy.Has(r.Context(), perm) {
+			http.Error(w, "Forbidden ("+string(perm)+")", http.StatusForbidden)
 			return
 		}
 		next(w, r)
 	}
 }
+
+// newStreamAuthMiddleware dipakai endpoint streaming: request dengan ?ticket=
+// (dari POST /api/events/ticket) diautentikasi dengan tiket sekali pakai karena
+// EventSource di browser tidak bisa mengirim header; request lain lewat
+// authMiddleware biasa.
+func newStreamAuthMiddleware(tickets *auth.Tickets, sessions session.Service, roles policy.Service, authMiddleware func(http.HandlerFunc) http.HandlerFunc) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		withHeader := authMiddleware(next)
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.URL.Query().Get("ticket")
+			if id == "" {
+				withHeader(w, r)
+				return
+			}
+			claims, ok := tickets.Redeem(id)
+			if !ok {
+				http.Error(w, "Tiket tidak valid atau kedaluwarsa", http.StatusUnauthorized)
+				return
+			}
+			authorize(w, r, claims, sessions, roles, next)
+		}
+	}
+}
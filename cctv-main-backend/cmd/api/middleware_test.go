@@ -0,0 +1,90 @@
+package main
+
+import (
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/internal/session"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// activeSessions menjawab IsActive dari map; sesi yang tidak ada dianggap dicabut.
+type activeSessions struct {
+	session.Service
+	active map[int64]bool
+}
+
+func (s *activeSessions) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	return s.active[sessionID], nil
+}
+
+type builtinRoles struct{ policy.Service }
+
+func (builtinRoles) Resolve(ctx context.Context, role string, companyID int64) (policy.Set, error) {
+	return policy.NewSet(policy.CameraRead), nil
+}
+
+func TestAuthMiddlewareRejectsRevokedSession(t *testing.T) {
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet("test", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := &activeSessions{active: map[int64]bool{1: true}}
+	mw := newAuthMiddleware(keys, sessions, builtinRoles{})
+	handler := mw(RequirePermission(policy.CameraRead, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	token := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+		s, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"sesi aktif", "Bearer " + token(jwt.MapClaims{"user_id": 7, "role": "user", "sid": 1}), http.StatusNoContent},
+		{"sesi dicabut", "Bearer " + token(jwt.MapClaims{"user_id": 7, "role": "user", "sid": 2}), http.StatusUnauthorized},
+		{"tanpa sid", "Bearer " + token(jwt.MapClaims{"user_id": 7, "role": "user"}), http.StatusUnauthorized},
+		{"tanpa header", "", http.StatusUnauthorized},
+		{"token rusak", "Bearer abc", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/cameras", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// Logout di tengah jalan: token yang sama langsung ditolak.
+	live := "Bearer " + token(jwt.MapClaims{"user_id": 7, "role": "user", "sid": 1})
+	sessions.active[1] = false
+	req := httptest.NewRequest(http.MethodGet, "/api/cameras", nil)
+	req.Header.Set("Authorization", live)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("token sesi yang baru dicabut: status = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"cctv-main-backend/pkg/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `Pemakaian: main migrate <status|up|down> [N]
+  status   tampilkan migrasi yang sudah/belum diterapkan
+  up [N]   terapkan N migrasi berikutnya (default: semua)
+  down [N] batalkan N migrasi terakhir (default: 1)`
+
+// runMigrateCommand menangani subcommand "migrate" dan mengembalikan exit code.
+func runMigrateCommand(db *sql.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			fmt.Fprintf(os.Stderr, "jumlah langkah tidak valid: %q\n", args[1])
+			return 2
+		}
+		steps = n
+	}
+
+	m, err := database.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "memuat migrasi:", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "status migrasi:", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range list {
+			state, at := "pending", ""
+			if s.Applied {
+				state = "applied"
+				at = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Missing {
+				state = "missing"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		tw.Flush()
+	case "up":
+		n, err := m.Up(ctx, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("%d migrasi diterapkan\n", n)
+	case "down":
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("%d migrasi dibatalkan\n", n)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package access
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+// userIDFromPath mengambil {id} dari /api/users/{id}/cameras.
+func userIDFromPath(path string) int64 {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	return id
+}
+
+// GET /api/users/{id}/cameras
+func (h *Handler) GetUserAccess(w http.ResponseWriter, r *http.Request) {
+	a, err := h.service.GetUserAccess(r.Context(), userIDFromPath(r.URL.Path), companyScope(r))
+	if err != nil {
+		writeAccessError(w, err, "Gagal mengambil akses kamera")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// PUT /api/users/{id}/cameras  body: {"restricted": true, "camera_ids": [1,2], "group_ids": [3]}
+func (h *Handler) SetUserAccess(w http.ResponseWriter, r *http.Request) {
+	var a domain.CameraAccess
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	a.UserID = userIDFromPath(r.URL.Path)
+
+	if err := h.service.SetUserAccess(r.Context(), companyScope(r), &a); err != nil {
+		writeAccessError(w, err, "Gagal menyimpan akses kamera")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Akses kamera berhasil diperbarui."))
+}
+
+// GET /api/camera-groups
+func (h *Handler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.service.ListGroups(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil grup kamera", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// POST /api/camera-groups  body: {"name": "Lobby", "camera_ids": [1,2]}
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var g domain.CameraGroup
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	g.CompanyID = companyScope(r)
+
+	id, err := h.service.CreateGroup(r.Context(), &g)
+	if err != nil {
+		writeAccessError(w, err, "Gagal membuat grup kamera")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"group_id": id})
+}
+
+// PUT /api/camera-groups/{id}
+func (h *Handler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	var g domain.CameraGroup
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	g.ID = id
+	g.CompanyID = companyScope(r)
+
+	if err := h.service.UpdateGroup(r.Context(), &g); err != nil {
+		writeAccessError(w, err, "Gagal memperbarui grup kamera")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Grup kamera berhasil diperbarui."))
+}
+
+// DELETE /api/camera-groups/{id}
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	if err := h.service.DeleteGroup(r.Context(), id, companyScope(r)); err != nil {
+		writeAccessError(w, err, "Gagal menghapus grup kamera")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Grup kamera berhasil dihapus."))
+}
+
+func writeAccessError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrGroupNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrGroupExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrForeignCamera), errors.Is(err, ErrInvalidGroup):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,250 @@
+package access
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrUserNotFound  = errors.New("pengguna tidak ditemukan atau bukan bagian dari perusahaan Anda")
+	ErrGroupNotFound = errors.New("grup kamera tidak ditemukan")
+	ErrGroupExists   = errors.New("nama grup kamera sudah dipakai")
+	// ErrForeignCamera dikembalikan bila kamera/grup yang diberikan bukan milik perusahaan yang sama.
+	ErrForeignCamera = errors.New("kamera atau grup bukan milik perusahaan ini")
+)
+
+type Repository interface {
+	IsRestricted(ctx context.Context, userID int64) (bool, error)
+	AccessibleCameraIDs(ctx context.Context, userID int64) ([]int64, error)
+	GetUserAccess(ctx context.Context, userID, companyID int64) (*domain.CameraAccess, error)
+	SetUserAccess(ctx context.Context, companyID int64, a *domain.CameraAccess) error
+
+	ListGroups(ctx context.Context, companyID int64) ([]domain.CameraGroup, error)
+	CreateGroup(ctx context.Context, g *domain.CameraGroup) (int64, error)
+	UpdateGroup(ctx context.Context, g *domain.CameraGroup) error
+	DeleteGroup(ctx context.Context, id, companyID int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) IsRestricted(ctx context.Context, userID int64) (bool, error) {
+	var restricted bool
+	err := r.db.QueryRowContext(ctx, `SELECT camera_access_restricted FROM users WHERE id = $1`, userID).Scan(&restricted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	return restricted, err
+}
+
+func (r *repository) AccessibleCameraIDs(ctx context.Context, userID int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT camera_id FROM user_accessible_cameras WHERE user_id = $1 ORDER BY camera_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *repository) GetUserAccess(ctx context.Context, userID, companyID int64) (*domain.CameraAccess, error) {
+	a := domain.CameraAccess{UserID: userID, CameraIDs: []int64{}, GroupIDs: []int64{}}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT camera_access_restricted FROM users WHERE id = $1 AND company_id = $2`, userID, companyID,
+	).Scan(&a.Restricted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT camera_id, group_id FROM user_camera_grants WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cameraID, groupID sql.NullInt64
+		if err := rows.Scan(&cameraID, &groupID); err != nil {
+			return nil, err
+		}
+		if cameraID.Valid {
+			a.CameraIDs = append(a.CameraIDs, cameraID.Int64)
+		}
+		if groupID.Valid {
+			a.GroupIDs = append(a.GroupIDs, groupID.Int64)
+		}
+	}
+	return &a, rows.Err()
+}
+
+// SetUserAccess mengganti seluruh pengaturan akses user dalam satu transaksi.
+func (r *repository) SetUserAccess(ctx context.Context, companyID int64, a *domain.CameraAccess) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE users SET camera_access_restricted = $1 WHERE id = $2 AND company_id = $3`,
+		a.Restricted, a.UserID, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+
+	var foreign int
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM UNNEST($1::bigint[]) AS x(id) WHERE NOT EXISTS (SELECT 1 FROM cameras WHERE id = x.id AND company_id = $3)) +
+			(SELECT COUNT(*) FROM UNNEST($2::bigint[]) AS x(id) WHERE NOT EXISTS (SELECT 1 FROM camera_groups WHERE id = x.id AND company_id = $3))`,
+		pqx.Array(a.CameraIDs), pqx.Array(a.GroupIDs), companyID,
+	).Scan(&foreign)
+	if err != nil {
+		return err
+	}
+	if foreign > 0 {
+		return ErrForeignCamera
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_camera_grants WHERE user_id = $1`, a.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_camera_grants (user_id, camera_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		a.UserID, pqx.Array(a.CameraIDs)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_camera_grants (user_id, group_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		a.UserID, pqx.Array(a.GroupIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) ListGroups(ctx context.Context, companyID int64) ([]domain.CameraGroup, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.company_id, g.name, g.created_at,
+		       COALESCE(ARRAY_AGG(m.camera_id ORDER BY m.camera_id) FILTER (WHERE m.camera_id IS NOT NULL), '{}')
+		FROM camera_groups g
+		LEFT JOIN camera_group_members m ON m.group_id = g.id
+		WHERE g.company_id = $1
+		GROUP BY g.id
+		ORDER BY g.name ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := []domain.CameraGroup{}
+	for rows.Next() {
+		var g domain.CameraGroup
+		if err := rows.Scan(&g.ID, &g.CompanyID, &g.Name, &g.CreatedAt, pqx.Array(&g.CameraIDs)); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *repository) CreateGroup(ctx context.Context, g *domain.CameraGroup) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO camera_groups (company_id, name) VALUES ($1, $2) RETURNING id`, g.CompanyID, g.Name,
+	).Scan(&id)
+	if err != nil {
+		if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
+			return 0, ErrGroupExists
+		}
+		return 0, err
+	}
+	if err := replaceMembers(ctx, tx, id, g.CompanyID, g.CameraIDs); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *repository) UpdateGroup(ctx context.Context, g *domain.CameraGroup) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE camera_groups SET name = $1 WHERE id = $2 AND company_id = $3`, g.Name, g.ID, g.CompanyID)
+	if err != nil {
+		if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
+			return ErrGroupExists
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGroupNotFound
+	}
+	if err := replaceMembers(ctx, tx, g.ID, g.CompanyID, g.CameraIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) DeleteGroup(ctx context.Context, id, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM camera_groups WHERE id = $1 AND company_id = $2`, id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+func replaceMembers(ctx context.Context, tx *sql.Tx, groupID, companyID int64, cameraIDs []int64) error {
+	var foreign int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM UNNEST($1::bigint[]) AS x(id)
+		WHERE NOT EXISTS (SELECT 1 FROM cameras WHERE id = x.id AND company_id = $2)`,
+		pqx.Array(cameraIDs), companyID,
+	).Scan(&foreign); err != nil {
+		return err
+	}
+	if foreign > 0 {
+		return ErrForeignCamera
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM camera_group_members WHERE group_id = $1`, groupID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO camera_group_members (group_id, camera_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		groupID, pqx.Array(cameraIDs))
+	return err
+}
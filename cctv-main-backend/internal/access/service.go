@@ -0,0 +1,103 @@
+package access
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidGroup = errors.New("nama grup kamera wajib diisi")
+
+type Service interface {
+	// Scope menentukan kamera mana saja yang boleh dilihat pemilik request.
+	Scope(ctx context.Context) (domain.CameraScope, error)
+	GetUserAccess(ctx context.Context, userID, companyID int64) (*domain.CameraAccess, error)
+	SetUserAccess(ctx context.Context, companyID int64, a *domain.CameraAccess) error
+
+	ListGroups(ctx context.Context, companyID int64) ([]domain.CameraGroup, error)
+	CreateGroup(ctx context.Context, g *domain.CameraGroup) (int64, error)
+	UpdateGroup(ctx context.Context, g *domain.CameraGroup) error
+	DeleteGroup(ctx context.Context, id, companyID int64) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Scope(ctx context.Context) (domain.CameraScope, error) {
+	// Pemegang CompanyManage (platform admin) tidak dibatasi ACL kamera.
+	if policy.Has(ctx, policy.CompanyManage) {
+		return domain.CameraScope{All: true}, nil
+	}
+	claims, _ := ctx.Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	restricted, err := s.repo.IsRestricted(ctx, int64(userID))
+	if err != nil {
+		return domain.CameraScope{}, err
+	}
+	if !restricted {
+		return domain.CameraScope{All: true}, nil
+	}
+	ids, err := s.repo.AccessibleCameraIDs(ctx, int64(userID))
+	if err != nil {
+		return domain.CameraScope{}, err
+	}
+	return domain.CameraScope{CameraIDs: ids}, nil
+}
+
+func (s *service) GetUserAccess(ctx context.Context, userID, companyID int64) (*domain.CameraAccess, error) {
+	return s.repo.GetUserAccess(ctx, userID, companyID)
+}
+
+func (s *service) SetUserAccess(ctx context.Context, companyID int64, a *domain.CameraAccess) error {
+	if a.CameraIDs == nil {
+		a.CameraIDs = []int64{}
+	}
+	if a.GroupIDs == nil {
+		a.GroupIDs = []int64{}
+	}
+	return s.repo.SetUserAccess(ctx, companyID, a)
+}
+
+func (s *service) ListGroups(ctx context.Context, companyID int64) ([]domain.CameraGroup, error) {
+	return s.repo.ListGroups(ctx, companyID)
+}
+
+func (s *service) CreateGroup(ctx context.Context, g *domain.CameraGroup) (int64, error) {
+	if err := normalizeGroup(g); err != nil {
+		return 0, err
+	}
+	return s.repo.CreateGroup(ctx, g)
+}
+
+func (s *service) UpdateGroup(ctx context.Context, g *domain.CameraGroup) error {
+	if err := normalizeGroup(g); err != nil {
+		return err
+	}
+	return s.repo.UpdateGroup(ctx, g)
+}
+
+func (s *service) DeleteGroup(ctx context.Context, id, companyID int64) error {
+	return s.repo.DeleteGroup(ctx, id, companyID)
+}
+
+func normalizeGroup(g *domain.CameraGroup) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return ErrInvalidGroup
+	}
+	if g.CameraIDs == nil {
+		g.CameraIDs = []int64{}
+	}
+	return nil
+}
@@ -2,13 +2,16 @@ package anomaly
 
 import (
 	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/internal/storage"
 	"cctv-main-backend/pkg/auth"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +26,7 @@ type Handler struct {
 	// optional S3 presigner for detail endpoint
 	s3         s3Presigner
 	clipBucket string
+	access     cameraScoper
 }
 
 type ContextKey string
@@ -34,8 +38,13 @@ type s3Presigner interface {
 	Presign(bucket, key string, ttl time.Duration) (string, error)
 }
 
-func NewHandler(service Service, s3 s3Presigner, clipBucket string) *Handler {
-	return &Handler{service: service, s3: s3, clipBucket: clipBucket}
+// cameraScoper adalah bagian access.Service yang dipakai untuk ACL kamera.
+type cameraScoper interface {
+	Scope(ctx context.Context) (domain.CameraScope, error)
+}
+
+func NewHandler(service Service, s3 s3Presigner, clipBucket string, access cameraScoper) *Handler {
+	return &Handler{service: service, s3: s3, clipBucket: clipBucket, access: access}
 }
 
 func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
@@ -60,13 +69,28 @@ func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
 	log.Printf("✅ Laporan Diterima dari Kamera ID: %d, Tipe: %s", report.CameraID, report.AnomalyType)
 
 	err := h.service.SaveReport(&report)
+	if errors.Is(err, ErrReportDropped) {
+		// Bukan kesalahan worker: laporan sengaja dibuang oleh filter kamera.
+		log.Printf("   > Laporan diabaikan: %v", err)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Laporan diabaikan oleh filter kamera."))
+		return
+	}
+	if errors.Is(err, ErrUnknownCamera) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("❌ `Gagal memproses laporan: %v", err)
 		http.Error(w, "Gagal memproses laporan", http.StatusInternalServerError)
 		return
 	}
 
-	log.Println("   > Laporan berhasil disimpan.")
+	if report.Occurrences > 1 {
+		log.Printf("   > Laporan digabung ke insiden %d (kemunculan ke-%d).", report.ID, report.Occurrences)
+	} else {
+		log.Println("   > Laporan berhasil disimpan.")
+	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Laporan berhasil diterima dan disimpan."))
 }
@@ -75,34 +99,118 @@ func (h *Handler) GetAllReports(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
 	if !ok || claims == nil {
 		http.Error(w, "Gagal mengambil data pengguna dari token", http.StatusUnauthorized)
-		fmt.Println("Failed to get claims from context.")
 		return
 	}
 
-	fmt.Println("Claims in GetAllReports:", claims)
-
 	var companyID int64
-	role, _ := claims["role"].(string)
-	if strings.EqualFold(role, "superadmin") {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		companyID = 0 // 0 = no filter (all companies)
 	} else {
 		cID, ok := claims["company_id"].(float64)
 		if !ok {
 			http.Error(w, "Gagal mengambil company_id dari token", http.StatusUnauthorized)
-			fmt.Println("Failed to extract company_id from claims.")
 			return
 		}
 		companyID = int64(cID)
 	}
 
-	reports, err := h.service.FetchAllReportsByCompany(int64(companyID))
+	scope, err := h.access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseAnomalyQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				companyID = id
+			}
+		}
+	}
+
+	page, err := h.service.FetchAllReportsByCompany(companyID, scope, q)
 	if err != nil {
+		if errors.Is(err, ErrInvalidQuery) || errors.Is(err, ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Gagal mengambil data", http.StatusInternalServerError)
 		return
 	}
 
+	// Body tetap berupa array agar klien lama tidak rusak; info paginasi lewat header.
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(reports)
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	json.NewEncoder(w).Encode(page.Items)
+}
+
+// parseAnomalyQuery membaca filter /api/anomalies:
+// camera_id, anomaly_type (boleh dipisah koma), status (boleh dipisah koma),
+// min_confidence, max_confidence, from, to (RFC3339), site_id, zone_id,
+// sort (reported_at, -reported_at, confidence, -confidence), limit, cursor.
+func parseAnomalyQuery(v url.Values) (domain.AnomalyQuery, error) {
+	q := domain.AnomalyQuery{
+		LocationFilter: domain.LocationFilterFromQuery(v),
+		AnomalyTypes:   splitList(v.Get("anomaly_type")),
+		Statuses:       splitList(v.Get("status")),
+		Sort:           v.Get("sort"),
+		Cursor:         v.Get("cursor"),
+	}
+	var err error
+	if s := v.Get("camera_id"); s != "" {
+		if q.CameraID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return q, fmt.Errorf("camera_id tidak valid")
+		}
+	}
+	if s := v.Get("suppressed"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("suppressed tidak valid")
+		}
+		q.Suppressed = &b
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return q, fmt.Errorf("limit tidak valid")
+		}
+	}
+	for name, dst := range map[string]**float64{"min_confidence": &q.MinConfidence, "max_confidence": &q.MaxConfidence} {
+		if s := v.Get(name); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return q, fmt.Errorf("%s tidak valid", name)
+			}
+			*dst = &f
+		}
+	}
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if s := v.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("%s harus format RFC3339", name)
+			}
+			*dst = t
+		}
+	}
+	return q, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
 }
 
 func (h *Handler) GetRecent(w http.ResponseWriter, r *http.Request) {
@@ -112,8 +220,7 @@ func (h *Handler) GetRecent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var companyID int64
-	role, _ := claims["role"].(string)
-	if strings.EqualFold(role, "superadmin") {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		companyID = 0
 	} else {
 		cID, ok := claims["company_id"].(float64)
@@ -137,7 +244,13 @@ func (h *Handler) GetRecent(w http.ResponseWriter, r *http.Request) {
 		limit = 100
 	}
 
-	reports, err := h.service.ListRecent(int64(companyID), limit)
+	scope, err := h.access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	reports, err := h.service.ListRecent(int64(companyID), scope, domain.LocationFilterFromQuery(r.URL.Query()), limit)
 	if err != nil {
 		http.Error(w, "Gagal mengambil data", http.StatusInternalServerError)
 		return
@@ -154,8 +267,7 @@ func (h *Handler) GetDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var companyID int64
-	role, _ := claims["role"].(string)
-	if strings.EqualFold(role, "superadmin") {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		companyID = 0
 	} else {
 		cID, ok := claims["company_id"].(float64)
@@ -177,22 +289,21 @@ func (h *Handler) GetDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rep, err := h.service.GetDetail(int64(companyID), id)
+	scope, err := h.access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	rep, err := h.service.GetDetail(int64(companyID), scope, id)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
 
-	clipURL := rep.VideoClipURL
-	if h.s3 != nil && clipURL != "" {
-		if bkt, key, ok := parseBucketKey(clipURL); ok {
-			if bkt == "" {
-				bkt = h.clipBucket
-			}
-			if url, err := h.s3.Presign(bkt, key, 10*time.Minute); err == nil {
-				clipURL = url
-			}
-		}
+	clipURLs := make([]string, 0, len(rep.VideoClipURLs))
+	for _, u := range rep.VideoClipURLs {
+		clipURLs = append(clipURLs, h.presignClip(u))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -202,20 +313,177 @@ func (h *Handler) GetDetail(w http.ResponseWriter, r *http.Request) {
 		"anomaly_type":   rep.AnomalyType,
 		"confidence":     rep.Confidence,
 		"reported_at":    rep.ReportedAt,
-		"video_clip_url": clipURL,
+		"video_clip_url": h.presignClip(rep.VideoClipURL),
+		// insiden gabungan (cooldown) dan jadwal deteksi
+		"occurrences":     rep.Occurrences,
+		"last_seen_at":    rep.LastSeenAt,
+		"suppressed":      rep.Suppressed,
+		"video_clip_urls": clipURLs,
+		// alur penanganan
+		"status":           rep.Status,
+		"assignee_id":      rep.AssigneeID,
+		"resolution_notes": rep.ResolutionNotes,
+		"acknowledged_at":  rep.AcknowledgedAt,
+		"resolved_at":      rep.ResolvedAt,
 	})
 }
 
-func parseBucketKey(u string) (bucket string, key string, ok bool) {
-	p, err := url.Parse(u)
+// presignClip mengganti URL objek klip dengan presigned URL bila S3 tersedia;
+// selain itu URL dikembalikan apa adanya.
+func (h *Handler) presignClip(clipURL string) string {
+	if h.s3 == nil || clipURL == "" {
+		return clipURL
+	}
+	bkt, key, ok := storage.SplitObjectURL(clipURL)
+	if !ok {
+		return clipURL
+	}
+	if bkt == "" {
+		bkt = h.clipBucket
+	}
+	if url, err := h.s3.Presign(bkt, key, 10*time.Minute); err == nil {
+		return url
+	}
+	return clipURL
+}
+
+// requestScope mengambil company_id (0 = semua perusahaan untuk pemegang
+// CompanyManage), scope kamera, dan actor dari request.
+func (h *Handler) requestScope(r *http.Request) (int64, domain.CameraScope, Actor, error) {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+	email, _ := claims["email"].(string)
+	actor := Actor{ID: int64(userID), Email: email}
+
+	var companyID int64
+	if !policy.Has(r.Context(), policy.CompanyManage) {
+		cID, _ := claims["company_id"].(float64)
+		companyID = int64(cID)
+	}
+	scope, err := h.access.Scope(r.Context())
+	return companyID, scope, actor, err
+}
+
+// POST /api/anomalies/{id}/{acknowledge|start|resolve|false-positive|reopen|assign}
+// body (opsional): {"note": "..."}; untuk assign: {"assignee_id": 7, "note": "..."} (null = lepas)
+func (h *Handler) Transition(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		http.Error(w, "bad path", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	action := parts[3]
+
+	var body struct {
+		Note       string `json:"note"`
+		AssigneeID *int64 `json:"assignee_id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	companyID, scope, actor, err := h.requestScope(r)
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	var ev *domain.AnomalyEvent
+	if action == "assign" {
+		ev, err = h.service.Assign(r.Context(), companyID, scope, id, body.AssigneeID, actor, body.Note)
+	} else {
+		ev, err = h.service.Apply(r.Context(), companyID, scope, id, action, actor, body.Note)
+	}
 	if err != nil {
-		return "", "", false
+		writeWorkflowError(w, err)
+		return
 	}
-	seg := strings.Split(strings.Trim(p.Path, "/"), "/")
-	if len(seg) < 2 {
-		return "", "", false
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ev)
+}
+
+// GET /api/anomalies/{id}/history
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		http.Error(w, "bad path", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	companyID, scope, _, err := h.requestScope(r)
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+	events, err := h.service.History(r.Context(), companyID, scope, id)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GET /api/anomalies/stats?bucket=hour|day|week&tz=Asia/Jakarta&top=5
+// plus filter yang sama dengan /api/anomalies (site_id, zone_id, camera_id, from, to, ...).
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	companyID, scope, _, err := h.requestScope(r)
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				companyID = id
+			}
+		}
+	}
+	q, err := parseAnomalyQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	topN, _ := strconv.Atoi(r.URL.Query().Get("top"))
+
+	stats, err := h.service.Stats(r.Context(), companyID, scope, q, r.URL.Query().Get("bucket"), r.URL.Query().Get("tz"), topN)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuery) || errors.Is(err, ErrInvalidStatsRange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("anomaly stats error: %v", err)
+		http.Error(w, "Gagal menghitung statistik", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func writeWorkflowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnknownAction):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidAssignee):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("anomaly workflow error: %v", err)
+		http.Error(w, "Gagal memproses anomali", http.StatusInternalServerError)
 	}
-	bucket = seg[0]
-	key = path.Clean(strings.Join(seg[1:], "/"))
-	return bucket, key, true
 }
@@ -0,0 +1,143 @@
+package anomaly
+
+import (
+	"cctv-main-backend/internal/domain"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pqx "github.com/lib/pq"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+var ErrInvalidCursor = errors.New("cursor tidak valid")
+
+// sortColumns memetakan nilai ?sort= ke kolom keyset.
+var sortColumns = map[string]string{
+	"reported_at": "r.reported_at",
+	"confidence":  "r.confidence",
+}
+
+// whereBuilder menyusun klausa WHERE dengan placeholder $n berurutan.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (b *whereBuilder) add(cond string, args ...any) {
+	for _, a := range args {
+		b.args = append(b.args, a)
+		cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(b.args)), 1)
+	}
+	b.conds = append(b.conds, cond)
+}
+
+func (b *whereBuilder) sql() string {
+	if len(b.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(b.conds, " AND ")
+}
+
+// buildReportFilter menyusun filter perusahaan, ACL kamera, dan AnomalyQuery
+// untuk query yang memakai alias r (anomaly_reports) dan c (cameras).
+func buildReportFilter(companyID int64, scope domain.CameraScope, q domain.AnomalyQuery) *whereBuilder {
+	b := &whereBuilder{}
+	if companyID != 0 {
+		b.add("c.company_id = ?", companyID)
+	}
+	if !scope.All {
+		b.add("r.camera_id = ANY(?)", pqx.Array(scope.CameraIDs))
+	}
+	if q.SiteID != 0 {
+		b.add("c.site_id = ?", q.SiteID)
+	}
+	if q.ZoneID != 0 {
+		b.add("c.zone_id = ?", q.ZoneID)
+	}
+	if q.CameraID != 0 {
+		b.add("r.camera_id = ?", q.CameraID)
+	}
+	if len(q.AnomalyTypes) > 0 {
+		b.add("r.anomaly_type = ANY(?)", pqx.Array(q.AnomalyTypes))
+	}
+	if len(q.Statuses) > 0 {
+		b.add("r.status = ANY(?)", pqx.Array(q.Statuses))
+	}
+	if q.MinConfidence != nil {
+		b.add("r.confidence >= ?", *q.MinConfidence)
+	}
+	if q.MaxConfidence != nil {
+		b.add("r.confidence <= ?", *q.MaxConfidence)
+	}
+	if q.Suppressed != nil {
+		b.add("r.suppressed = ?", *q.Suppressed)
+	}
+	if !q.From.IsZero() {
+		b.add("r.reported_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		b.add("r.reported_at < ?", q.To)
+	}
+	return b
+}
+
+// parseSort mengembalikan kolom keyset dan arah urutan (true = DESC).
+func parseSort(sort string) (string, bool, error) {
+	desc := true
+	key := "reported_at"
+	if sort != "" {
+		desc = strings.HasPrefix(sort, "-")
+		key = strings.TrimPrefix(sort, "-")
+	}
+	col, ok := sortColumns[key]
+	if !ok {
+		return "", false, fmt.Errorf("sort tidak dikenal: %s", sort)
+	}
+	return col, desc, nil
+}
+
+// Cursor berbentuk base64url("<nilai kolom sort>|<id>") agar tetap opaque bagi klien.
+func encodeCursor(col string, rep *domain.AnomalyReport) string {
+	var v string
+	if col == "r.confidence" {
+		v = strconv.FormatFloat(rep.Confidence, 'g', -1, 64)
+	} else {
+		v = rep.ReportedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(v + "|" + strconv.FormatInt(rep.ID, 10)))
+}
+
+func decodeCursor(col, cursor string) (any, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	v, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	if col == "r.confidence" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return f, id, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return t, id, nil
+}
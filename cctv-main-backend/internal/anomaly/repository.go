@@ -2,17 +2,89 @@ package anomaly
 
 import (
 	"cctv-main-backend/internal/domain"
+	"context"
 	"database/sql"
+	"errors"
+	"strconv"
 	"time"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrNotFound          = errors.New("anomali tidak ditemukan")
+	ErrInvalidTransition = errors.New("perubahan status tidak diizinkan dari status saat ini")
+	ErrInvalidAssignee   = errors.New("penanggung jawab bukan pengguna perusahaan ini")
+	ErrUnknownCamera     = errors.New("kamera tidak dikenal")
 )
 
+// reportColumns dipakai semua query baca agar urutan kolom sama dengan scanReport.
+const reportColumns = `r.id, r.camera_id, r.anomaly_type, r.confidence, COALESCE(r.video_clip_url, ''), r.reported_at,
+	r.status, r.assignee_id, COALESCE(r.resolution_notes, ''), r.acknowledged_at, r.resolved_at, c.company_id,
+	r.occurrences, r.last_seen_at, r.suppressed, r.video_clip_urls`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row rowScanner) (*domain.AnomalyReport, error) {
+	var (
+		report       domain.AnomalyReport
+		assigneeID   sql.NullInt64
+		ackAt, resAt sql.NullTime
+		clips        pqx.StringArray
+	)
+	err := row.Scan(&report.ID, &report.CameraID, &report.AnomalyType, &report.Confidence, &report.VideoClipURL, &report.ReportedAt,
+		&report.Status, &assigneeID, &report.ResolutionNotes, &ackAt, &resAt, &report.CompanyID,
+		&report.Occurrences, &report.LastSeenAt, &report.Suppressed, &clips)
+	if err != nil {
+		return nil, err
+	}
+	report.VideoClipURLs = []string(clips)
+	if report.VideoClipURLs == nil {
+		report.VideoClipURLs = []string{}
+	}
+	if assigneeID.Valid {
+		report.AssigneeID = &assigneeID.Int64
+	}
+	if ackAt.Valid {
+		report.AcknowledgedAt = &ackAt.Time
+	}
+	if resAt.Valid {
+		report.ResolvedAt = &resAt.Time
+	}
+	return &report, nil
+}
+
 type Repository interface {
-	CreateReport(report *domain.AnomalyReport) error
-	GetAllReportsByCompany(companyID int64) ([]domain.AnomalyReport, error)
-	GetRecentReportsByCompany(companyID int64, limit int) ([]domain.AnomalyReport, error)
-	GetByIDForCompany(companyID int64, id int64) (*domain.AnomalyReport, error)
+	// CreateReport menyimpan laporan, atau melipatnya ke insiden terbuka dengan kamera dan
+	// tipe yang sama bila kemunculan terakhirnya masih dalam cooldown (report.Occurrences > 1).
+	// Jendela: cooldown.ByType untuk tipe laporan, lalu cameras.alert_cooldown_seconds,
+	// lalu cooldown.Default. Laporan hanya dilipat ke insiden dengan status suppressed
+	// yang sama; klipnya ditambahkan ke video_clip_urls insiden.
+	CreateReport(report *domain.AnomalyReport, cooldown Cooldown) error
+	// GetAlertFilter mengambil ambang confidence dan tipe anomali yang diterima kamera.
+	GetAlertFilter(ctx context.Context, cameraID int64) (*domain.AlertFilter, error)
+	// Semua query baca dibatasi oleh scope kamera user (ACL per kamera).
+	GetAllReportsByCompany(companyID int64, scope domain.CameraScope, q domain.AnomalyQuery) (*domain.AnomalyPage, error)
+	GetRecentReportsByCompany(companyID int64, scope domain.CameraScope, filter domain.LocationFilter, limit int) ([]domain.AnomalyReport, error)
+	GetByIDForCompany(companyID int64, scope domain.CameraScope, id int64) (*domain.AnomalyReport, error)
+
+	// ChangeStatus memindahkan anomali ke ev.ToStatus bila status saat ini ada di
+	// allowedFrom, lalu mencatat ev ke riwayat dalam transaksi yang sama.
+	// onChange (opsional) dipanggil sebelum commit dengan kondisi anomali terbaru;
+	// error darinya membatalkan perubahan.
+	ChangeStatus(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, allowedFrom []string, ev *domain.AnomalyEvent, onChange ChangeHook) error
+	Assign(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, ev *domain.AnomalyEvent, onChange ChangeHook) error
+	ListEvents(ctx context.Context, companyID int64, scope domain.CameraScope, id int64) ([]domain.AnomalyEvent, error)
+
+	Stats(ctx context.Context, companyID int64, scope domain.CameraScope, q domain.AnomalyQuery, bucket string, loc *time.Location, topN int) (*domain.AnomalyStats, error)
 }
 
+// ChangeHook menjalankan efek samping perubahan anomali (mis. antrean webhook)
+// di dalam transaksi perubahan itu sendiri.
+type ChangeHook func(tx *sql.Tx, report *domain.AnomalyReport) error
+
 type repository struct {
 	db *sql.DB
 }
@@ -21,52 +93,165 @@ func NewRepository(db *sql.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) CreateReport(report *domain.AnomalyReport) error {
-	// Kembalikan ID agar bisa dikirimkan dalam payload notifikasi (untuk deep-link/detail)
-	query := `INSERT INTO anomaly_reports (camera_id, anomaly_type, confidence, video_clip_url, reported_at)
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	return r.db.QueryRow(query, report.CameraID, report.AnomalyType, report.Confidence, report.VideoClipURL, time.Now()).Scan(&report.ID)
+// CreateReport menyimpan anomali beserta baris outbox notifikasinya dalam satu
+// transaksi; notifikasi dikirim oleh dispatcher outbox. Laporan yang dilipat ke
+// insiden lama atau yang suppressed tidak membuat outbox baru.
+func (r *repository) CreateReport(report *domain.AnomalyReport, cooldown Cooldown) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialkan laporan untuk kamera + tipe yang sama agar dua klip beruntun tidak
+	// sama-sama membuat insiden baru.
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1::int, hashtext($2))`, report.CameraID, report.AnomalyType); err != nil {
+		return err
+	}
+
+	// Jendela per tipe (bila diatur) didahulukan dari pengaturan kamera.
+	var typeWindow sql.NullInt64
+	if d, ok := cooldown.ByType[report.AnomalyType]; ok {
+		typeWindow = sql.NullInt64{Int64: int64(d / time.Second), Valid: true}
+	}
+
+	// Insiden yang sudah ditutup (resolved/false_positive) tidak dilanjutkan.
+	folded, err := scanReport(tx.QueryRow(`
+		UPDATE anomaly_reports r
+		SET occurrences = r.occurrences + 1, last_seen_at = NOW(), confidence = GREATEST(r.confidence, $3),
+		    video_clip_urls = CASE WHEN $7::text = '' THEN r.video_clip_urls ELSE array_append(r.video_clip_urls, $7::text) END
+		FROM cameras c
+		WHERE c.id = r.camera_id AND r.id = (
+			SELECT id FROM anomaly_reports
+			WHERE camera_id = $1 AND anomaly_type = $2
+			  AND status NOT IN ('resolved', 'false_positive') AND suppressed = $5
+			  AND last_seen_at > NOW() - COALESCE($4::integer, (SELECT alert_cooldown_seconds FROM cameras WHERE id = $1), $6::integer) * INTERVAL '1 second'
+			ORDER BY last_seen_at DESC
+			LIMIT 1
+		)
+		RETURNING `+reportColumns,
+		report.CameraID, report.AnomalyType, report.Confidence, typeWindow, report.Suppressed,
+		int64(cooldown.Default/time.Second), report.VideoClipURL,
+	))
+	if err == nil {
+		*report = *folded
+		return tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Kembalikan ID agar bisa dikirimkan dalam payload notifikasi (untuk deep-link/detail),
+	// company_id kamera untuk event real-time, dan status awal.
+	query := `INSERT INTO anomaly_reports (camera_id, anomaly_type, confidence, video_clip_url, reported_at, last_seen_at, suppressed, video_clip_urls)
+              VALUES ($1, $2, $3, $4, $5, $5, $6, CASE WHEN $4::text = '' THEN '{}'::text[] ELSE ARRAY[$4::text] END)
+              RETURNING id, reported_at, status, occurrences, last_seen_at, (SELECT company_id FROM cameras WHERE id = $1)`
+	err = tx.QueryRow(query, report.CameraID, report.AnomalyType, report.Confidence, report.VideoClipURL, time.Now(), report.Suppressed).
+		Scan(&report.ID, &report.ReportedAt, &report.Status, &report.Occurrences, &report.LastSeenAt, &report.CompanyID)
+	if err != nil {
+		return err
+	}
+	if report.Suppressed {
+		return tx.Commit()
+	}
+	if _, err := tx.Exec(`INSERT INTO notification_outbox (anomaly_id) VALUES ($1)`, report.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) GetAlertFilter(ctx context.Context, cameraID int64) (*domain.AlertFilter, error) {
+	var f domain.AlertFilter
+	var minConf sql.NullFloat64
+	var types pqx.StringArray
+	err := r.db.QueryRowContext(ctx, `SELECT min_confidence, anomaly_types, filter_action FROM cameras WHERE id = $1`, cameraID).
+		Scan(&minConf, &types, &f.FilterAction)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownCamera
+	}
+	if err != nil {
+		return nil, err
+	}
+	if minConf.Valid {
+		f.MinConfidence = &minConf.Float64
+	}
+	f.AnomalyTypes = []string(types)
+	return &f, nil
 }
 
-func (r *repository) GetAllReportsByCompany(companyID int64) ([]domain.AnomalyReport, error) {
-	// Query sekarang mengambil juga video_clip_url
+// GetAllReportsByCompany mengembalikan satu halaman anomali (keyset pagination)
+// beserta jumlah total yang cocok dengan filter. companyID 0 = semua perusahaan.
+func (r *repository) GetAllReportsByCompany(companyID int64, scope domain.CameraScope, q domain.AnomalyQuery) (*domain.AnomalyPage, error) {
+	col, desc, err := parseSort(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+	b := buildReportFilter(companyID, scope, q)
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id WHERE ` + b.sql()
+	if err := r.db.QueryRow(countQuery, b.args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	if q.Cursor != "" {
+		v, id, err := decodeCursor(col, q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		b.add("("+col+", r.id) "+cmp+" (?, ?)", v, id)
+	}
+	// Ambil satu baris lebih untuk tahu apakah masih ada halaman berikutnya.
+	b.args = append(b.args, q.Limit+1)
 	query := `
-		SELECT r.id, r.camera_id, r.anomaly_type, r.confidence, r.video_clip_url, r.reported_at
+		SELECT ` + reportColumns + `
 		FROM anomaly_reports r
 		JOIN cameras c ON r.camera_id = c.id
-		WHERE c.company_id = $1
-		ORDER BY r.reported_at DESC`
+		WHERE ` + b.sql() + `
+		ORDER BY ` + col + ` ` + dir + `, r.id ` + dir + `
+		LIMIT $` + strconv.Itoa(len(b.args))
 
-	rows, err := r.db.Query(query, companyID)
+	rows, err := r.db.Query(query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var reports []domain.AnomalyReport
+	page := &domain.AnomalyPage{Items: []domain.AnomalyReport{}, Total: total}
 	for rows.Next() {
-		var report domain.AnomalyReport
-		// Sesuaikan Scan untuk membaca kolom baru
-		if err := rows.Scan(&report.ID, &report.CameraID, &report.AnomalyType, &report.Confidence, &report.VideoClipURL, &report.ReportedAt); err != nil {
+		report, err := scanReport(rows)
+		if err != nil {
 			return nil, err
 		}
-		reports = append(reports, report)
+		page.Items = append(page.Items, *report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = encodeCursor(col, &page.Items[q.Limit-1])
 	}
-	return reports, nil
+	return page, nil
 }
 
-func (r *repository) GetRecentReportsByCompany(companyID int64, limit int) ([]domain.AnomalyReport, error) {
+func (r *repository) GetRecentReportsByCompany(companyID int64, scope domain.CameraScope, filter domain.LocationFilter, limit int) ([]domain.AnomalyReport, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	const q = `
-        SELECT r.id, r.camera_id, r.anomaly_type, r.confidence, r.video_clip_url, r.reported_at
+        SELECT ` + reportColumns + `
         FROM anomaly_reports r
         JOIN cameras c ON r.camera_id = c.id
-        WHERE c.company_id = $1
+        WHERE ($1 = 0 OR c.company_id = $1) AND ($2 OR r.camera_id = ANY($3))
+          AND ($4 = 0 OR c.site_id = $4) AND ($5 = 0 OR c.zone_id = $5)
         ORDER BY r.reported_at DESC
-        LIMIT $2`
-	rows, err := r.db.Query(q, companyID, limit)
+        LIMIT $6`
+	rows, err := r.db.Query(q, companyID, scope.All, pqx.Array(scope.CameraIDs), filter.SiteID, filter.ZoneID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -74,25 +259,189 @@ func (r *repository) GetRecentReportsByCompany(companyID int64, limit int) ([]do
 
 	var reports []domain.AnomalyReport
 	for rows.Next() {
-		var report domain.AnomalyReport
-		if err := rows.Scan(&report.ID, &report.CameraID, &report.AnomalyType, &report.Confidence, &report.VideoClipURL, &report.ReportedAt); err != nil {
+		report, err := scanReport(rows)
+		if err != nil {
 			return nil, err
 		}
-		reports = append(reports, report)
+		reports = append(reports, *report)
 	}
 	return reports, rows.Err()
 }
 
-func (r *repository) GetByIDForCompany(companyID int64, id int64) (*domain.AnomalyReport, error) {
+func (r *repository) GetByIDForCompany(companyID int64, scope domain.CameraScope, id int64) (*domain.AnomalyReport, error) {
 	const q = `
-        SELECT r.id, r.camera_id, r.anomaly_type, r.confidence, r.video_clip_url, r.reported_at
+        SELECT ` + reportColumns + `
         FROM anomaly_reports r
         JOIN cameras c ON r.camera_id = c.id
-        WHERE c.company_id = $1 AND r.id = $2`
-	var report domain.AnomalyReport
-	err := r.db.QueryRow(q, companyID, id).Scan(&report.ID, &report.CameraID, &report.AnomalyType, &report.Confidence, &report.VideoClipURL, &report.ReportedAt)
+        WHERE ($1 = 0 OR c.company_id = $1) AND r.id = $2 AND ($3 OR r.camera_id = ANY($4))`
+	return scanReport(r.db.QueryRow(q, companyID, id, scope.All, pqx.Array(scope.CameraIDs)))
+}
+
+// lockReport mengunci baris anomali (FOR UPDATE) setelah memastikan anomali
+// terlihat oleh pemanggil. Mengembalikan status dan company_id kamera.
+func lockReport(ctx context.Context, tx *sql.Tx, companyID int64, scope domain.CameraScope, id int64) (string, int64, error) {
+	var status string
+	var cameraCompanyID int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT r.status, c.company_id
+		FROM anomaly_reports r
+		JOIN cameras c ON r.camera_id = c.id
+		WHERE r.id = $1 AND ($2 = 0 OR c.company_id = $2) AND ($3 OR r.camera_id = ANY($4))
+		FOR UPDATE OF r`,
+		id, companyID, scope.All, pqx.Array(scope.CameraIDs),
+	).Scan(&status, &cameraCompanyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, ErrNotFound
+	}
+	return status, cameraCompanyID, err
+}
+
+// commitChange menjalankan onChange dengan kondisi anomali setelah perubahan,
+// lalu meng-commit transaksi.
+func commitChange(ctx context.Context, tx *sql.Tx, id int64, onChange ChangeHook) error {
+	if onChange != nil {
+		report, err := scanReport(tx.QueryRowContext(ctx, `
+			SELECT `+reportColumns+`
+			FROM anomaly_reports r
+			JOIN cameras c ON r.camera_id = c.id
+			WHERE r.id = $1`, id))
+		if err != nil {
+			return err
+		}
+		if err := onChange(tx, report); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func insertEvent(ctx context.Context, tx *sql.Tx, ev *domain.AnomalyEvent) error {
+	return tx.QueryRowContext(ctx, `
+		INSERT INTO anomaly_events (anomaly_id, actor_id, actor_email, action, from_status, to_status, assignee_id, note)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''))
+		RETURNING id, created_at`,
+		ev.AnomalyID, ev.ActorID, ev.ActorEmail, ev.Action, ev.FromStatus, ev.ToStatus, ev.AssigneeID, ev.Note,
+	).Scan(&ev.ID, &ev.CreatedAt)
+}
+
+func (r *repository) ChangeStatus(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, allowedFrom []string, ev *domain.AnomalyEvent, onChange ChangeHook) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, _, err := lockReport(ctx, tx, companyID, scope, id)
+	if err != nil {
+		return err
+	}
+	allowed := false
+	for _, st := range allowedFrom {
+		if st == current {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ErrInvalidTransition
+	}
+
+	// acknowledged_at diisi sekali saat anomali pertama kali ditangani;
+	// resolved_at dihapus lagi bila anomali dibuka kembali.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE anomaly_reports SET
+			status = $1,
+			acknowledged_at = CASE WHEN $1 <> 'new' THEN COALESCE(acknowledged_at, NOW()) ELSE acknowledged_at END,
+			resolved_at = CASE WHEN $1 IN ('resolved', 'false_positive') THEN NOW() ELSE NULL END,
+			resolution_notes = CASE WHEN $1 IN ('resolved', 'false_positive') AND $2 <> '' THEN $2 ELSE resolution_notes END
+		WHERE id = $3`,
+		ev.ToStatus, ev.Note, id); err != nil {
+		return err
+	}
+
+	ev.AnomalyID = id
+	ev.FromStatus = current
+	if err := insertEvent(ctx, tx, ev); err != nil {
+		return err
+	}
+	return commitChange(ctx, tx, id, onChange)
+}
+
+func (r *repository) Assign(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, ev *domain.AnomalyEvent, onChange ChangeHook) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, cameraCompanyID, err := lockReport(ctx, tx, companyID, scope, id)
+	if err != nil {
+		return err
+	}
+	if ev.AssigneeID != nil {
+		var ok bool
+		if err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND company_id = $2)`, *ev.AssigneeID, cameraCompanyID,
+		).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidAssignee
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE anomaly_reports SET assignee_id = $1 WHERE id = $2`, ev.AssigneeID, id); err != nil {
+		return err
+	}
+
+	ev.AnomalyID = id
+	ev.FromStatus = current
+	ev.ToStatus = current
+	if err := insertEvent(ctx, tx, ev); err != nil {
+		return err
+	}
+	return commitChange(ctx, tx, id, onChange)
+}
+
+func (r *repository) ListEvents(ctx context.Context, companyID int64, scope domain.CameraScope, id int64) ([]domain.AnomalyEvent, error) {
+	var visible bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+			WHERE r.id = $1 AND ($2 = 0 OR c.company_id = $2) AND ($3 OR r.camera_id = ANY($4))
+		)`, id, companyID, scope.All, pqx.Array(scope.CameraIDs)).Scan(&visible)
 	if err != nil {
 		return nil, err
 	}
-	return &report, nil
+	if !visible {
+		return nil, ErrNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.id, e.anomaly_id, e.actor_id, COALESCE(e.actor_email, ''), e.action,
+		       COALESCE(e.from_status, ''), COALESCE(e.to_status, ''), e.assignee_id, COALESCE(e.note, ''), e.created_at
+		FROM anomaly_events e
+		WHERE e.anomaly_id = $1
+		ORDER BY e.created_at ASC, e.id ASC`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.AnomalyEvent{}
+	for rows.Next() {
+		var ev domain.AnomalyEvent
+		var actorID, assigneeID sql.NullInt64
+		if err := rows.Scan(&ev.ID, &ev.AnomalyID, &actorID, &ev.ActorEmail, &ev.Action,
+			&ev.FromStatus, &ev.ToStatus, &assigneeID, &ev.Note, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			ev.ActorID = &actorID.Int64
+		}
+		if assigneeID.Valid {
+			ev.AssigneeID = &assigneeID.Int64
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
 }
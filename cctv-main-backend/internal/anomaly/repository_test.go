@@ -0,0 +1,143 @@
+package anomaly
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/database"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+// openTestDB membuka database dari TEST_POSTGRES_DSN dan menjalankan migrasi.
+// Test dilewati bila variabel itu kosong; pakai database yang boleh dikotori.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN tidak diisi")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testCamera membuat perusahaan dan kamera baru; keduanya (beserta anomalinya)
+// dihapus saat test selesai.
+func testCamera(t *testing.T, db *sql.DB, cooldownSeconds *int) int64 {
+	t.Helper()
+	var companyID, cameraID int64
+	if err := db.QueryRow(`INSERT INTO companies (name) VALUES ('anomaly-test') RETURNING id`).Scan(&companyID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM companies WHERE id = $1`, companyID) })
+	if err := db.QueryRow(`INSERT INTO cameras (name, company_id, alert_cooldown_seconds) VALUES ('cam', $1, $2) RETURNING id`,
+		companyID, cooldownSeconds).Scan(&cameraID); err != nil {
+		t.Fatal(err)
+	}
+	return cameraID
+}
+
+func outboxCount(t *testing.T, db *sql.DB, anomalyID int64) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM notification_outbox WHERE anomaly_id = $1`, anomalyID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCreateReportFoldsWithinCooldown(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewRepository(db)
+	cameraID := testCamera(t, db, nil)
+	cooldown := Cooldown{Default: time.Hour}
+
+	first := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.6, VideoClipURL: "a.mp4"}
+	if err := repo.CreateReport(first, cooldown); err != nil {
+		t.Fatal(err)
+	}
+	if first.Occurrences != 1 || outboxCount(t, db, first.ID) != 1 {
+		t.Fatalf("laporan pertama: occurrences %d, outbox %d", first.Occurrences, outboxCount(t, db, first.ID))
+	}
+
+	again := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.9, VideoClipURL: "b.mp4"}
+	if err := repo.CreateReport(again, cooldown); err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Occurrences != 2 || again.Confidence != 0.9 {
+		t.Fatalf("laporan ulang = id %d, occurrences %d, confidence %v; want dilipat ke %d", again.ID, again.Occurrences, again.Confidence, first.ID)
+	}
+	if len(again.VideoClipURLs) != 2 || again.VideoClipURLs[1] != "b.mp4" {
+		t.Fatalf("video_clip_urls = %v", again.VideoClipURLs)
+	}
+	if outboxCount(t, db, first.ID) != 1 {
+		t.Fatal("laporan yang dilipat membuat outbox baru")
+	}
+
+	// Tipe lain, laporan suppressed, dan insiden yang sudah ditutup tidak dilipat.
+	other := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "intrusion", Confidence: 0.8}
+	suppressed := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.8, Suppressed: true}
+	for _, r := range []*domain.AnomalyReport{other, suppressed} {
+		if err := repo.CreateReport(r, cooldown); err != nil {
+			t.Fatal(err)
+		}
+		if r.ID == first.ID || r.Occurrences != 1 {
+			t.Fatalf("%s (suppressed %v) dilipat ke insiden %d", r.AnomalyType, r.Suppressed, first.ID)
+		}
+	}
+	if outboxCount(t, db, suppressed.ID) != 0 {
+		t.Fatal("laporan suppressed membuat outbox")
+	}
+	if _, err := db.Exec(`UPDATE anomaly_reports SET status = 'resolved' WHERE id = $1`, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	reopened := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.8}
+	if err := repo.CreateReport(reopened, cooldown); err != nil {
+		t.Fatal(err)
+	}
+	if reopened.ID == first.ID {
+		t.Fatal("laporan dilipat ke insiden yang sudah resolved")
+	}
+}
+
+func TestCreateReportCooldownWindow(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewRepository(db)
+	zero := 0
+
+	tests := []struct {
+		name       string
+		camera     *int // cameras.alert_cooldown_seconds
+		cooldown   Cooldown
+		wantFolded bool
+	}{
+		{"default", nil, Cooldown{Default: time.Hour}, true},
+		{"default nol", nil, Cooldown{}, false},
+		{"kamera menimpa default", &zero, Cooldown{Default: time.Hour}, false},
+		{"tipe menimpa kamera", &zero, Cooldown{ByType: map[string]time.Duration{"fire": time.Hour}}, true},
+		{"tipe nol menimpa default", nil, Cooldown{Default: time.Hour, ByType: map[string]time.Duration{"fire": 0}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cameraID := testCamera(t, db, tt.camera)
+			first := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.8}
+			second := &domain.AnomalyReport{CameraID: cameraID, AnomalyType: "fire", Confidence: 0.8}
+			if err := repo.CreateReport(first, tt.cooldown); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.CreateReport(second, tt.cooldown); err != nil {
+				t.Fatal(err)
+			}
+			if folded := second.ID == first.ID; folded != tt.wantFolded {
+				t.Fatalf("dilipat = %v, want %v", folded, tt.wantFolded)
+			}
+		})
+	}
+}
@@ -2,58 +2,336 @@ package anomaly
 
 import (
 	"cctv-main-backend/internal/domain"
-	"cctv-main-backend/pkg/notifier"
+	"cctv-main-backend/pkg/events"
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 )
 
 type Service interface {
+    // SaveReport menerapkan filter kamera, jadwal deteksi, dan cooldown sebelum menyimpan;
+    // ErrReportDropped bila laporan dibuang oleh filter kamera.
     SaveReport(report *domain.AnomalyReport) error
-    FetchAllReportsByCompany(companyID int64) ([]domain.AnomalyReport, error)
-    ListRecent(companyID int64, limit int) ([]domain.AnomalyReport, error)
-    GetDetail(companyID, id int64) (*domain.AnomalyReport, error)
+    // FetchAllReportsByCompany mengembalikan satu halaman anomali sesuai q; companyID 0 = semua perusahaan.
+    FetchAllReportsByCompany(companyID int64, scope domain.CameraScope, q domain.AnomalyQuery) (*domain.AnomalyPage, error)
+    ListRecent(companyID int64, scope domain.CameraScope, filter domain.LocationFilter, limit int) ([]domain.AnomalyReport, error)
+    GetDetail(companyID int64, scope domain.CameraScope, id int64) (*domain.AnomalyReport, error)
+
+    // Apply menjalankan aksi alur kerja (acknowledge, start, resolve, false-positive, reopen).
+    Apply(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, action string, actor Actor, note string) (*domain.AnomalyEvent, error)
+    Assign(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, assigneeID *int64, actor Actor, note string) (*domain.AnomalyEvent, error)
+    History(ctx context.Context, companyID int64, scope domain.CameraScope, id int64) ([]domain.AnomalyEvent, error)
+
+    // Stats menghitung statistik anomali; bucket hour/day/week, tz nama zona IANA.
+    Stats(ctx context.Context, companyID int64, scope domain.CameraScope, q domain.AnomalyQuery, bucket, tz string, topN int) (*domain.AnomalyStats, error)
+}
+
+// Actor adalah user yang melakukan aksi, dicatat di riwayat anomali.
+type Actor struct {
+	ID    int64
+	Email string
+}
+
+var (
+	ErrUnknownAction = errors.New("aksi tidak dikenal")
+	ErrInvalidQuery  = errors.New("parameter query tidak valid")
+	ErrReportDropped = errors.New("laporan di bawah ambang atau tipe tidak diterima kamera")
+)
+
+func isValidStatus(st string) bool {
+	switch st {
+	case domain.AnomalyStatusNew, domain.AnomalyStatusAcknowledged, domain.AnomalyStatusInProgress,
+		domain.AnomalyStatusResolved, domain.AnomalyStatusFalsePositive:
+		return true
+	}
+	return false
+}
+
+type transition struct {
+	to   string
+	from []string
+}
+
+// transitions mendefinisikan alur status anomali per aksi.
+var transitions = map[string]transition{
+	"acknowledge": {
+		to:   domain.AnomalyStatusAcknowledged,
+		from: []string{domain.AnomalyStatusNew},
+	},
+	"start": {
+		to:   domain.AnomalyStatusInProgress,
+		from: []string{domain.AnomalyStatusNew, domain.AnomalyStatusAcknowledged},
+	},
+	"resolve": {
+		to:   domain.AnomalyStatusResolved,
+		from: []string{domain.AnomalyStatusNew, domain.AnomalyStatusAcknowledged, domain.AnomalyStatusInProgress},
+	},
+	"false-positive": {
+		to:   domain.AnomalyStatusFalsePositive,
+		from: []string{domain.AnomalyStatusNew, domain.AnomalyStatusAcknowledged, domain.AnomalyStatusInProgress},
+	},
+	"reopen": {
+		to:   domain.AnomalyStatusInProgress,
+		from: []string{domain.AnomalyStatusResolved, domain.AnomalyStatusFalsePositive},
+	},
+}
+
+// Waker membangunkan dispatcher outbox agar notifikasi baru tidak menunggu polling berikutnya.
+type Waker interface {
+	Wake()
+}
+
+// Cooldown adalah jendela penggabungan laporan per kamera dan tipe anomali. Laporan
+// yang datang sebelum jendela sejak kemunculan terakhir dilipat ke insiden yang sama
+// dan tidak mengirim notifikasi lagi. Jendela dipilih berurutan: ByType untuk tipe
+// laporan, cameras.alert_cooldown_seconds, lalu Default.
+type Cooldown struct {
+	Default time.Duration
+	ByType  map[string]time.Duration // opsional, per anomaly_type
+}
+
+// WebhookQueue mengantrekan event ke webhook perusahaan yang berlangganan di
+// dalam transaksi yang mengubah anomalinya.
+type WebhookQueue interface {
+	EnqueueTx(ctx context.Context, tx *sql.Tx, companyID, cameraID int64, event string, data any) (int, error)
+}
+
+// ArmChecker menentukan apakah kamera sedang armed menurut jadwal deteksinya.
+type ArmChecker interface {
+	Armed(ctx context.Context, cameraID int64, at time.Time) (bool, error)
 }
 
 type service struct {
 	repo     Repository
-	notifier notifier.Notifier
+	outbox   Waker            // opsional
+	events   events.Publisher // opsional: event real-time untuk /api/events
+	cooldown Cooldown
+	schedule ArmChecker   // opsional: tanpa jadwal kamera selalu armed
+	webhooks WebhookQueue // opsional: anomaly.status_changed ke webhook
 }
 
-func NewService(repo Repository, notifier notifier.Notifier) Service {
-	return &service{repo: repo, notifier: notifier}
+func NewService(repo Repository, outbox Waker, publisher events.Publisher, cooldown Cooldown, schedule ArmChecker, webhooks WebhookQueue) Service {
+	return &service{repo: repo, outbox: outbox, events: publisher, cooldown: cooldown, schedule: schedule, webhooks: webhooks}
+}
+
+func (s *service) publish(ev events.Event) {
+	if s.events != nil {
+		s.events.Publish(ev)
+	}
 }
 
 func (s *service) SaveReport(report *domain.AnomalyReport) error {
-	if err := s.repo.CreateReport(report); err != nil {
+	filter, err := s.repo.GetAlertFilter(context.Background(), report.CameraID)
+	if err != nil {
 		return err
 	}
-
-	// 2) Kirim notifikasi (sinkron agar jelas terlihat di log)
-	if s.notifier != nil {
-		if err := s.notifier.NotifyAnomaly(context.Background(), report); err != nil {
-			log.Printf("NotifyAnomaly error: %v", err)
-			// tidak return error supaya penyimpanan tetap dianggap sukses
+	report.Suppressed = false
+	if !filter.Accepts(report.AnomalyType, report.Confidence) {
+		if filter.FilterAction != domain.FilterActionStore {
+			return ErrReportDropped
 		}
-	} else {
-		log.Println("NotifyAnomaly skip: notifier nil")
+		report.Suppressed = true
+	}
+	if s.schedule != nil && !report.Suppressed {
+		armed, err := s.schedule.Armed(context.Background(), report.CameraID, time.Now())
+		if err != nil {
+			// Lebih baik alert terkirim di luar jadwal daripada alert hilang.
+			log.Printf("cek jadwal deteksi kamera %d: %v", report.CameraID, err)
+			armed = true
+		}
+		report.Suppressed = !armed
+	}
+	if err := s.repo.CreateReport(report, s.cooldown); err != nil {
+		return err
+	}
+	if report.Suppressed {
+		// Kamera tidak armed atau laporan ditahan filter kamera (store): tersimpan tanpa
+		// notifikasi maupun event real-time.
+		return nil
+	}
+	if report.Occurrences > 1 {
+		// Kemunculan ulang hanya memperbarui insiden di dashboard; notifikasi sudah dikirim
+		// untuk laporan pertama.
+		s.publish(events.Event{
+			Type:      events.AnomalyRepeated,
+			CompanyID: report.CompanyID,
+			CameraID:  report.CameraID,
+			Data:      report,
+		})
+		return nil
+	}
+	s.publish(events.Event{
+		Type:      events.AnomalyCreated,
+		CompanyID: report.CompanyID,
+		CameraID:  report.CameraID,
+		Data:      report,
+	})
+
+	// Notifikasi sudah tercatat di outbox bersama anomali; dispatcher mengirimnya di background.
+	if s.outbox != nil {
+		s.outbox.Wake()
 	}
 	return nil
 }
 
-func (s *service) FetchAllReportsByCompany(companyID int64) ([]domain.AnomalyReport, error) {
-	return s.repo.GetAllReportsByCompany(companyID)
+func (s *service) FetchAllReportsByCompany(companyID int64, scope domain.CameraScope, q domain.AnomalyQuery) (*domain.AnomalyPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	for _, st := range q.Statuses {
+		if !isValidStatus(st) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, st)
+		}
+	}
+	if _, _, err := parseSort(q.Sort); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return s.repo.GetAllReportsByCompany(companyID, scope, q)
 }
 
-func (s *service) ListRecent(companyID int64, limit int) ([]domain.AnomalyReport, error) {
+func (s *service) ListRecent(companyID int64, scope domain.CameraScope, filter domain.LocationFilter, limit int) ([]domain.AnomalyReport, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	if limit > 100 {
 		limit = 100
 	}
-	return s.repo.GetRecentReportsByCompany(companyID, limit)
+	return s.repo.GetRecentReportsByCompany(companyID, scope, filter, limit)
+}
+
+func (s *service) GetDetail(companyID int64, scope domain.CameraScope, id int64) (*domain.AnomalyReport, error) {
+    return s.repo.GetByIDForCompany(companyID, scope, id)
+}
+
+func (s *service) Apply(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, action string, actor Actor, note string) (*domain.AnomalyEvent, error) {
+	t, ok := transitions[action]
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+	ev := &domain.AnomalyEvent{
+		ActorID:    &actor.ID,
+		ActorEmail: actor.Email,
+		Action:     action,
+		ToStatus:   t.to,
+		Note:       strings.TrimSpace(note),
+	}
+	var report *domain.AnomalyReport
+	if err := s.repo.ChangeStatus(ctx, companyID, scope, id, t.from, ev, s.onStatusChanged(ctx, ev, &report)); err != nil {
+		return nil, err
+	}
+	s.publishStatusChanged(report, ev)
+	return ev, nil
+}
+
+func (s *service) Assign(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, assigneeID *int64, actor Actor, note string) (*domain.AnomalyEvent, error) {
+	ev := &domain.AnomalyEvent{
+		ActorID:    &actor.ID,
+		ActorEmail: actor.Email,
+		Action:     "assign",
+		AssigneeID: assigneeID,
+		Note:       strings.TrimSpace(note),
+	}
+	var report *domain.AnomalyReport
+	if err := s.repo.Assign(ctx, companyID, scope, id, ev, s.onStatusChanged(ctx, ev, &report)); err != nil {
+		return nil, err
+	}
+	s.publishStatusChanged(report, ev)
+	return ev, nil
 }
 
-func (s *service) GetDetail(companyID, id int64) (*domain.AnomalyReport, error) {
-    return s.repo.GetByIDForCompany(companyID, id)
+// onStatusChanged mengantrekan anomaly.status_changed ke webhook di dalam
+// transaksi perubahan, sehingga event tidak hilang bila proses berhenti tepat
+// setelah commit. Kondisi anomali terbaru disimpan ke *report untuk event real-time.
+func (s *service) onStatusChanged(ctx context.Context, ev *domain.AnomalyEvent, report **domain.AnomalyReport) ChangeHook {
+	return func(tx *sql.Tx, r *domain.AnomalyReport) error {
+		*report = r
+		if s.webhooks == nil {
+			return nil
+		}
+		_, err := s.webhooks.EnqueueTx(ctx, tx, r.CompanyID, r.CameraID, events.AnomalyStatusChanged, statusChangedData(r, ev))
+		return err
+	}
+}
+
+// publishStatusChanged mengirim kondisi anomali terbaru beserta event riwayatnya ke /api/events.
+func (s *service) publishStatusChanged(report *domain.AnomalyReport, ev *domain.AnomalyEvent) {
+	if report == nil {
+		return
+	}
+	s.publish(events.Event{
+		Type:      events.AnomalyStatusChanged,
+		CompanyID: report.CompanyID,
+		CameraID:  report.CameraID,
+		Data:      statusChangedData(report, ev),
+	})
+}
+
+func statusChangedData(report *domain.AnomalyReport, ev *domain.AnomalyEvent) map[string]any {
+	return map[string]any{
+		"anomaly": report,
+		"event":   ev,
+	}
+}
+
+func (s *service) History(ctx context.Context, companyID int64, scope domain.CameraScope, id int64) ([]domain.AnomalyEvent, error) {
+	return s.repo.ListEvents(ctx, companyID, scope, id)
+}
+
+// defaultStatsWindow adalah rentang bawaan bila ?from= tidak diisi.
+var defaultStatsWindow = map[string]time.Duration{
+	"hour": 24 * time.Hour,
+	"day":  30 * 24 * time.Hour,
+	"week": 12 * 7 * 24 * time.Hour,
+}
+
+func (s *service) Stats(ctx context.Context, companyID int64, scope domain.CameraScope, q domain.AnomalyQuery, bucket, tz string, topN int) (*domain.AnomalyStats, error) {
+	if bucket == "" {
+		bucket = "day"
+	}
+	unit, ok := bucketUnits[bucket]
+	if !ok {
+		return nil, fmt.Errorf("%w: bucket %s", ErrInvalidQuery, bucket)
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tz %s", ErrInvalidQuery, tz)
+	}
+	for _, st := range q.Statuses {
+		if !isValidStatus(st) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, st)
+		}
+	}
+	if topN <= 0 {
+		topN = 5
+	}
+	if topN > 50 {
+		topN = 50
+	}
+
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultStatsWindow[bucket])
+	}
+	if !q.From.Before(q.To) || q.To.Sub(q.From)/unit > maxStatsBuckets {
+		return nil, ErrInvalidStatsRange
+	}
+
+	stats, err := s.repo.Stats(ctx, companyID, scope, q, bucket, loc, topN)
+	if err != nil {
+		return nil, err
+	}
+	stats.Series = fillBuckets(stats.Series, q.From, q.To, bucket, loc)
+	return stats, nil
 }
@@ -0,0 +1,211 @@
+package anomaly
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/events"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// txRepo meniru transaksi ChangeStatus/Assign: perubahan hanya tersimpan bila
+// onChange tidak mengembalikan error.
+type txRepo struct {
+	Repository
+	report domain.AnomalyReport
+}
+
+func (r *txRepo) ChangeStatus(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, allowedFrom []string, ev *domain.AnomalyEvent, onChange ChangeHook) error {
+	next := r.report
+	next.Status = ev.ToStatus
+	return r.commit(&next, onChange)
+}
+
+func (r *txRepo) Assign(ctx context.Context, companyID int64, scope domain.CameraScope, id int64, ev *domain.AnomalyEvent, onChange ChangeHook) error {
+	next := r.report
+	next.AssigneeID = ev.AssigneeID
+	return r.commit(&next, onChange)
+}
+
+func (r *txRepo) commit(next *domain.AnomalyReport, onChange ChangeHook) error {
+	if onChange != nil {
+		if err := onChange(nil, next); err != nil {
+			return err
+		}
+	}
+	r.report = *next
+	return nil
+}
+
+type queuedWebhook struct {
+	companyID, cameraID int64
+	event               string
+	data                map[string]any
+}
+
+type fakeWebhooks struct {
+	err    error
+	queued []queuedWebhook
+}
+
+func (f *fakeWebhooks) EnqueueTx(ctx context.Context, tx *sql.Tx, companyID, cameraID int64, event string, data any) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.queued = append(f.queued, queuedWebhook{companyID, cameraID, event, data.(map[string]any)})
+	return 1, nil
+}
+
+type publishedEvents []events.Event
+
+func (p *publishedEvents) Publish(ev events.Event) { *p = append(*p, ev) }
+
+func TestStatusChangeQueuesWebhookInTransaction(t *testing.T) {
+	assignee := int64(9)
+	tests := []struct {
+		name       string
+		run        func(s Service) error
+		enqueueErr error
+		wantStatus string
+	}{
+		{
+			name: "acknowledge",
+			run: func(s Service) error {
+				_, err := s.Apply(context.Background(), 1, domain.CameraScope{All: true}, 42, "acknowledge", Actor{ID: 3}, "")
+				return err
+			},
+			wantStatus: domain.AnomalyStatusAcknowledged,
+		},
+		{
+			name: "assign",
+			run: func(s Service) error {
+				_, err := s.Assign(context.Background(), 1, domain.CameraScope{All: true}, 42, &assignee, Actor{ID: 3}, "")
+				return err
+			},
+			wantStatus: domain.AnomalyStatusNew,
+		},
+		{
+			name: "gagal antre membatalkan perubahan",
+			run: func(s Service) error {
+				_, err := s.Apply(context.Background(), 1, domain.CameraScope{All: true}, 42, "acknowledge", Actor{ID: 3}, "")
+				return err
+			},
+			enqueueErr: errors.New("db down"),
+			wantStatus: domain.AnomalyStatusNew,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &txRepo{report: domain.AnomalyReport{ID: 42, CameraID: 5, CompanyID: 1, Status: domain.AnomalyStatusNew}}
+			webhooks := &fakeWebhooks{err: tt.enqueueErr}
+			var published publishedEvents
+			s := NewService(repo, nil, &published, Cooldown{}, nil, webhooks)
+
+			err := tt.run(s)
+			if !errors.Is(err, tt.enqueueErr) {
+				t.Fatalf("err = %v, want %v", err, tt.enqueueErr)
+			}
+			if repo.report.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", repo.report.Status, tt.wantStatus)
+			}
+			if tt.enqueueErr != nil {
+				if len(published) != 0 {
+					t.Fatal("event real-time dikirim walau perubahan dibatalkan")
+				}
+				return
+			}
+			if len(webhooks.queued) != 1 {
+				t.Fatalf("webhook diantrekan %d kali, want 1", len(webhooks.queued))
+			}
+			q := webhooks.queued[0]
+			if q.companyID != 1 || q.cameraID != 5 || q.event != events.AnomalyStatusChanged {
+				t.Fatalf("webhook = %+v", q)
+			}
+			// Payload memuat kondisi setelah perubahan, bukan sebelum.
+			if got := q.data["anomaly"].(*domain.AnomalyReport); got.Status != tt.wantStatus || (tt.name == "assign" && got.AssigneeID == nil) {
+				t.Fatalf("payload anomaly = %+v", got)
+			}
+			if len(published) != 1 || published[0].Type != events.AnomalyStatusChanged {
+				t.Fatalf("event real-time = %+v", published)
+			}
+		})
+	}
+}
+
+// foldRepo melipat laporan ke insiden terakhir dengan kamera, tipe, dan status
+// suppressed yang sama (cooldown dianggap belum habis).
+type foldRepo struct {
+	Repository
+	filter  domain.AlertFilter
+	reports []*domain.AnomalyReport
+}
+
+func (r *foldRepo) GetAlertFilter(ctx context.Context, cameraID int64) (*domain.AlertFilter, error) {
+	return &r.filter, nil
+}
+
+func (r *foldRepo) CreateReport(report *domain.AnomalyReport, cooldown Cooldown) error {
+	for _, open := range r.reports {
+		if open.CameraID == report.CameraID && open.AnomalyType == report.AnomalyType && open.Suppressed == report.Suppressed {
+			open.Occurrences++
+			*report = *open
+			return nil
+		}
+	}
+	report.ID = int64(len(r.reports) + 1)
+	report.Occurrences = 1
+	saved := *report
+	r.reports = append(r.reports, &saved)
+	return nil
+}
+
+type countWaker int
+
+func (w *countWaker) Wake() { *w++ }
+
+func TestSaveReport(t *testing.T) {
+	minConf := 0.5
+	repo := &foldRepo{filter: domain.AlertFilter{MinConfidence: &minConf, FilterAction: domain.FilterActionStore}}
+	var wakes countWaker
+	var published publishedEvents
+	s := NewService(repo, &wakes, &published, Cooldown{Default: time.Minute}, nil, nil)
+
+	steps := []struct {
+		name       string
+		confidence float64
+		wantEvent  string // "" = tidak ada event real-time
+		wantWakes  int
+		wantOcc    int
+	}{
+		{"laporan pertama", 0.8, events.AnomalyCreated, 1, 1},
+		{"kemunculan ulang dilipat", 0.9, events.AnomalyRepeated, 1, 2},
+		{"di bawah ambang disimpan suppressed", 0.1, "", 1, 1},
+		{"suppressed berikutnya dilipat ke insiden suppressed", 0.2, "", 1, 2},
+	}
+	for _, st := range steps {
+		before := len(published)
+		report := &domain.AnomalyReport{CameraID: 5, AnomalyType: "fire", Confidence: st.confidence}
+		if err := s.SaveReport(report); err != nil {
+			t.Fatalf("%s: %v", st.name, err)
+		}
+		if report.Occurrences != st.wantOcc {
+			t.Errorf("%s: occurrences %d, want %d", st.name, report.Occurrences, st.wantOcc)
+		}
+		if int(wakes) != st.wantWakes {
+			t.Errorf("%s: outbox dibangunkan %d kali, want %d", st.name, wakes, st.wantWakes)
+		}
+		switch {
+		case st.wantEvent == "" && len(published) != before:
+			t.Errorf("%s: event %s dikirim", st.name, published[len(published)-1].Type)
+		case st.wantEvent != "" && (len(published) != before+1 || published[before].Type != st.wantEvent):
+			t.Errorf("%s: events %v, want %s", st.name, published[before:], st.wantEvent)
+		}
+	}
+
+	repo.filter.FilterAction = domain.FilterActionDrop
+	if err := s.SaveReport(&domain.AnomalyReport{CameraID: 5, AnomalyType: "fire", Confidence: 0.1}); !errors.Is(err, ErrReportDropped) {
+		t.Fatalf("filter drop: err = %v, want ErrReportDropped", err)
+	}
+}
@@ -0,0 +1,204 @@
+package anomaly
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Batas band confidence untuk breakdown statistik.
+const (
+	confidenceMedium = 0.5
+	confidenceHigh   = 0.8
+)
+
+// bucketUnits adalah nilai ?bucket= yang valid (langsung dipakai di date_trunc).
+var bucketUnits = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+const maxStatsBuckets = 2000
+
+var ErrInvalidStatsRange = errors.New("rentang waktu statistik tidak valid atau terlalu banyak bucket")
+
+// Stats menghitung agregat anomali dengan filter yang sama seperti daftar anomali.
+// q.From dan q.To wajib diisi; bucket sudah divalidasi oleh service.
+func (r *repository) Stats(ctx context.Context, companyID int64, scope domain.CameraScope, q domain.AnomalyQuery, bucket string, loc *time.Location, topN int) (*domain.AnomalyStats, error) {
+	stats := &domain.AnomalyStats{
+		From:     q.From,
+		To:       q.To,
+		Bucket:   bucket,
+		TZ:       loc.String(),
+		ByStatus: map[string]int{},
+	}
+
+	// Ringkasan: total, band confidence, dan waktu acknowledge dalam satu scan.
+	b := buildReportFilter(companyID, scope, q)
+	b.args = append(b.args, confidenceMedium, confidenceHigh)
+	nMed, nHigh := strconv.Itoa(len(b.args)-1), strconv.Itoa(len(b.args))
+	var low, medium, high int
+	var mean sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE r.confidence < $`+nMed+`),
+		       COUNT(*) FILTER (WHERE r.confidence >= $`+nMed+` AND r.confidence < $`+nHigh+`),
+		       COUNT(*) FILTER (WHERE r.confidence >= $`+nHigh+`),
+		       COUNT(*) FILTER (WHERE r.acknowledged_at IS NOT NULL),
+		       AVG(EXTRACT(EPOCH FROM (r.acknowledged_at - r.reported_at))) FILTER (WHERE r.acknowledged_at IS NOT NULL),
+		       COUNT(*) FILTER (WHERE r.status = 'new')
+		FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+		WHERE `+b.sql(), b.args...,
+	).Scan(&stats.Total, &low, &medium, &high, &stats.Acknowledge.Count, &mean, &stats.Acknowledge.UnacknowledgedOpen)
+	if err != nil {
+		return nil, err
+	}
+	if mean.Valid {
+		stats.Acknowledge.MeanSeconds = &mean.Float64
+	}
+	stats.ByConfidence = []domain.ConfidenceBand{
+		{Band: "low", Min: 0, Max: confidenceMedium, Count: low},
+		{Band: "medium", Min: confidenceMedium, Max: confidenceHigh, Count: medium},
+		{Band: "high", Min: confidenceHigh, Max: 1, Count: high},
+	}
+
+	// Deret waktu: bucket dihitung pada jam lokal loc.
+	b = buildReportFilter(companyID, scope, q)
+	b.args = append(b.args, loc.String())
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date_trunc('`+bucket+`', r.reported_at AT TIME ZONE $`+strconv.Itoa(len(b.args))+`) AS bucket, COUNT(*)
+		FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+		WHERE `+b.sql()+`
+		GROUP BY bucket ORDER BY bucket`, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var sb domain.StatsBucket
+		var wall time.Time
+		if err := rows.Scan(&wall, &sb.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// date_trunc ... AT TIME ZONE menghasilkan jam dinding tanpa zona; pasang loc.
+		sb.Start = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), 0, 0, 0, loc)
+		stats.Series = append(stats.Series, sb)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	b = buildReportFilter(companyID, scope, q)
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, COUNT(*) AS n
+		FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+		WHERE `+b.sql()+`
+		GROUP BY c.id, c.name ORDER BY n DESC, c.id ASC`, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	stats.ByCamera = []domain.CameraCount{}
+	for rows.Next() {
+		var cc domain.CameraCount
+		if err := rows.Scan(&cc.CameraID, &cc.CameraName, &cc.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.ByCamera = append(stats.ByCamera, cc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	top := stats.ByCamera
+	if len(top) > topN {
+		top = top[:topN]
+	}
+	stats.TopCameras = top
+
+	b = buildReportFilter(companyID, scope, q)
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT r.anomaly_type, COUNT(*) AS n
+		FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+		WHERE `+b.sql()+`
+		GROUP BY r.anomaly_type ORDER BY n DESC, r.anomaly_type ASC`, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	stats.ByType = []domain.TypeCount{}
+	for rows.Next() {
+		var tc domain.TypeCount
+		if err := rows.Scan(&tc.AnomalyType, &tc.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.ByType = append(stats.ByType, tc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	b = buildReportFilter(companyID, scope, q)
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT r.status, COUNT(*)
+		FROM anomaly_reports r JOIN cameras c ON r.camera_id = c.id
+		WHERE `+b.sql()+`
+		GROUP BY r.status`, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var st string
+		var n int
+		if err := rows.Scan(&st, &n); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[st] = n
+	}
+	return stats, rows.Err()
+}
+
+// fillBuckets melengkapi deret waktu dengan bucket bernilai 0 agar grafik kontinu.
+func fillBuckets(series []domain.StatsBucket, from, to time.Time, bucket string, loc *time.Location) []domain.StatsBucket {
+	counts := make(map[int64]int, len(series))
+	for _, sb := range series {
+		counts[sb.Start.Unix()] = sb.Count
+	}
+	out := []domain.StatsBucket{}
+	for t := truncateBucket(from.In(loc), bucket); t.Before(to); t = nextBucket(t, bucket) {
+		out = append(out, domain.StatsBucket{Start: t, Count: counts[t.Unix()]})
+	}
+	return out
+}
+
+// truncateBucket meniru date_trunc Postgres (minggu dimulai hari Senin).
+func truncateBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "week":
+		d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		offset := (int(d.Weekday()) + 6) % 7
+		return d.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
@@ -2,12 +2,16 @@ package camera
 
 import (
 	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
 	"cctv-main-backend/pkg/auth"
+	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"os"
 
@@ -16,10 +20,22 @@ import (
 
 type Handler struct {
 	service Service
+	access  cameraScoper
+	arm     armChecker
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+// cameraScoper adalah bagian access.Service yang dipakai handler kamera.
+type cameraScoper interface {
+	Scope(ctx context.Context) (domain.CameraScope, error)
+}
+
+// armChecker adalah bagian schedule.Service untuk flag "armed" di daftar kamera.
+type armChecker interface {
+	ArmedMap(ctx context.Context, cameraIDs []int64, at time.Time) (map[int64]bool, error)
+}
+
+func NewHandler(service Service, access cameraScoper, arm armChecker) *Handler {
+	return &Handler{service: service, access: access, arm: arm}
 }
 
 func (h *Handler) CreateCamera(w http.ResponseWriter, r *http.Request) {
@@ -30,7 +46,6 @@ func (h *Handler) CreateCamera(w http.ResponseWriter, r *http.Request) {
 	}
 
 	companyID, _ := claims["company_id"].(float64)
-	role, _ := claims["role"].(string)
 
 	var camera domain.Camera
 	if err := json.NewDecoder(r.Body).Decode(&camera); err != nil {
@@ -38,8 +53,8 @@ func (h *Handler) CreateCamera(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// superadmin can specify target company_id in request body
-	if role == "superadmin" && camera.CompanyID != 0 {
+	// platform admin can specify target company_id in request body
+	if policy.Has(r.Context(), policy.CompanyManage) && camera.CompanyID != 0 {
 		// use provided
 	} else {
 		camera.CompanyID = int64(companyID)
@@ -51,6 +66,10 @@ func (h *Handler) CreateCamera(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "stream_key sudah digunakan", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, ErrInvalidPlacement) || errors.Is(err, ErrInvalidAlertSettings) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Gagal mendaftarkan kamera", http.StatusInternalServerError)
 		return
 	}
@@ -67,6 +86,10 @@ func (h *Handler) CreateCamera(w http.ResponseWriter, r *http.Request) {
 		"rtsp_url":   buildRTSPURL(sk),
 		"webrtc_url": buildWebRTCURL(sk),
 	}
+	// Pembuat kamera (camera:write) menerima kredensial publish dan read path MediaMTX.
+	if camera.Credentials != nil {
+		resp["credentials"] = camera.Credentials
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(resp)
@@ -79,8 +102,7 @@ func (h *Handler) GetCameras(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	companyID, _ := claims["company_id"].(float64)
-	role, _ := claims["role"].(string)
-	if role == "superadmin" {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		if v := r.URL.Query().Get("company_id"); v != "" {
 			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
 				companyID = float64(id)
@@ -88,23 +110,60 @@ func (h *Handler) GetCameras(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	cameras, err := h.service.GetCamerasForCompany(int64(companyID))
+	scope, err := h.access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	filter := domain.LocationFilterFromQuery(r.URL.Query())
+	cameras, err := h.service.GetCamerasForCompany(int64(companyID), scope, filter)
 	if err != nil {
 		http.Error(w, "Gagal mengambil data kamera", http.StatusInternalServerError)
 		return
 	}
 
+	// Status armed saat ini dari jadwal deteksi; gagal dihitung → flag tidak dikirim.
+	var armed map[int64]bool
+	if h.arm != nil && len(cameras) > 0 {
+		ids := make([]int64, 0, len(cameras))
+		for _, c := range cameras {
+			ids = append(ids, c.ID)
+		}
+		if armed, err = h.arm.ArmedMap(r.Context(), ids, time.Now()); err != nil {
+			log.Printf("hitung status armed kamera: %v", err)
+		}
+	}
+
 	type CameraResp struct {
 		ID        int64  `json:"id"`
 		Name      string `json:"name"`
 		Location  string `json:"location,omitempty"`
+		SiteID    *int64 `json:"site_id,omitempty"`
+		ZoneID    *int64 `json:"zone_id,omitempty"`
 		StreamKey string `json:"stream_key,omitempty"`
 		HLSURL    string `json:"hls_url,omitempty"`
 		RTSPURL   string `json:"rtsp_url,omitempty"`
 		WebRTCURL string `json:"webrtc_url,omitempty"`
+
+		AlertCooldownSeconds *int `json:"alert_cooldown_seconds,omitempty"`
+		domain.AlertFilter
+		Armed       *bool                     `json:"armed,omitempty"`
+		Status      *domain.CameraStatus      `json:"status,omitempty"`
+		Credentials *domain.StreamCredentials `json:"credentials,omitempty"`
 	}
+	// Kredensial publish hanya untuk camera:write; pemegang camera:read cukup kredensial read.
+	canPublish := policy.Has(r.Context(), policy.CameraWrite)
 	out := make([]CameraResp, 0)
 	for _, c := range cameras {
+		creds := c.Credentials
+		if !canPublish {
+			creds = creds.ReadOnly()
+		}
+		var isArmed *bool
+		if a, ok := armed[c.ID]; ok {
+			isArmed = &a
+		}
 		sk := c.StreamKey
 		if sk == "" {
 			sk = "cam" + strconv.FormatInt(c.ID, 10)
@@ -113,10 +172,18 @@ func (h *Handler) GetCameras(w http.ResponseWriter, r *http.Request) {
 			ID:        c.ID,
 			Name:      c.Name,
 			Location:  c.Location,
+			SiteID:    c.SiteID,
+			ZoneID:    c.ZoneID,
 			StreamKey: sk,
 			HLSURL:    buildHLSURL(sk),
 			RTSPURL:   buildRTSPURL(sk),
 			WebRTCURL: buildWebRTCURL(sk),
+
+			AlertCooldownSeconds: c.AlertCooldownSeconds,
+			AlertFilter:          c.AlertFilter,
+			Armed:                isArmed,
+			Status:               c.Status,
+			Credentials:          creds,
 		})
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -129,21 +196,43 @@ func (h *Handler) UpdateCamera(w http.ResponseWriter, r *http.Request) {
 
 	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
 	companyID, _ := claims["company_id"].(float64)
-	role, _ := claims["role"].(string)
+	if !h.cameraAllowed(w, r, id) {
+		return
+	}
 
-	var camera domain.Camera
+	owner := int64(companyID)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		owner = 0
+	}
+	current, err := h.service.GetCamera(id, owner)
+	if err != nil {
+		if errors.Is(err, ErrCameraNotFound) {
+			http.Error(w, "Kamera tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Gagal mengambil data kamera", http.StatusInternalServerError)
+		return
+	}
+
+	// Body diterapkan di atas kamera saat ini: field yang tidak dikirim tetap,
+	// null mengosongkan field opsional (site_id, min_confidence, ...).
+	camera := *current
 	if err := json.NewDecoder(r.Body).Decode(&camera); err != nil {
 		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
 		return
 	}
 
 	camera.ID = id
-	if role == "superadmin" {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		if err := h.service.UpdateCameraAdmin(&camera); err != nil {
 			if errors.Is(err, ErrStreamKeyConflict) {
 				http.Error(w, "stream_key sudah digunakan", http.StatusConflict)
 				return
 			}
+			if errors.Is(err, ErrInvalidPlacement) || errors.Is(err, ErrInvalidAlertSettings) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, "Gagal memperbarui kamera", http.StatusInternalServerError)
 			return
 		}
@@ -154,6 +243,10 @@ func (h *Handler) UpdateCamera(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "stream_key sudah digunakan", http.StatusConflict)
 				return
 			}
+			if errors.Is(err, ErrInvalidPlacement) || errors.Is(err, ErrInvalidAlertSettings) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, "Gagal memperbarui kamera", http.StatusInternalServerError)
 			return
 		}
@@ -167,9 +260,11 @@ func (h *Handler) DeleteCamera(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
 
 	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
-	role, _ := claims["role"].(string)
+	if !h.cameraAllowed(w, r, id) {
+		return
+	}
 
-	if role == "superadmin" {
+	if policy.Has(r.Context(), policy.CompanyManage) {
 		if err := h.service.DeleteCameraAdmin(id); err != nil {
 			http.Error(w, "Gagal menghapus kamera", http.StatusInternalServerError)
 			return
@@ -185,6 +280,21 @@ func (h *Handler) DeleteCamera(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Kamera berhasil dihapus."))
 }
 
+// cameraAllowed menolak kamera di luar cakupan akses pemanggil dengan 404,
+// sama seperti kamera yang tidak ada, supaya keberadaannya tidak bocor.
+func (h *Handler) cameraAllowed(w http.ResponseWriter, r *http.Request, id int64) bool {
+	scope, err := h.access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return false
+	}
+	if !scope.Allows(id) {
+		http.Error(w, "Kamera tidak ditemukan", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
 // Utilities to build stream URLs from env.
 func env(k, def string) string {
 	if v := os.Getenv(k); v != "" {
@@ -0,0 +1,208 @@
+package camera
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// recordingService mencatat kamera yang diubah/dihapus handler. Service asli
+// dipakai untuk validasi; repo-nya menyimpan kamera di memori.
+type recordingService struct {
+	Service
+	updated []domain.Camera
+	deleted []int64
+}
+
+func newRecordingService(cameras ...domain.Camera) *recordingService {
+	repo := &memCameras{cameras: map[int64]domain.Camera{}}
+	for _, c := range cameras {
+		repo.cameras[c.ID] = c
+	}
+	return &recordingService{Service: NewService(repo, nil)}
+}
+
+func (s *recordingService) UpdateCamera(c *domain.Camera) error {
+	if err := s.Service.UpdateCamera(c); err != nil {
+		return err
+	}
+	s.updated = append(s.updated, *c)
+	return nil
+}
+
+func (s *recordingService) DeleteCamera(cameraID, companyID int64) error {
+	s.deleted = append(s.deleted, cameraID)
+	return nil
+}
+
+type memCameras struct {
+	Repository
+	cameras map[int64]domain.Camera
+}
+
+func (m *memCameras) GetCameraByID(ctx context.Context, cameraID int64) (*domain.Camera, error) {
+	c, ok := m.cameras[cameraID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &c, nil
+}
+
+func (m *memCameras) UpdateCamera(c *domain.Camera) error {
+	m.cameras[c.ID] = *c
+	return nil
+}
+
+type fixedScope domain.CameraScope
+
+func (f fixedScope) Scope(ctx context.Context) (domain.CameraScope, error) {
+	return domain.CameraScope(f), nil
+}
+
+func TestUpdateDeleteCameraScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scope  domain.CameraScope
+		method string
+		want   int
+	}{
+		{"ubah kamera dalam cakupan", domain.CameraScope{CameraIDs: []int64{5}}, http.MethodPut, http.StatusOK},
+		{"ubah kamera di luar cakupan", domain.CameraScope{CameraIDs: []int64{6}}, http.MethodPut, http.StatusNotFound},
+		{"hapus kamera dalam cakupan", domain.CameraScope{CameraIDs: []int64{5}}, http.MethodDelete, http.StatusOK},
+		{"hapus kamera di luar cakupan", domain.CameraScope{CameraIDs: []int64{6}}, http.MethodDelete, http.StatusNotFound},
+		{"akses semua kamera", domain.CameraScope{All: true}, http.MethodDelete, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newRecordingService(domain.Camera{ID: 5, CompanyID: 1, Name: "Gudang", AlertFilter: domain.AlertFilter{FilterAction: domain.FilterActionDrop}})
+			h := NewHandler(svc, fixedScope(tt.scope), nil)
+
+			ctx := context.WithValue(context.Background(), auth.UserClaimsKey, jwt.MapClaims{"company_id": float64(1)})
+			ctx = policy.WithPermissions(ctx, policy.NewSet(policy.CameraWrite, policy.CameraDelete))
+			req := httptest.NewRequest(tt.method, "/api/cameras/5", strings.NewReader(`{"name":"Lobi"}`)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			if tt.method == http.MethodDelete {
+				h.DeleteCamera(rec, req)
+			} else {
+				h.UpdateCamera(rec, req)
+			}
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if touched := len(svc.updated) + len(svc.deleted); (touched > 0) != (tt.want == http.StatusOK) {
+				t.Fatalf("kamera diubah/dihapus %d kali, status %d", touched, rec.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateCameraKeepsOmittedFields(t *testing.T) {
+	cooldown := 120
+	minConf := 0.7
+	site, zone := int64(3), int64(4)
+	stored := domain.Camera{
+		ID: 5, CompanyID: 1, Name: "Gudang", Location: "Lantai 1", StreamKey: "gudang", RTSPSource: "rtsp://10.0.0.5/live",
+		SiteID: &site, ZoneID: &zone, AlertCooldownSeconds: &cooldown,
+		AlertFilter: domain.AlertFilter{MinConfidence: &minConf, AnomalyTypes: []string{"fire"}, FilterAction: domain.FilterActionStore},
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, got domain.Camera)
+	}{
+		{
+			name: "hanya name (dashboard admin)",
+			body: `{"name":"Gudang Utara","location":"Lantai 1","stream_key":"gudang"}`,
+			check: func(t *testing.T, got domain.Camera) {
+				want := stored
+				want.Name = "Gudang Utara"
+				if got.Name != want.Name || got.RTSPSource != want.RTSPSource || *got.SiteID != site || *got.ZoneID != zone ||
+					*got.AlertCooldownSeconds != cooldown || *got.MinConfidence != minConf ||
+					len(got.AnomalyTypes) != 1 || got.FilterAction != domain.FilterActionStore {
+					t.Fatalf("kamera = %+v, want %+v", got, want)
+				}
+			},
+		},
+		{
+			name: "null mengosongkan pengaturan",
+			body: `{"min_confidence":null,"alert_cooldown_seconds":null,"anomaly_types":[]}`,
+			check: func(t *testing.T, got domain.Camera) {
+				if got.MinConfidence != nil || got.AlertCooldownSeconds != nil || len(got.AnomalyTypes) != 0 {
+					t.Fatalf("kamera = %+v, want pengaturan kosong", got)
+				}
+				if got.Name != "Gudang" || got.FilterAction != domain.FilterActionStore {
+					t.Fatalf("field lain ikut berubah: %+v", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newRecordingService(stored)
+			h := NewHandler(svc, fixedScope{All: true}, nil)
+
+			ctx := context.WithValue(context.Background(), auth.UserClaimsKey, jwt.MapClaims{"company_id": float64(1)})
+			req := httptest.NewRequest(http.MethodPut, "/api/cameras/5", strings.NewReader(tt.body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			h.UpdateCamera(rec, req)
+			if rec.Code != http.StatusOK || len(svc.updated) != 1 {
+				t.Fatalf("status = %d (%s)", rec.Code, rec.Body.String())
+			}
+			tt.check(t, svc.updated[0])
+		})
+	}
+}
+
+func TestUpdateCameraOtherCompany(t *testing.T) {
+	svc := newRecordingService(domain.Camera{ID: 5, CompanyID: 2, Name: "Lain"})
+	h := NewHandler(svc, fixedScope{All: true}, nil)
+
+	ctx := context.WithValue(context.Background(), auth.UserClaimsKey, jwt.MapClaims{"company_id": float64(1)})
+	rec := httptest.NewRecorder()
+	h.UpdateCamera(rec, httptest.NewRequest(http.MethodPut, "/api/cameras/5", strings.NewReader(`{"name":"x"}`)).WithContext(ctx))
+	if rec.Code != http.StatusNotFound || len(svc.updated) != 0 {
+		t.Fatalf("status = %d, updated %d; want 404 tanpa perubahan", rec.Code, len(svc.updated))
+	}
+}
+
+func TestFilterActionDefault(t *testing.T) {
+	invalid := &domain.Camera{}
+	if err := validateAlertSettings(invalid); !errors.Is(err, ErrInvalidAlertSettings) {
+		t.Fatalf("filter_action kosong saat update: err = %v, want ErrInvalidAlertSettings", err)
+	}
+
+	repo := &memCameras{cameras: map[int64]domain.Camera{}}
+	created := &domain.Camera{Name: "Baru", CompanyID: 1}
+	if _, err := NewService(&createRepo{memCameras: repo}, nil).RegisterCamera(created); err != nil {
+		t.Fatal(err)
+	}
+	if created.FilterAction != domain.FilterActionDrop {
+		t.Fatalf("filter_action kamera baru = %q, want drop", created.FilterAction)
+	}
+}
+
+// createRepo menerima kamera baru tanpa kredensial stream.
+type createRepo struct {
+	*memCameras
+}
+
+func (r *createRepo) CreateCamera(c *domain.Camera) (int64, error) {
+	c.ID = int64(len(r.cameras) + 1)
+	r.cameras[c.ID] = *c
+	return c.ID, nil
+}
+
+func (r *createRepo) EnsureStreamCredentials(ctx context.Context, cameraID int64, creds *domain.StreamCredentials) (*domain.StreamCredentials, error) {
+	return creds, nil
+}
@@ -0,0 +1,195 @@
+package camera
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/events"
+	"cctv-main-backend/pkg/mediamtx"
+	"context"
+	"log"
+	"time"
+)
+
+// PathLister adalah bagian klien MediaMTX yang dipakai monitor.
+type PathLister interface {
+	ListPaths(ctx context.Context) ([]mediamtx.Path, error)
+}
+
+// Monitor memantau status streaming kamera lewat control API MediaMTX: path
+// stream_key yang ready berarti online. Transisi dicatat di camera_status dan
+// diterbitkan sebagai camera.online/camera.offline; kamera yang offline lebih
+// lama dari Grace dikirimi notifikasi "kamera offline" satu kali per periode
+// lewat outbox notifikasi.
+//
+// Jalankan monitor di satu replika saja agar transisi tidak tercatat ganda.
+type Monitor struct {
+	repo   Repository
+	client PathLister
+
+	Interval time.Duration
+	Grace    time.Duration
+
+	// Opsional
+	Publish events.Publisher
+	// Wake membangunkan dispatcher outbox setelah notifikasi offline diantrekan.
+	Wake func()
+	// Enqueue mengantrekan camera.online/camera.offline ke webhook perusahaan.
+	Enqueue func(ctx context.Context, companyID, cameraID int64, event string, data any) (int, error)
+
+	// byte diterima per kamera pada polling sebelumnya, untuk menghitung bitrate
+	lastBytes map[int64]sample
+}
+
+type sample struct {
+	bytes uint64
+	at    time.Time
+}
+
+func NewMonitor(repo Repository, client PathLister) *Monitor {
+	return &Monitor{
+		repo:      repo,
+		client:    client,
+		Interval:  15 * time.Second,
+		Grace:     2 * time.Minute,
+		lastBytes: map[int64]sample{},
+	}
+}
+
+// Run melakukan polling setiap Interval sampai ctx dibatalkan.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Poll(ctx); err != nil {
+			log.Printf("camera monitor: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll menjalankan satu putaran. Bila API MediaMTX tidak bisa dihubungi putaran
+// dilewati tanpa menandai kamera offline.
+func (m *Monitor) Poll(ctx context.Context) error {
+	paths, err := m.client.ListPaths(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]mediamtx.Path, len(paths))
+	for _, p := range paths {
+		byName[p.Name] = p
+	}
+	cameras, err := m.repo.ListForMonitor(ctx)
+	if err != nil {
+		return err
+	}
+	statuses, err := m.repo.GetStatuses(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, cam := range cameras {
+		p, found := byName[cam.StreamKey]
+		prev, known := statuses[cam.ID]
+		st := m.observe(cam, p, found && p.Ready, now)
+		transition := known && prev.Online != st.Online
+
+		switch {
+		case !known || transition:
+			st.Since = now
+		default:
+			st.Since = prev.Since
+			st.OfflineNotifiedAt = prev.OfflineNotifiedAt
+		}
+		if !st.Online {
+			st.LastSeenAt = prev.LastSeenAt
+		}
+		if err := m.repo.SaveStatus(ctx, st, transition); err != nil {
+			log.Printf("camera monitor: simpan status kamera %d: %v", cam.ID, err)
+			continue
+		}
+		if transition {
+			m.publish(ctx, cam, st)
+		}
+		if m.shouldNotify(st, now) {
+			m.notifyOffline(ctx, cam, st)
+		}
+	}
+	return nil
+}
+
+// observe membentuk status dari path MediaMTX; bitrate dihitung dari selisih
+// bytesReceived terhadap polling sebelumnya.
+func (m *Monitor) observe(cam domain.MonitoredCamera, p mediamtx.Path, online bool, now time.Time) *domain.CameraStatus {
+	st := &domain.CameraStatus{CameraID: cam.ID, Online: online, UpdatedAt: now}
+	if !online {
+		delete(m.lastBytes, cam.ID)
+		return st
+	}
+	seen := now
+	st.LastSeenAt = &seen
+	st.Codec, st.Width, st.Height = p.Video()
+	if last, ok := m.lastBytes[cam.ID]; ok && p.BytesReceived >= last.bytes {
+		if secs := now.Sub(last.at).Seconds(); secs > 0 {
+			kbps := int(float64(p.BytesReceived-last.bytes) * 8 / 1000 / secs)
+			st.BitrateKbps = &kbps
+		}
+	}
+	m.lastBytes[cam.ID] = sample{bytes: p.BytesReceived, at: now}
+	return st
+}
+
+// shouldNotify: kamera offline melewati masa tenggang, belum diberi tahu, dan
+// pernah online (kamera yang belum pernah streaming tidak dianggap gangguan).
+func (m *Monitor) shouldNotify(st *domain.CameraStatus, now time.Time) bool {
+	return !st.Online && st.OfflineNotifiedAt == nil &&
+		st.LastSeenAt != nil && now.Sub(st.Since) >= m.Grace
+}
+
+// notifyOffline mengantrekan laporan semu bertipe camera_offline ke outbox;
+// dispatcher yang mengirim dan mencoba ulang. Bila gagal diantrekan, dicoba lagi
+// pada polling berikutnya.
+func (m *Monitor) notifyOffline(ctx context.Context, cam domain.MonitoredCamera, st *domain.CameraStatus) {
+	report := &domain.AnomalyReport{
+		CameraID:    cam.ID,
+		CompanyID:   cam.CompanyID,
+		AnomalyType: domain.AnomalyTypeCameraOffline,
+		Confidence:  1,
+		ReportedAt:  st.Since,
+		Occurrences: 1,
+		LastSeenAt:  st.Since,
+	}
+	if err := m.repo.QueueOfflineAlert(ctx, report, time.Now()); err != nil {
+		log.Printf("camera monitor: antrekan notifikasi offline kamera %d: %v", cam.ID, err)
+		return
+	}
+	if m.Wake != nil {
+		m.Wake()
+	}
+	log.Printf("camera monitor: kamera %d (%s) offline sejak %s, notifikasi diantrekan", cam.ID, cam.Name, st.Since.Format(time.RFC3339))
+}
+
+func (m *Monitor) publish(ctx context.Context, cam domain.MonitoredCamera, st *domain.CameraStatus) {
+	typ := events.CameraOffline
+	if st.Online {
+		typ = events.CameraOnline
+	}
+	if m.Enqueue != nil {
+		if _, err := m.Enqueue(ctx, cam.CompanyID, cam.ID, typ, st); err != nil {
+			log.Printf("camera monitor: antrekan webhook %s kamera %d: %v", typ, cam.ID, err)
+		}
+	}
+	if m.Publish == nil {
+		return
+	}
+	m.Publish.Publish(events.Event{
+		Type:      typ,
+		CompanyID: cam.CompanyID,
+		CameraID:  cam.ID,
+		Time:      st.Since,
+		Data:      st,
+	})
+}
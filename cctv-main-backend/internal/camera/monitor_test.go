@@ -0,0 +1,199 @@
+package camera
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/events"
+	"cctv-main-backend/pkg/mediamtx"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMediaMTX melayani /v3/paths/list dari daftar path yang bisa diubah test.
+type fakeMediaMTX struct {
+	mu     sync.Mutex
+	paths  []mediamtx.Path
+	status int // selain 0: balas dengan kode ini
+}
+
+func (f *fakeMediaMTX) set(status int, paths ...mediamtx.Path) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.paths = status, paths
+}
+
+func (f *fakeMediaMTX) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v3/paths/list" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unavailable"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"pageCount": 1, "items": f.paths})
+}
+
+// monitorRepo menyimpan status di memori; metode Repository lain tidak dipakai monitor.
+type monitorRepo struct {
+	Repository
+
+	cameras     []domain.MonitoredCamera
+	statuses    map[int64]domain.CameraStatus
+	transitions int
+	alerts      []*domain.AnomalyReport
+}
+
+func (r *monitorRepo) ListForMonitor(ctx context.Context) ([]domain.MonitoredCamera, error) {
+	return r.cameras, nil
+}
+
+func (r *monitorRepo) GetStatuses(ctx context.Context) (map[int64]domain.CameraStatus, error) {
+	out := make(map[int64]domain.CameraStatus, len(r.statuses))
+	for id, st := range r.statuses {
+		out[id] = st
+	}
+	return out, nil
+}
+
+func (r *monitorRepo) SaveStatus(ctx context.Context, st *domain.CameraStatus, transition bool) error {
+	r.statuses[st.CameraID] = *st
+	if transition {
+		r.transitions++
+	}
+	return nil
+}
+
+func (r *monitorRepo) QueueOfflineAlert(ctx context.Context, report *domain.AnomalyReport, at time.Time) error {
+	st := r.statuses[report.CameraID]
+	if st.Online || st.OfflineNotifiedAt != nil {
+		return nil
+	}
+	st.OfflineNotifiedAt = &at
+	r.statuses[report.CameraID] = st
+	r.alerts = append(r.alerts, report)
+	return nil
+}
+
+type recordedEvents struct{ types []string }
+
+func (p *recordedEvents) Publish(ev events.Event) { p.types = append(p.types, ev.Type) }
+
+func newTestMonitor(t *testing.T) (*Monitor, *fakeMediaMTX, *monitorRepo, *recordedEvents, *int) {
+	t.Helper()
+	mtx := &fakeMediaMTX{}
+	srv := httptest.NewServer(mtx)
+	t.Cleanup(srv.Close)
+
+	repo := &monitorRepo{
+		cameras: []domain.MonitoredCamera{
+			{ID: 1, CompanyID: 7, Name: "Lobi", StreamKey: "cam1"},
+			{ID: 2, CompanyID: 7, Name: "Gudang", StreamKey: "cam2"},
+		},
+		statuses: map[int64]domain.CameraStatus{},
+	}
+	pub := &recordedEvents{}
+	wakes := new(int)
+	m := NewMonitor(repo, mediamtx.NewClient(srv.URL, "", ""))
+	m.Grace = time.Hour
+	m.Publish = pub
+	m.Wake = func() { *wakes++ }
+	return m, mtx, repo, pub, wakes
+}
+
+func TestMonitorOfflineAlert(t *testing.T) {
+	m, mtx, repo, pub, wakes := newTestMonitor(t)
+	ctx := context.Background()
+	cam1 := mediamtx.Path{Name: "cam1", Ready: true, BytesReceived: 1000,
+		Tracks2: []mediamtx.Track{{Codec: "H264"}}}
+	cam1.Tracks2[0].CodecProps.Width, cam1.Tracks2[0].CodecProps.Height = 1920, 1080
+
+	// Polling pertama hanya mencatat status awal, tanpa transisi.
+	mtx.set(0, cam1)
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if st := repo.statuses[1]; !st.Online || st.Codec != "H264" || st.Width != 1920 || st.LastSeenAt == nil {
+		t.Fatalf("status cam1 = %+v", st)
+	}
+	if st := repo.statuses[2]; st.Online {
+		t.Fatal("cam2 tanpa path dianggap online")
+	}
+	if repo.transitions != 0 || len(pub.types) != 0 {
+		t.Fatalf("polling pertama: %d transisi, event %v", repo.transitions, pub.types)
+	}
+
+	// Bitrate dihitung dari selisih bytesReceived.
+	cam1.BytesReceived = 1_000_000
+	mtx.set(0, cam1)
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if st := repo.statuses[1]; st.BitrateKbps == nil || *st.BitrateKbps <= 0 {
+		t.Fatalf("bitrate cam1 = %v", st.BitrateKbps)
+	}
+
+	// cam1 berhenti: transisi offline, tetapi masih dalam masa tenggang.
+	mtx.set(0)
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if repo.transitions != 1 || len(pub.types) != 1 || pub.types[0] != events.CameraOffline {
+		t.Fatalf("transisi offline: %d transisi, event %v", repo.transitions, pub.types)
+	}
+	if len(repo.alerts) != 0 {
+		t.Fatal("notifikasi offline diantrekan sebelum masa tenggang habis")
+	}
+
+	// Masa tenggang habis: satu alert ke outbox, hanya untuk kamera yang pernah online.
+	m.Grace = 0
+	for i := 0; i < 2; i++ {
+		if err := m.Poll(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(repo.alerts) != 1 || *wakes != 1 {
+		t.Fatalf("diantrekan %d alert dan %d wake, want 1 dan 1", len(repo.alerts), *wakes)
+	}
+	a := repo.alerts[0]
+	if a.CameraID != 1 || a.CompanyID != 7 || a.AnomalyType != domain.AnomalyTypeCameraOffline || !a.ReportedAt.Equal(repo.statuses[1].Since) {
+		t.Fatalf("alert = %+v", a)
+	}
+
+	// Online lagi: event online dan tanda notifikasi dikosongkan.
+	mtx.set(0, cam1)
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if st := repo.statuses[1]; !st.Online || st.OfflineNotifiedAt != nil {
+		t.Fatalf("status cam1 setelah online = %+v", st)
+	}
+	if got := pub.types[len(pub.types)-1]; got != events.CameraOnline {
+		t.Fatalf("event terakhir = %s, want %s", got, events.CameraOnline)
+	}
+}
+
+func TestMonitorSkipsPollWhenMediaMTXFails(t *testing.T) {
+	m, mtx, repo, pub, _ := newTestMonitor(t)
+	ctx := context.Background()
+
+	mtx.set(0, mediamtx.Path{Name: "cam1", Ready: true})
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.Grace = 0
+	mtx.set(http.StatusInternalServerError)
+	if err := m.Poll(ctx); err == nil {
+		t.Fatal("Poll tidak mengembalikan error saat MediaMTX gagal")
+	}
+	if !repo.statuses[1].Online || repo.transitions != 0 || len(pub.types) != 0 || len(repo.alerts) != 0 {
+		t.Fatalf("API gagal mengubah status: %+v, %d transisi, event %v", repo.statuses[1], repo.transitions, pub.types)
+	}
+}
@@ -0,0 +1,103 @@
+package camera
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/mediamtx"
+	"context"
+	"log"
+	"time"
+)
+
+// PathConfigurer adalah bagian klien MediaMTX untuk mengatur konfigurasi path.
+type PathConfigurer interface {
+	ListPathConfs(ctx context.Context) ([]mediamtx.PathConf, error)
+	AddPath(ctx context.Context, name string, conf mediamtx.PathConf) error
+	DeletePath(ctx context.Context, name string) error
+}
+
+// Provisioner membuat path MediaMTX per kamera lewat control API. Path yang
+// ditambahkan lewat API hilang saat MediaMTX restart, jadi Run menyamakan ulang
+// semua kamera secara berkala; kredensial tetap di database dan diperiksa oleh
+// StreamAuth.
+type Provisioner struct {
+	repo   Repository
+	client PathConfigurer
+
+	Interval time.Duration
+}
+
+func NewProvisioner(repo Repository, client PathConfigurer) *Provisioner {
+	return &Provisioner{repo: repo, client: client, Interval: time.Minute}
+}
+
+// pathConf: kamera IP dengan rtsp_source ditarik terus-menerus (dipakai AI worker
+// dan arsip), selain itu path menunggu kamera/encoder melakukan publish.
+func pathConf(rtspSource string) mediamtx.PathConf {
+	if rtspSource != "" {
+		return mediamtx.PathConf{Source: rtspSource}
+	}
+	return mediamtx.PathConf{Source: "publisher"}
+}
+
+func (p *Provisioner) Provision(ctx context.Context, streamKey, rtspSource string) error {
+	return p.client.AddPath(ctx, streamKey, pathConf(rtspSource))
+}
+
+func (p *Provisioner) Remove(ctx context.Context, streamKey string) error {
+	return p.client.DeletePath(ctx, streamKey)
+}
+
+// Run menjalankan Sync setiap Interval sampai ctx dibatalkan.
+func (p *Provisioner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Sync(ctx); err != nil {
+			log.Printf("mediamtx provisioning: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync membuat kredensial yang belum ada (kamera lama) dan menambah atau
+// memperbarui path yang hilang atau sumbernya berbeda. Path yang bukan milik
+// kamera mana pun dibiarkan; tanpa kredensial, StreamAuth menolak aksesnya.
+func (p *Provisioner) Sync(ctx context.Context) error {
+	cameras, err := p.repo.ListForMonitor(ctx)
+	if err != nil {
+		return err
+	}
+	confs, err := p.client.ListPathConfs(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]mediamtx.PathConf, len(confs))
+	for _, c := range confs {
+		existing[c.Name] = c
+	}
+
+	for _, cam := range cameras {
+		if !cam.HasCredentials {
+			creds, err := domain.NewStreamCredentials(cam.StreamKey)
+			if err == nil {
+				_, err = p.repo.EnsureStreamCredentials(ctx, cam.ID, creds)
+			}
+			if err != nil {
+				log.Printf("mediamtx provisioning: kredensial kamera %d: %v", cam.ID, err)
+				continue
+			}
+		}
+		want := pathConf(cam.RTSPSource)
+		if have, ok := existing[cam.StreamKey]; ok && have.Source == want.Source {
+			continue
+		}
+		if err := p.client.AddPath(ctx, cam.StreamKey, want); err != nil {
+			log.Printf("mediamtx provisioning: path %s: %v", cam.StreamKey, err)
+		}
+	}
+	return nil
+}
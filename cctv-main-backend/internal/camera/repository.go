@@ -4,22 +4,43 @@ import (
 	"cctv-main-backend/internal/domain"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	pqx "github.com/lib/pq"
 )
 
 type Repository interface {
     CreateCamera(camera *domain.Camera) (int64, error)
-    GetCamerasByCompanyID(companyID int64) ([]domain.Camera, error)
+    // GetCamerasByCompanyID hanya mengembalikan kamera yang diizinkan scope,
+    // opsional disaring per site/zone.
+    GetCamerasByCompanyID(companyID int64, scope domain.CameraScope, filter domain.LocationFilter) ([]domain.Camera, error)
     UpdateCamera(camera *domain.Camera) error
     DeleteCamera(cameraID int64, companyID int64) error
     // NEW: ambil company_id berdasarkan camera_id (untuk FCM)
     GetCompanyIDByCameraID(ctx context.Context, cameraID int64) (int64, error)
+    // GetCameraByID tanpa filter perusahaan; dipakai notifier untuk nama dan lokasi kamera.
+    GetCameraByID(ctx context.Context, cameraID int64) (*domain.Camera, error)
     // Admin variants: no company filter
     UpdateCameraAdmin(camera *domain.Camera) error
     DeleteCameraAdmin(cameraID int64) error
+
+    // Status streaming (monitor MediaMTX)
+    ListForMonitor(ctx context.Context) ([]domain.MonitoredCamera, error)
+    GetStatuses(ctx context.Context) (map[int64]domain.CameraStatus, error)
+    // SaveStatus menyimpan status terbaru; transition=true juga mencatat riwayat online/offline.
+    SaveStatus(ctx context.Context, st *domain.CameraStatus, transition bool) error
+    // QueueOfflineAlert menulis laporan camera_offline ke outbox notifikasi dan menandai
+    // kamera sudah diberi tahu dalam satu transaksi.
+    QueueOfflineAlert(ctx context.Context, report *domain.AnomalyReport, at time.Time) error
+
+    // Kredensial path MediaMTX
+    // EnsureStreamCredentials menyimpan creds bila kamera belum punya, lalu mengembalikan yang tersimpan.
+    EnsureStreamCredentials(ctx context.Context, cameraID int64, creds *domain.StreamCredentials) (*domain.StreamCredentials, error)
+    // GetStreamCredentials mencari kredensial berdasarkan nama path (stream key); nil bila tidak ada.
+    GetStreamCredentials(ctx context.Context, streamKey string) (*domain.StreamCredentials, error)
 }
 
 type repository struct {
@@ -31,11 +52,16 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) CreateCamera(camera *domain.Camera) (int64, error) {
+	if err := r.checkPlacement(camera.CompanyID, camera); err != nil {
+		return 0, err
+	}
 	var cameraID int64
 	// Insert dulu; stream_key bisa dikosongkan, nanti diisi 'cam<id>' bila tidak diberikan
-	query := `INSERT INTO cameras (name, location, company_id, stream_key, rtsp_source)
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	err := r.db.QueryRow(query, camera.Name, camera.Location, camera.CompanyID, camera.StreamKey, camera.RTSPSource).Scan(&cameraID)
+	query := `INSERT INTO cameras (name, location, company_id, stream_key, rtsp_source, site_id, zone_id, alert_cooldown_seconds,
+                                   min_confidence, anomaly_types, filter_action)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err := r.db.QueryRow(query, camera.Name, camera.Location, camera.CompanyID, camera.StreamKey, camera.RTSPSource, camera.SiteID, camera.ZoneID, camera.AlertCooldownSeconds,
+		camera.MinConfidence, pqx.Array(camera.AnomalyTypes), camera.FilterAction).Scan(&cameraID)
 	if err != nil {
 		if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
 			return 0, ErrStreamKeyConflict
@@ -50,9 +76,17 @@ func (r *repository) CreateCamera(camera *domain.Camera) (int64, error) {
 	return cameraID, nil
 }
 
-func (r *repository) GetCamerasByCompanyID(companyID int64) ([]domain.Camera, error) {
-	query := `SELECT id, name, location, stream_key, rtsp_source, company_id, created_at FROM cameras WHERE company_id = $1 ORDER BY created_at DESC`
-	rows, err := r.db.Query(query, companyID)
+func (r *repository) GetCamerasByCompanyID(companyID int64, scope domain.CameraScope, filter domain.LocationFilter) ([]domain.Camera, error) {
+	query := `SELECT c.id, c.name, c.location, c.stream_key, c.rtsp_source, c.company_id, c.site_id, c.zone_id, c.alert_cooldown_seconds,
+                     c.min_confidence, c.anomaly_types, c.filter_action, c.created_at,
+                     s.online, s.since, s.last_seen_at, s.bitrate_kbps, s.codec, s.width, s.height, s.updated_at,
+                     k.publish_user, k.publish_pass, k.read_user, k.read_pass
+              FROM cameras c LEFT JOIN camera_status s ON s.camera_id = c.id
+              LEFT JOIN camera_stream_credentials k ON k.camera_id = c.id
+              WHERE c.company_id = $1 AND ($2 OR c.id = ANY($3))
+                AND ($4 = 0 OR c.site_id = $4) AND ($5 = 0 OR c.zone_id = $5)
+              ORDER BY c.created_at DESC`
+	rows, err := r.db.Query(query, companyID, scope.All, pqx.Array(scope.CameraIDs), filter.SiteID, filter.ZoneID)
 	if err != nil {
 		return nil, err
 	}
@@ -61,18 +95,52 @@ func (r *repository) GetCamerasByCompanyID(companyID int64) ([]domain.Camera, er
 	var cameras []domain.Camera
 	for rows.Next() {
 		var cam domain.Camera
-		if err := rows.Scan(&cam.ID, &cam.Name, &cam.Location, &cam.StreamKey, &cam.RTSPSource, &cam.CompanyID, &cam.CreatedAt); err != nil {
+		var siteID, zoneID, cooldown sql.NullInt64
+		var minConf sql.NullFloat64
+		var types pqx.StringArray
+		var st statusRow
+		var pubUser, pubPass, readUser, readPass sql.NullString
+		if err := rows.Scan(&cam.ID, &cam.Name, &cam.Location, &cam.StreamKey, &cam.RTSPSource, &cam.CompanyID, &siteID, &zoneID, &cooldown,
+			&minConf, &types, &cam.FilterAction, &cam.CreatedAt,
+			&st.online, &st.since, &st.lastSeen, &st.bitrate, &st.codec, &st.width, &st.height, &st.updatedAt,
+			&pubUser, &pubPass, &readUser, &readPass); err != nil {
 			return nil, err
 		}
+		cam.Status = st.status(cam.ID)
+		if readUser.Valid {
+			cam.Credentials = &domain.StreamCredentials{
+				PublishUser: pubUser.String, PublishPass: pubPass.String,
+				ReadUser: readUser.String, ReadPass: readPass.String,
+			}
+		}
+		if minConf.Valid {
+			cam.MinConfidence = &minConf.Float64
+		}
+		cam.AnomalyTypes = []string(types)
+		if cooldown.Valid {
+			secs := int(cooldown.Int64)
+			cam.AlertCooldownSeconds = &secs
+		}
+		if siteID.Valid {
+			cam.SiteID = &siteID.Int64
+		}
+		if zoneID.Valid {
+			cam.ZoneID = &zoneID.Int64
+		}
 		cameras = append(cameras, cam)
 	}
 	return cameras, nil
 }
 
 func (r *repository) UpdateCamera(camera *domain.Camera) error {
-    query := `UPDATE cameras SET name = $1, location = $2, stream_key = COALESCE(NULLIF($3,''), stream_key), rtsp_source = $4 WHERE id = $5 AND company_id = $6`
+	if err := r.checkPlacement(camera.CompanyID, camera); err != nil {
+		return err
+	}
+    query := `UPDATE cameras SET name = $1, location = $2, stream_key = COALESCE(NULLIF($3,''), stream_key), rtsp_source = $4, site_id = $5, zone_id = $6, alert_cooldown_seconds = $9,
+              min_confidence = $10, anomaly_types = $11, filter_action = $12 WHERE id = $7 AND company_id = $8`
 
-	result, err := r.db.Exec(query, camera.Name, camera.Location, camera.StreamKey, camera.RTSPSource, camera.ID, camera.CompanyID)
+	result, err := r.db.Exec(query, camera.Name, camera.Location, camera.StreamKey, camera.RTSPSource, camera.SiteID, camera.ZoneID, camera.ID, camera.CompanyID, camera.AlertCooldownSeconds,
+		camera.MinConfidence, pqx.Array(camera.AnomalyTypes), camera.FilterAction)
 	if err != nil {
 		if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
 			return ErrStreamKeyConflict
@@ -94,6 +162,40 @@ func (r *repository) UpdateCamera(camera *domain.Camera) error {
 
 var ErrStreamKeyConflict = errors.New("stream_key already exists")
 
+// ErrInvalidPlacement: site/zone tidak ada, bukan milik perusahaan kamera, atau zone bukan bagian dari site.
+var ErrInvalidPlacement = errors.New("site/zone tidak valid untuk kamera ini")
+
+// checkPlacement memvalidasi site_id/zone_id kamera terhadap companyID.
+// Bila hanya zone_id yang diisi, site_id diisi otomatis dari zone tersebut.
+func (r *repository) checkPlacement(companyID int64, camera *domain.Camera) error {
+	if camera.ZoneID != nil {
+		var siteID int64
+		err := r.db.QueryRow(`SELECT z.site_id FROM zones z JOIN sites s ON s.id = z.site_id
+                              WHERE z.id = $1 AND s.company_id = $2`, *camera.ZoneID, companyID).Scan(&siteID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidPlacement
+		}
+		if err != nil {
+			return err
+		}
+		if camera.SiteID != nil && *camera.SiteID != siteID {
+			return ErrInvalidPlacement
+		}
+		camera.SiteID = &siteID
+		return nil
+	}
+	if camera.SiteID != nil {
+		var ok bool
+		if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sites WHERE id = $1 AND company_id = $2)`, *camera.SiteID, companyID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidPlacement
+		}
+	}
+	return nil
+}
+
 func (r *repository) DeleteCamera(cameraID int64, companyID int64) error {
     query := `DELETE FROM cameras WHERE id = $1 AND company_id = $2`
 
@@ -114,6 +216,40 @@ func (r *repository) DeleteCamera(cameraID int64, companyID int64) error {
 	return nil
 }
 
+func (r *repository) GetCameraByID(ctx context.Context, cameraID int64) (*domain.Camera, error) {
+	var c domain.Camera
+	var location, streamKey, rtspSource sql.NullString
+	var siteID, zoneID, cooldown sql.NullInt64
+	var minConf sql.NullFloat64
+	var types pqx.StringArray
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, location, stream_key, rtsp_source, company_id, site_id, zone_id, alert_cooldown_seconds,
+		       min_confidence, anomaly_types, filter_action, created_at
+		FROM cameras WHERE id = $1`, cameraID,
+	).Scan(&c.ID, &c.Name, &location, &streamKey, &rtspSource, &c.CompanyID, &siteID, &zoneID, &cooldown, &minConf, &types, &c.FilterAction, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.Location = location.String
+	c.StreamKey = streamKey.String
+	c.RTSPSource = rtspSource.String
+	if siteID.Valid {
+		c.SiteID = &siteID.Int64
+	}
+	if zoneID.Valid {
+		c.ZoneID = &zoneID.Int64
+	}
+	if cooldown.Valid {
+		secs := int(cooldown.Int64)
+		c.AlertCooldownSeconds = &secs
+	}
+	if minConf.Valid {
+		c.MinConfidence = &minConf.Float64
+	}
+	c.AnomalyTypes = []string(types)
+	return &c, nil
+}
+
 // NEW: lookup company_id dari camera_id (dipakai FCM untuk ambil token admin per company)
 func (r *repository) GetCompanyIDByCameraID(ctx context.Context, cameraID int64) (int64, error) {
 	var companyID int64
@@ -130,9 +266,18 @@ func (r *repository) GetCompanyIDByCameraID(ctx context.Context, cameraID int64)
 
 // Admin variants
 func (r *repository) UpdateCameraAdmin(camera *domain.Camera) error {
-    query := `UPDATE cameras SET name = $1, location = $2, stream_key = COALESCE(NULLIF($3,''), stream_key), rtsp_source = $4 WHERE id = $5`
+    companyID, err := r.GetCompanyIDByCameraID(context.Background(), camera.ID)
+    if err != nil {
+        return err
+    }
+    if err := r.checkPlacement(companyID, camera); err != nil {
+        return err
+    }
+    query := `UPDATE cameras SET name = $1, location = $2, stream_key = COALESCE(NULLIF($3,''), stream_key), rtsp_source = $4, site_id = $5, zone_id = $6, alert_cooldown_seconds = $8,
+              min_confidence = $9, anomaly_types = $10, filter_action = $11 WHERE id = $7`
 
-    result, err := r.db.Exec(query, camera.Name, camera.Location, camera.StreamKey, camera.RTSPSource, camera.ID)
+    result, err := r.db.Exec(query, camera.Name, camera.Location, camera.StreamKey, camera.RTSPSource, camera.SiteID, camera.ZoneID, camera.ID, camera.AlertCooldownSeconds,
+        camera.MinConfidence, pqx.Array(camera.AnomalyTypes), camera.FilterAction)
     if err != nil {
         if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
             return ErrStreamKeyConflict
@@ -164,3 +309,168 @@ func (r *repository) DeleteCameraAdmin(cameraID int64) error {
     }
     return nil
 }
+
+// statusRow menampung kolom camera_status hasil LEFT JOIN (semua bisa NULL).
+type statusRow struct {
+	online                     sql.NullBool
+	since, lastSeen, updatedAt sql.NullTime
+	bitrate, width, height     sql.NullInt64
+	codec                      sql.NullString
+	notified                   sql.NullTime
+}
+
+func (s statusRow) status(cameraID int64) *domain.CameraStatus {
+	if !s.online.Valid {
+		return nil
+	}
+	st := &domain.CameraStatus{
+		CameraID:  cameraID,
+		Online:    s.online.Bool,
+		Since:     s.since.Time,
+		Codec:     s.codec.String,
+		Width:     int(s.width.Int64),
+		Height:    int(s.height.Int64),
+		UpdatedAt: s.updatedAt.Time,
+	}
+	if s.lastSeen.Valid {
+		st.LastSeenAt = &s.lastSeen.Time
+	}
+	if s.bitrate.Valid {
+		kbps := int(s.bitrate.Int64)
+		st.BitrateKbps = &kbps
+	}
+	if s.notified.Valid {
+		st.OfflineNotifiedAt = &s.notified.Time
+	}
+	return st
+}
+
+func (r *repository) ListForMonitor(ctx context.Context) ([]domain.MonitoredCamera, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.company_id, c.name, COALESCE(NULLIF(c.stream_key, ''), 'cam' || c.id), COALESCE(c.rtsp_source, ''),
+		       EXISTS (SELECT 1 FROM camera_stream_credentials k WHERE k.camera_id = c.id)
+		FROM cameras c ORDER BY c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []domain.MonitoredCamera
+	for rows.Next() {
+		var c domain.MonitoredCamera
+		if err := rows.Scan(&c.ID, &c.CompanyID, &c.Name, &c.StreamKey, &c.RTSPSource, &c.HasCredentials); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) GetStatuses(ctx context.Context) (map[int64]domain.CameraStatus, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT camera_id, online, since, last_seen_at, bitrate_kbps, codec, width, height, updated_at, offline_notified_at
+		FROM camera_status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := map[int64]domain.CameraStatus{}
+	for rows.Next() {
+		var id int64
+		var st statusRow
+		if err := rows.Scan(&id, &st.online, &st.since, &st.lastSeen, &st.bitrate, &st.codec, &st.width, &st.height, &st.updatedAt, &st.notified); err != nil {
+			return nil, err
+		}
+		statuses[id] = *st.status(id)
+	}
+	return statuses, rows.Err()
+}
+
+func (r *repository) SaveStatus(ctx context.Context, st *domain.CameraStatus, transition bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO camera_status (camera_id, online, since, last_seen_at, bitrate_kbps, codec, width, height, offline_notified_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, 0), NULLIF($8, 0), $9, NOW())
+		ON CONFLICT (camera_id) DO UPDATE SET
+			online = EXCLUDED.online, since = EXCLUDED.since, last_seen_at = EXCLUDED.last_seen_at,
+			bitrate_kbps = EXCLUDED.bitrate_kbps, codec = EXCLUDED.codec, width = EXCLUDED.width, height = EXCLUDED.height,
+			offline_notified_at = EXCLUDED.offline_notified_at, updated_at = NOW()`,
+		st.CameraID, st.Online, st.Since, st.LastSeenAt, st.BitrateKbps, st.Codec, st.Width, st.Height, st.OfflineNotifiedAt)
+	if err != nil {
+		return err
+	}
+	if transition {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO camera_status_events (camera_id, online, created_at) VALUES ($1, $2, $3)`,
+			st.CameraID, st.Online, st.Since); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *repository) QueueOfflineAlert(ctx context.Context, report *domain.AnomalyReport, at time.Time) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE camera_status SET offline_notified_at = $2
+		WHERE camera_id = $1 AND NOT online AND offline_notified_at IS NULL`, report.CameraID, at)
+	if err != nil {
+		return err
+	}
+	// Kamera sudah online lagi atau sudah diberi tahu: tidak ada yang perlu dikirim.
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO notification_outbox (report) VALUES ($1)`, data); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) EnsureStreamCredentials(ctx context.Context, cameraID int64, creds *domain.StreamCredentials) (*domain.StreamCredentials, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO camera_stream_credentials (camera_id, publish_user, publish_pass, read_user, read_pass)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (camera_id) DO NOTHING`,
+		cameraID, creds.PublishUser, creds.PublishPass, creds.ReadUser, creds.ReadPass); err != nil {
+		return nil, err
+	}
+	var c domain.StreamCredentials
+	err := r.db.QueryRowContext(ctx, `
+		SELECT publish_user, publish_pass, read_user, read_pass FROM camera_stream_credentials WHERE camera_id = $1`, cameraID,
+	).Scan(&c.PublishUser, &c.PublishPass, &c.ReadUser, &c.ReadPass)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *repository) GetStreamCredentials(ctx context.Context, streamKey string) (*domain.StreamCredentials, error) {
+	var c domain.StreamCredentials
+	err := r.db.QueryRowContext(ctx, `
+		SELECT k.publish_user, k.publish_pass, k.read_user, k.read_pass
+		FROM camera_stream_credentials k JOIN cameras c ON c.id = k.camera_id
+		WHERE COALESCE(NULLIF(c.stream_key, ''), 'cam' || c.id) = $1`, streamKey,
+	).Scan(&c.PublishUser, &c.PublishPass, &c.ReadUser, &c.ReadPass)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
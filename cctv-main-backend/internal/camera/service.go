@@ -1,10 +1,28 @@
 package camera
 
-import "cctv-main-backend/internal/domain"
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAlertSettings: pengaturan alert kamera di luar batas yang diizinkan.
+var ErrInvalidAlertSettings = errors.New("pengaturan alert kamera tidak valid")
+
+// ErrCameraNotFound: kamera tidak ada atau milik perusahaan lain.
+var ErrCameraNotFound = errors.New("kamera tidak ditemukan")
 
 type Service interface {
     RegisterCamera(camera *domain.Camera) (int64, error)
-    GetCamerasForCompany(companyID int64) ([]domain.Camera, error)
+    GetCamerasForCompany(companyID int64, scope domain.CameraScope, filter domain.LocationFilter) ([]domain.Camera, error)
+    // GetCamera mengambil kamera milik companyID (0 = perusahaan mana pun), dipakai
+    // handler sebagai dasar PUT agar field yang tidak dikirim tetap bernilai lama.
+    GetCamera(cameraID, companyID int64) (*domain.Camera, error)
     UpdateCamera(camera *domain.Camera) error
     DeleteCamera(cameraID int64, companyID int64) error
     // Admin variants: bypass company ownership checks
@@ -12,34 +30,179 @@ type Service interface {
     DeleteCameraAdmin(cameraID int64) error
 }
 
+// PathProvisioner mengatur path MediaMTX kamera (lihat Provisioner); opsional.
+type PathProvisioner interface {
+	Provision(ctx context.Context, streamKey, rtspSource string) error
+	Remove(ctx context.Context, streamKey string) error
+}
+
 type service struct {
-	repo Repository
+	repo  Repository
+	paths PathProvisioner
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, paths PathProvisioner) Service {
+	return &service{repo: repo, paths: paths}
 }
 
+// RegisterCamera menyimpan kamera beserta kredensial stream-nya lalu membuat path
+// MediaMTX. Kegagalan kredensial/path hanya dicatat: Provisioner.Sync melengkapinya nanti.
 func (s *service) RegisterCamera(camera *domain.Camera) (int64, error) {
-	return s.repo.CreateCamera(camera)
+	if camera.FilterAction == "" {
+		camera.FilterAction = domain.FilterActionDrop
+	}
+	if err := validateAlertSettings(camera); err != nil {
+		return 0, err
+	}
+	cameraID, err := s.repo.CreateCamera(camera)
+	if err != nil {
+		return 0, err
+	}
+	camera.ID = cameraID
+	if camera.StreamKey == "" {
+		camera.StreamKey = streamKeyOf(camera)
+	}
+	creds, err := domain.NewStreamCredentials(camera.StreamKey)
+	if err == nil {
+		camera.Credentials, err = s.repo.EnsureStreamCredentials(context.Background(), cameraID, creds)
+	}
+	if err != nil {
+		log.Printf("kredensial stream kamera %d: %v", cameraID, err)
+	}
+	s.provision(camera.StreamKey, camera.RTSPSource)
+	return cameraID, nil
 }
 
-func (s *service) GetCamerasForCompany(companyID int64) ([]domain.Camera, error) {
-	return s.repo.GetCamerasByCompanyID(companyID)
+func (s *service) GetCamerasForCompany(companyID int64, scope domain.CameraScope, filter domain.LocationFilter) ([]domain.Camera, error) {
+	return s.repo.GetCamerasByCompanyID(companyID, scope, filter)
+}
+
+func (s *service) GetCamera(cameraID, companyID int64) (*domain.Camera, error) {
+	c, err := s.repo.GetCameraByID(context.Background(), cameraID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && companyID != 0 && c.CompanyID != companyID) {
+		return nil, ErrCameraNotFound
+	}
+	return c, err
 }
 
 func (s *service) UpdateCamera(camera *domain.Camera) error {
-    return s.repo.UpdateCamera(camera)
+    if err := validateAlertSettings(camera); err != nil {
+        return err
+    }
+    old := s.current(camera.ID)
+    if err := s.repo.UpdateCamera(camera); err != nil {
+        return err
+    }
+    s.reprovision(old, camera.ID)
+    return nil
 }
 
 func (s *service) DeleteCamera(cameraID int64, companyID int64) error {
-    return s.repo.DeleteCamera(cameraID, companyID)
+    old := s.current(cameraID)
+    if err := s.repo.DeleteCamera(cameraID, companyID); err != nil {
+        return err
+    }
+    s.unprovision(old)
+    return nil
 }
 
 func (s *service) UpdateCameraAdmin(camera *domain.Camera) error {
-    return s.repo.UpdateCameraAdmin(camera)
+    if err := validateAlertSettings(camera); err != nil {
+        return err
+    }
+    old := s.current(camera.ID)
+    if err := s.repo.UpdateCameraAdmin(camera); err != nil {
+        return err
+    }
+    s.reprovision(old, camera.ID)
+    return nil
 }
 
 func (s *service) DeleteCameraAdmin(cameraID int64) error {
-    return s.repo.DeleteCameraAdmin(cameraID)
+    old := s.current(cameraID)
+    if err := s.repo.DeleteCameraAdmin(cameraID); err != nil {
+        return err
+    }
+    s.unprovision(old)
+    return nil
+}
+
+// streamKeyOf mengikuti default repository: stream_key kosong berarti cam<id>.
+func streamKeyOf(c *domain.Camera) string {
+	if c.StreamKey != "" {
+		return c.StreamKey
+	}
+	return "cam" + strconv.FormatInt(c.ID, 10)
+}
+
+// current mengambil kamera sebelum diubah/dihapus agar path lamanya diketahui.
+func (s *service) current(cameraID int64) *domain.Camera {
+	if s.paths == nil {
+		return nil
+	}
+	c, err := s.repo.GetCameraByID(context.Background(), cameraID)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+func (s *service) provision(streamKey, rtspSource string) {
+	if s.paths == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.paths.Provision(ctx, streamKey, rtspSource); err != nil {
+		log.Printf("provisioning path mediamtx %s: %v", streamKey, err)
+	}
+}
+
+// reprovision memperbarui path setelah kamera diubah; path lama dihapus bila stream_key berganti.
+func (s *service) reprovision(old *domain.Camera, cameraID int64) {
+	if s.paths == nil {
+		return
+	}
+	cur, err := s.repo.GetCameraByID(context.Background(), cameraID)
+	if err != nil {
+		log.Printf("provisioning path mediamtx kamera %d: %v", cameraID, err)
+		return
+	}
+	s.provision(streamKeyOf(cur), cur.RTSPSource)
+	if old != nil && streamKeyOf(old) != streamKeyOf(cur) {
+		s.unprovision(old)
+	}
+}
+
+func (s *service) unprovision(old *domain.Camera) {
+	if s.paths == nil || old == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.paths.Remove(ctx, streamKeyOf(old)); err != nil {
+		log.Printf("hapus path mediamtx %s: %v", streamKeyOf(old), err)
+	}
+}
+
+func validateAlertSettings(camera *domain.Camera) error {
+	if camera.AlertCooldownSeconds != nil && *camera.AlertCooldownSeconds < 0 {
+		return ErrInvalidAlertSettings
+	}
+	if camera.MinConfidence != nil && (*camera.MinConfidence < 0 || *camera.MinConfidence > 1) {
+		return ErrInvalidAlertSettings
+	}
+	switch camera.FilterAction {
+	case domain.FilterActionDrop, domain.FilterActionStore:
+	default:
+		return ErrInvalidAlertSettings
+	}
+	types := make([]string, 0, len(camera.AnomalyTypes))
+	for _, t := range camera.AnomalyTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	camera.AnomalyTypes = types
+	return nil
 }
@@ -0,0 +1,102 @@
+package camera
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// streamCredentialGetter adalah bagian Repository yang dipakai StreamAuth.
+type streamCredentialGetter interface {
+	GetStreamCredentials(ctx context.Context, streamKey string) (*domain.StreamCredentials, error)
+}
+
+// StreamAuth menjawab callback authMethod http MediaMTX: 200 mengizinkan, 401 menolak.
+//   - publish: kredensial publish kamera pemilik path
+//   - read/playback: kredensial read (atau publish) kamera pemilik path
+//   - api/metrics/pprof: hanya user API backend
+//
+// User internal (arsip, AI worker, publisher uji di jaringan docker) boleh
+// publish/read semua path milik kamera terdaftar.
+type StreamAuth struct {
+	repo streamCredentialGetter
+
+	APIUser, APIPass           string
+	InternalUser, InternalPass string
+	// Secret opsional: bila diisi, callback wajib membawa ?secret= yang sama.
+	Secret string
+}
+
+func NewStreamAuth(repo streamCredentialGetter) *StreamAuth {
+	return &StreamAuth{repo: repo}
+}
+
+// authRequest adalah body callback MediaMTX.
+type authRequest struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	IP       string `json:"ip"`
+	Action   string `json:"action"`
+	Path     string `json:"path"`
+	Protocol string `json:"protocol"`
+}
+
+// POST /internal/mediamtx/auth (listener internal INTERNAL_ADDR, bukan port API publik)
+func (a *StreamAuth) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+	if a.Secret != "" && !equal(r.URL.Query().Get("secret"), a.Secret) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req authRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	ok, err := a.allowed(r.Context(), req)
+	if err != nil {
+		log.Printf("mediamtx auth %s %s: %v", req.Action, req.Path, err)
+		http.Error(w, "Gagal memeriksa kredensial", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *StreamAuth) allowed(ctx context.Context, req authRequest) (bool, error) {
+	switch req.Action {
+	case "api", "metrics", "pprof":
+		return a.APIUser != "" && equal(req.User, a.APIUser) && equal(req.Password, a.APIPass), nil
+	case "publish", "read", "playback":
+	default:
+		return false, nil
+	}
+	// Tanpa user MediaMTX akan meminta kredensial ke klien; tidak perlu query database.
+	if req.User == "" {
+		return false, nil
+	}
+	creds, err := a.repo.GetStreamCredentials(ctx, req.Path)
+	if err != nil || creds == nil {
+		return false, err
+	}
+	if a.InternalUser != "" && equal(req.User, a.InternalUser) && equal(req.Password, a.InternalPass) {
+		return true, nil
+	}
+	if equal(req.User, creds.PublishUser) && equal(req.Password, creds.PublishPass) {
+		return true, nil
+	}
+	return req.Action != "publish" && equal(req.User, creds.ReadUser) && equal(req.Password, creds.ReadPass), nil
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
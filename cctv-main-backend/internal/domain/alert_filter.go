@@ -0,0 +1,30 @@
+package domain
+
+// Tindakan untuk laporan yang tidak lolos AlertFilter kamera.
+const (
+	FilterActionDrop  = "drop"  // laporan dibuang, tidak disimpan
+	FilterActionStore = "store" // laporan disimpan sebagai suppressed, tanpa notifikasi
+)
+
+// AlertFilter adalah ambang confidence dan tipe anomali yang diterima sebuah kamera.
+type AlertFilter struct {
+	MinConfidence *float64 `json:"min_confidence,omitempty"`
+	AnomalyTypes  []string `json:"anomaly_types,omitempty"` // kosong = semua tipe
+	FilterAction  string   `json:"filter_action,omitempty"` // drop (default) | store
+}
+
+// Accepts melaporkan apakah laporan lolos filter; MinConfidence bersifat inklusif.
+func (f AlertFilter) Accepts(anomalyType string, confidence float64) bool {
+	if f.MinConfidence != nil && confidence < *f.MinConfidence {
+		return false
+	}
+	if len(f.AnomalyTypes) == 0 {
+		return true
+	}
+	for _, t := range f.AnomalyTypes {
+		if t == anomalyType {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,50 @@ package domain
 
 import "time"
 
+// Status penanganan anomali.
+const (
+	AnomalyStatusNew           = "new"
+	AnomalyStatusAcknowledged  = "acknowledged"
+	AnomalyStatusInProgress    = "in_progress"
+	AnomalyStatusResolved      = "resolved"
+	AnomalyStatusFalsePositive = "false_positive"
+)
+
 type AnomalyReport struct {
 	ID           int64     `json:"id"`
 	CameraID     int64     `json:"camera_id"`
+	CompanyID    int64     `json:"company_id,omitempty"`
 	AnomalyType  string    `json:"anomaly_type"`
 	Confidence   float64   `json:"confidence"`
 	VideoClipURL string    `json:"video_clip_url,omitempty"`
 	ReportedAt   time.Time `json:"reported_at"`
+
+	// Laporan berulang dalam jendela cooldown dilipat ke insiden ini: ReportedAt adalah
+	// kemunculan pertama, LastSeenAt yang terakhir. VideoClipURLs berisi klip semua
+	// kemunculan, dimulai dari VideoClipURL.
+	Occurrences   int       `json:"occurrences"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	VideoClipURLs []string  `json:"video_clip_urls"`
+	// Suppressed: laporan datang saat kamera tidak armed (jadwal deteksi); disimpan tanpa notifikasi.
+	Suppressed bool `json:"suppressed"`
+
+	Status          string     `json:"status,omitempty"`
+	AssigneeID      *int64     `json:"assignee_id,omitempty"`
+	ResolutionNotes string     `json:"resolution_notes,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+// AnomalyEvent adalah satu baris riwayat penanganan anomali.
+type AnomalyEvent struct {
+	ID         int64     `json:"id"`
+	AnomalyID  int64     `json:"anomaly_id"`
+	ActorID    *int64    `json:"actor_id,omitempty"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	Action     string    `json:"action"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status,omitempty"`
+	AssigneeID *int64    `json:"assignee_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
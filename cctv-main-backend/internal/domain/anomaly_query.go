@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// AnomalyQuery adalah filter, urutan, dan paginasi untuk daftar anomali.
+// Nilai kosong/nol berarti filter tidak dipakai.
+type AnomalyQuery struct {
+	LocationFilter
+	CameraID      int64
+	AnomalyTypes  []string
+	Statuses      []string
+	MinConfidence *float64
+	MaxConfidence *float64
+	Suppressed    *bool // nil = semua
+	From          time.Time
+	To            time.Time
+
+	// Sort: "-reported_at" (default), "reported_at", "-confidence", "confidence".
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// AnomalyPage adalah satu halaman hasil AnomalyQuery.
+type AnomalyPage struct {
+	Items      []AnomalyReport `json:"items"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
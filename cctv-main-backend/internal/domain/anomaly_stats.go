@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// AnomalyStats adalah ringkasan agregat anomali untuk dashboard admin.
+type AnomalyStats struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Bucket string    `json:"bucket"`
+	TZ     string    `json:"tz"`
+	Total  int       `json:"total"`
+
+	Series       []StatsBucket      `json:"series"`
+	ByCamera     []CameraCount      `json:"by_camera"`
+	ByType       []TypeCount        `json:"by_type"`
+	ByConfidence []ConfidenceBand   `json:"by_confidence"`
+	TopCameras   []CameraCount      `json:"top_cameras"`
+	ByStatus     map[string]int     `json:"by_status"`
+	Acknowledge  AcknowledgeSummary `json:"acknowledge"`
+}
+
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type CameraCount struct {
+	CameraID   int64  `json:"camera_id"`
+	CameraName string `json:"camera_name"`
+	Count      int    `json:"count"`
+}
+
+type TypeCount struct {
+	AnomalyType string `json:"anomaly_type"`
+	Count       int    `json:"count"`
+}
+
+// ConfidenceBand: Min inklusif, Max eksklusif (kecuali band tertinggi).
+type ConfidenceBand struct {
+	Band  string  `json:"band"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// AcknowledgeSummary: rata-rata waktu dari reported_at ke acknowledged_at.
+type AcknowledgeSummary struct {
+	Count              int      `json:"count"`
+	MeanSeconds        *float64 `json:"mean_seconds"`
+	UnacknowledgedOpen int      `json:"unacknowledged_open"`
+}
@@ -9,5 +9,16 @@ type Camera struct {
     StreamKey string    `json:"stream_key,omitempty"`
     RTSPSource string   `json:"rtsp_source,omitempty"`
     CompanyID int64     `json:"company_id"`
+    SiteID    *int64    `json:"site_id,omitempty"`
+    ZoneID    *int64    `json:"zone_id,omitempty"`
+    // AlertCooldownSeconds menimpa jendela penggabungan alert server; nil = default.
+    AlertCooldownSeconds *int `json:"alert_cooldown_seconds,omitempty"`
+    // AlertFilter: laporan di bawah ambang atau di luar tipe yang diizinkan dibuang/diredam.
+    AlertFilter
     CreatedAt time.Time `json:"created_at"`
+    // Status dari monitor MediaMTX; nil bila kamera belum pernah dipantau.
+    Status *CameraStatus `json:"status,omitempty"`
+    // Credentials kredensial path MediaMTX; tidak pernah dibaca dari body request,
+    // handler memilih bagian yang boleh dikirim ke pemanggil.
+    Credentials *StreamCredentials `json:"-"`
 }
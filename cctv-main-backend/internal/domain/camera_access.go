@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+type CameraGroup struct {
+	ID        int64     `json:"id"`
+	CompanyID int64     `json:"company_id"`
+	Name      string    `json:"name"`
+	CameraIDs []int64   `json:"camera_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CameraAccess adalah pengaturan akses kamera seorang user.
+type CameraAccess struct {
+	UserID     int64   `json:"user_id"`
+	Restricted bool    `json:"restricted"`
+	CameraIDs  []int64 `json:"camera_ids"`
+	GroupIDs   []int64 `json:"group_ids"`
+}
+
+// CameraScope adalah hasil resolve akses kamera untuk satu request.
+// All = true berarti tidak ada pembatasan di dalam perusahaan.
+type CameraScope struct {
+	All       bool
+	CameraIDs []int64
+}
+
+func (s CameraScope) Allows(cameraID int64) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.CameraIDs {
+		if id == cameraID {
+			return true
+		}
+	}
+	return false
+}
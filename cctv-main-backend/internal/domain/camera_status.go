@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// AnomalyTypeCameraOffline adalah tipe laporan semu yang dikirim lewat notifier
+// ketika kamera offline melewati masa tenggang monitor.
+const AnomalyTypeCameraOffline = "camera_offline"
+
+// CameraStatus adalah status streaming kamera hasil polling MediaMTX.
+type CameraStatus struct {
+	CameraID    int64      `json:"camera_id"`
+	Online      bool       `json:"online"`
+	Since       time.Time  `json:"since"`                  // transisi online/offline terakhir
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"` // terakhir terlihat online
+	BitrateKbps *int       `json:"bitrate_kbps,omitempty"`
+	Codec       string     `json:"codec,omitempty"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// OfflineNotifiedAt diisi setelah notifikasi offline diantrekan ke outbox; dikosongkan saat online lagi.
+	OfflineNotifiedAt *time.Time `json:"-"`
+}
+
+// MonitoredCamera adalah data minimum kamera untuk monitor status dan provisioning path.
+type MonitoredCamera struct {
+	ID             int64
+	CompanyID      int64
+	Name           string
+	StreamKey      string
+	RTSPSource     string
+	HasCredentials bool
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Status outbox notifikasi.
+const (
+	OutboxPending = "pending"
+	OutboxDone    = "done"
+	OutboxFailed  = "failed"
+)
+
+// NotificationOutbox adalah satu notifikasi anomali yang menunggu/selesai dikirim.
+type NotificationOutbox struct {
+	ID            int64                  `json:"id"`
+	AnomalyID     int64                  `json:"anomaly_id"`
+	Status        string                 `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	NextAttemptAt time.Time              `json:"next_attempt_at"`
+	LastError     string                 `json:"last_error,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	CompletedAt   *time.Time             `json:"completed_at,omitempty"`
+	Deliveries    []NotificationDelivery `json:"deliveries"`
+}
+
+// NotificationDelivery adalah hasil kirim ke satu penerima di satu channel.
+type NotificationDelivery struct {
+	Channel   string    `json:"channel"`
+	Recipient string    `json:"recipient"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailDigestItem adalah satu alert yang menunggu dikirim dalam email digest.
+// Alert berisi notifier.EmailAlert dalam bentuk JSON.
+type EmailDigestItem struct {
+	ID        int64
+	Recipient EmailRecipient
+	Alert     []byte
+	CreatedAt time.Time
+}
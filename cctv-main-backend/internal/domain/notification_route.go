@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// NotificationRoute memilih channel notifikasi (push, webhook, ...) untuk anomali
+// perusahaan yang cocok dengan tipe dan batas confidence-nya.
+type NotificationRoute struct {
+	ID            int64     `json:"id"`
+	CompanyID     int64     `json:"company_id"`
+	Channel       string    `json:"channel"`
+	AnomalyTypes  []string  `json:"anomaly_types"`
+	MinConfidence *float64  `json:"min_confidence,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Matches melaporkan apakah laporan memenuhi aturan; AnomalyTypes kosong berarti
+// semua tipe, MinConfidence nil berarti tanpa batas (batas bersifat inklusif).
+func (rt NotificationRoute) Matches(r *AnomalyReport) bool {
+	if rt.MinConfidence != nil && r.Confidence < *rt.MinConfidence {
+		return false
+	}
+	if len(rt.AnomalyTypes) == 0 {
+		return true
+	}
+	for _, t := range rt.AnomalyTypes {
+		if t == r.AnomalyType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package domain
+
+import "time"
+
+// Channel notifikasi yang bisa dipilih per user (webhook diatur per perusahaan).
+const (
+	UserChannelPush  = "push"
+	UserChannelEmail = "email"
+)
+
+// NotificationSettings adalah preferensi notifikasi satu user. Kamera dan site
+// digabung: alert lolos bila kameranya ada di CameraIDs atau site-nya ada di
+// SiteIDs; keduanya kosong = semua kamera yang boleh dilihat user.
+type NotificationSettings struct {
+	CameraIDs     []int64          `json:"camera_ids"`
+	SiteIDs       []int64          `json:"site_ids"`
+	MinConfidence *float64         `json:"min_confidence"`
+	AnomalyTypes  []string         `json:"anomaly_types"` // kosong = semua tipe
+	Channels      []string         `json:"channels"`      // push, email
+	QuietHours    []ScheduleWindow `json:"quiet_hours"`   // jam tidak diganggu (APP_TZ)
+	EmailMode     string           `json:"email_mode"`    // users.email_alerts: off, instant, digest
+}
+
+// DefaultNotificationSettings berlaku untuk user yang belum menyimpan preferensi.
+func DefaultNotificationSettings() NotificationSettings {
+	return NotificationSettings{
+		CameraIDs:    []int64{},
+		SiteIDs:      []int64{},
+		AnomalyTypes: []string{},
+		Channels:     []string{UserChannelPush, UserChannelEmail},
+		QuietHours:   []ScheduleWindow{},
+		EmailMode:    EmailAlertsOff,
+	}
+}
+
+// Allows melaporkan apakah laporan boleh dikirim ke user lewat channel pada
+// waktu lokal now. siteID adalah site kamera laporan (nil bila tanpa site).
+// Laporan camera_offline tidak disaring confidence dan tipe anomali.
+func (s NotificationSettings) Allows(r *AnomalyReport, siteID *int64, channel string, now time.Time) bool {
+	if !containsString(s.Channels, channel) {
+		return false
+	}
+	if len(s.CameraIDs) > 0 || len(s.SiteIDs) > 0 {
+		inSite := siteID != nil && containsInt64(s.SiteIDs, *siteID)
+		if !containsInt64(s.CameraIDs, r.CameraID) && !inSite {
+			return false
+		}
+	}
+	if r.AnomalyType != AnomalyTypeCameraOffline {
+		if s.MinConfidence != nil && r.Confidence < *s.MinConfidence {
+			return false
+		}
+		if len(s.AnomalyTypes) > 0 && !containsString(s.AnomalyTypes, r.AnomalyType) {
+			return false
+		}
+	}
+	for _, w := range s.QuietHours {
+		if w.Covers(now) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// PushRecipient adalah satu token perangkat beserta preferensi pemiliknya.
+type PushRecipient struct {
+	UserID   int64
+	Token    string
+	Provider string
+	Locale   string
+	Settings NotificationSettings
+}
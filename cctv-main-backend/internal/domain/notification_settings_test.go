@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationSettingsAllows(t *testing.T) {
+	site := int64(3)
+	otherSite := int64(4)
+	half := 0.5
+	base := func(mod func(s *NotificationSettings)) NotificationSettings {
+		s := DefaultNotificationSettings()
+		mod(&s)
+		return s
+	}
+	fire := &AnomalyReport{CameraID: 5, AnomalyType: "fire", Confidence: 0.8}
+	weak := &AnomalyReport{CameraID: 5, AnomalyType: "fire", Confidence: 0.3}
+	offline := &AnomalyReport{CameraID: 5, AnomalyType: AnomalyTypeCameraOffline}
+	night := []ScheduleWindow{{Start: "22:00", End: "06:00"}}
+	saturdayNight := []ScheduleWindow{{Days: []string{"sat"}, Start: "22:00", End: "06:00"}}
+
+	tests := []struct {
+		name     string
+		settings NotificationSettings
+		report   *AnomalyReport
+		siteID   *int64
+		channel  string
+		now      time.Time
+		want     bool
+	}{
+		{"default menerima semua", DefaultNotificationSettings(), fire, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+
+		// Kamera dan site digabung (union).
+		{"kamera dipilih", base(func(s *NotificationSettings) { s.CameraIDs = []int64{5} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"kamera lain dipilih", base(func(s *NotificationSettings) { s.CameraIDs = []int64{6} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"site kamera dipilih", base(func(s *NotificationSettings) { s.SiteIDs = []int64{3} }), fire, &site, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"site lain dipilih", base(func(s *NotificationSettings) { s.SiteIDs = []int64{3} }), fire, &otherSite, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"kamera tanpa site", base(func(s *NotificationSettings) { s.SiteIDs = []int64{3} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"kamera lain tapi site cocok", base(func(s *NotificationSettings) { s.CameraIDs = []int64{6}; s.SiteIDs = []int64{3} }), fire, &site, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"site lain tapi kamera cocok", base(func(s *NotificationSettings) { s.CameraIDs = []int64{5}; s.SiteIDs = []int64{3} }), fire, &otherSite, UserChannelPush, at(time.Monday, 12, 0), true},
+
+		// Confidence dan tipe.
+		{"di atas min_confidence", base(func(s *NotificationSettings) { s.MinConfidence = &half }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"di bawah min_confidence", base(func(s *NotificationSettings) { s.MinConfidence = &half }), weak, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"tipe dipilih", base(func(s *NotificationSettings) { s.AnomalyTypes = []string{"fire"} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"tipe lain dipilih", base(func(s *NotificationSettings) { s.AnomalyTypes = []string{"intrusion"} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+
+		// Kamera offline tidak disaring confidence/tipe, tetapi tetap disaring kamera dan channel.
+		{"offline lolos min_confidence", base(func(s *NotificationSettings) { s.MinConfidence = &half }), offline, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"offline lolos filter tipe", base(func(s *NotificationSettings) { s.AnomalyTypes = []string{"intrusion"} }), offline, nil, UserChannelPush, at(time.Monday, 12, 0), true},
+		{"offline kamera lain", base(func(s *NotificationSettings) { s.CameraIDs = []int64{6} }), offline, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"offline saat jam tenang", base(func(s *NotificationSettings) { s.QuietHours = night }), offline, nil, UserChannelPush, at(time.Monday, 23, 0), false},
+
+		// Channel.
+		{"channel email aktif", base(func(s *NotificationSettings) { s.Channels = []string{UserChannelEmail} }), fire, nil, UserChannelEmail, at(time.Monday, 12, 0), true},
+		{"channel push dimatikan", base(func(s *NotificationSettings) { s.Channels = []string{UserChannelEmail} }), fire, nil, UserChannelPush, at(time.Monday, 12, 0), false},
+		{"tanpa channel", base(func(s *NotificationSettings) { s.Channels = []string{} }), fire, nil, UserChannelEmail, at(time.Monday, 12, 0), false},
+
+		// Jam tenang lewat tengah malam.
+		{"sebelum jam tenang", base(func(s *NotificationSettings) { s.QuietHours = night }), fire, nil, UserChannelPush, at(time.Monday, 21, 59), true},
+		{"jam tenang malam", base(func(s *NotificationSettings) { s.QuietHours = night }), fire, nil, UserChannelPush, at(time.Monday, 23, 0), false},
+		{"jam tenang lewat tengah malam", base(func(s *NotificationSettings) { s.QuietHours = night }), fire, nil, UserChannelPush, at(time.Tuesday, 5, 59), false},
+		{"setelah jam tenang", base(func(s *NotificationSettings) { s.QuietHours = night }), fire, nil, UserChannelPush, at(time.Tuesday, 6, 0), true},
+		{"jam tenang hari lain", base(func(s *NotificationSettings) { s.QuietHours = saturdayNight }), fire, nil, UserChannelPush, at(time.Tuesday, 1, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.Allows(tt.report, tt.siteID, tt.channel, tt.now); got != tt.want {
+				t.Fatalf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type Role struct {
+	ID          int64     `json:"id,omitempty"`
+	CompanyID   int64     `json:"company_id,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode jadwal deteksi.
+const (
+	ScheduleModeArm   = "arm"   // kamera hanya armed di dalam jendela
+	ScheduleModeQuiet = "quiet" // kamera tidak armed di dalam jendela (jam tenang)
+)
+
+// DetectionSchedule adalah jadwal mingguan yang dipasang ke kamera dan/atau grup kamera.
+type DetectionSchedule struct {
+	ID        int64            `json:"id"`
+	CompanyID int64            `json:"company_id"`
+	Name      string           `json:"name"`
+	Mode      string           `json:"mode"`
+	Windows   []ScheduleWindow `json:"windows"`
+	CameraIDs []int64          `json:"camera_ids"`
+	GroupIDs  []int64          `json:"group_ids"`
+	Enabled   bool             `json:"enabled"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// ScheduleWindow adalah rentang jam lokal "HH:MM" pada hari tertentu. End <= Start
+// berarti jendela melewati tengah malam (dan milik hari mulainya); Start == End = 24 jam.
+type ScheduleWindow struct {
+	Days  []string `json:"days"` // mon..sun; kosong = setiap hari
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWeekday menerima mon..sun (tidak peka huruf besar).
+func ParseWeekday(s string) (time.Weekday, bool) {
+	d, ok := weekdays[strings.ToLower(strings.TrimSpace(s))]
+	return d, ok
+}
+
+// ParseClock mengubah "HH:MM" menjadi menit sejak tengah malam.
+func ParseClock(s string) (int, bool) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+func (w ScheduleWindow) hasDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, s := range w.Days {
+		if wd, ok := ParseWeekday(s); ok && wd == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers melaporkan apakah t (sudah dalam zona waktu jadwal) berada di dalam jendela.
+func (w ScheduleWindow) Covers(t time.Time) bool {
+	start, ok1 := ParseClock(w.Start)
+	end, ok2 := ParseClock(w.End)
+	if !ok1 || !ok2 {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return w.hasDay(t.Weekday()) && now >= start && now < end
+	}
+	// Melewati tengah malam: bagian awal di hari ini, sisanya di hari berikutnya.
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.hasDay(t.Weekday()) && now >= start) || (w.hasDay(yesterday) && now < end)
+}
+
+// Covers melaporkan apakah salah satu jendela jadwal mencakup t.
+func (s DetectionSchedule) Covers(t time.Time) bool {
+	for _, w := range s.Windows {
+		if w.Covers(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Armed menghitung status kamera dari jadwal aktifnya pada t: tanpa jadwal arm kamera
+// selalu armed, dan jendela quiet selalu menang atas jendela arm.
+func Armed(schedules []DetectionSchedule, t time.Time) bool {
+	hasArm, inArm := false, false
+	for _, s := range schedules {
+		if !s.Enabled {
+			continue
+		}
+		switch s.Mode {
+		case ScheduleModeQuiet:
+			if s.Covers(t) {
+				return false
+			}
+		case ScheduleModeArm:
+			hasArm = true
+			inArm = inArm || s.Covers(t)
+		}
+	}
+	return !hasArm || inArm
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+// at mengembalikan waktu pada minggu 5-11 Januari 2026 (Senin 5 Januari).
+func at(day time.Weekday, hh, mm int) time.Time {
+	return time.Date(2026, 1, 4+int(day), hh, mm, 0, 0, time.UTC)
+}
+
+func TestScheduleWindowCovers(t *testing.T) {
+	if at(time.Monday, 0, 0).Weekday() != time.Monday || at(time.Sunday, 0, 0).Weekday() != time.Sunday {
+		t.Fatal("helper at() salah hari")
+	}
+	tests := []struct {
+		name string
+		w    ScheduleWindow
+		t    time.Time
+		want bool
+	}{
+		{"di dalam jendela", ScheduleWindow{Start: "08:00", End: "17:00"}, at(time.Tuesday, 12, 0), true},
+		{"tepat di awal", ScheduleWindow{Start: "08:00", End: "17:00"}, at(time.Tuesday, 8, 0), true},
+		{"tepat di akhir (eksklusif)", ScheduleWindow{Start: "08:00", End: "17:00"}, at(time.Tuesday, 17, 0), false},
+		{"hari lain", ScheduleWindow{Days: []string{"mon"}, Start: "08:00", End: "17:00"}, at(time.Tuesday, 12, 0), false},
+		{"nama hari tidak peka huruf", ScheduleWindow{Days: []string{" TUE"}, Start: "08:00", End: "17:00"}, at(time.Tuesday, 12, 0), true},
+		{"lewat tengah malam, bagian awal", ScheduleWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, at(time.Friday, 23, 30), true},
+		{"lewat tengah malam, milik hari mulai", ScheduleWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, at(time.Saturday, 5, 59), true},
+		{"lewat tengah malam, sebelum hari mulai", ScheduleWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, at(time.Friday, 5, 0), false},
+		{"minggu ke senin", ScheduleWindow{Days: []string{"sun"}, Start: "22:00", End: "06:00"}, at(time.Monday, 1, 0), true},
+		{"start == end berarti 24 jam", ScheduleWindow{Days: []string{"wed"}, Start: "07:00", End: "07:00"}, at(time.Thursday, 6, 59), true},
+		{"jam tidak valid", ScheduleWindow{Start: "25:00", End: "06:00"}, at(time.Monday, 1, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.Covers(tt.t); got != tt.want {
+				t.Fatalf("Covers(%s) = %v, want %v", tt.t.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArmed(t *testing.T) {
+	office := DetectionSchedule{Mode: ScheduleModeArm, Enabled: true,
+		Windows: []ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "18:00", End: "07:00"}}}
+	weekend := DetectionSchedule{Mode: ScheduleModeArm, Enabled: true,
+		Windows: []ScheduleWindow{{Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00"}}}
+	lunch := DetectionSchedule{Mode: ScheduleModeQuiet, Enabled: true,
+		Windows: []ScheduleWindow{{Start: "12:00", End: "13:00"}}}
+	cleaning := DetectionSchedule{Mode: ScheduleModeQuiet, Enabled: true,
+		Windows: []ScheduleWindow{{Days: []string{"tue"}, Start: "19:00", End: "20:00"}}}
+	disabled := func(s DetectionSchedule) DetectionSchedule { s.Enabled = false; return s }
+
+	tests := []struct {
+		name      string
+		schedules []DetectionSchedule
+		t         time.Time
+		want      bool
+	}{
+		{"tanpa jadwal selalu armed", nil, at(time.Monday, 12, 0), true},
+		{"di dalam jendela arm", []DetectionSchedule{office}, at(time.Monday, 22, 0), true},
+		{"di luar jendela arm", []DetectionSchedule{office}, at(time.Monday, 12, 0), false},
+		{"jendela arm lewat tengah malam", []DetectionSchedule{office}, at(time.Saturday, 6, 0), true},
+		{"salah satu jadwal arm cukup", []DetectionSchedule{office, weekend}, at(time.Sunday, 12, 0), true},
+		{"hanya quiet: armed di luar jendela", []DetectionSchedule{lunch}, at(time.Monday, 11, 59), true},
+		{"hanya quiet: tidak armed di dalam jendela", []DetectionSchedule{lunch}, at(time.Monday, 12, 30), false},
+		{"quiet menang atas arm", []DetectionSchedule{office, cleaning}, at(time.Tuesday, 19, 30), false},
+		{"quiet hari lain tidak berpengaruh", []DetectionSchedule{office, cleaning}, at(time.Wednesday, 19, 30), true},
+		{"jadwal arm nonaktif diabaikan", []DetectionSchedule{disabled(office)}, at(time.Monday, 12, 0), true},
+		{"jadwal quiet nonaktif diabaikan", []DetectionSchedule{office, disabled(cleaning)}, at(time.Tuesday, 19, 30), true},
+		{"mode tidak dikenal diabaikan", []DetectionSchedule{{Mode: "x", Enabled: true, Windows: lunch.Windows}}, at(time.Monday, 12, 30), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Armed(tt.schedules, tt.t); got != tt.want {
+				t.Fatalf("Armed(%s) = %v, want %v", tt.t.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Site adalah cabang/lokasi fisik milik perusahaan.
+type Site struct {
+	ID              int64     `json:"id"`
+	CompanyID       int64     `json:"company_id"`
+	Name            string    `json:"name"`
+	Address         string    `json:"address,omitempty"`
+	NotifyStaffOnly bool      `json:"notify_staff_only"`
+	Zones           []Zone    `json:"zones"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Zone adalah area di dalam sebuah site (mis. lobi, gudang, parkir).
+type Zone struct {
+	ID        int64     `json:"id"`
+	SiteID    int64     `json:"site_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LocationFilter menyaring kamera/anomali berdasarkan site atau zone; 0 = semua.
+type LocationFilter struct {
+	SiteID int64
+	ZoneID int64
+}
+
+// LocationFilterFromQuery membaca ?site_id= dan ?zone_id= dari query string.
+func LocationFilterFromQuery(q url.Values) LocationFilter {
+	var f LocationFilter
+	f.SiteID, _ = strconv.ParseInt(q.Get("site_id"), 10, 64)
+	f.ZoneID, _ = strconv.ParseInt(q.Get("zone_id"), 10, 64)
+	return f
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// StreamCredentials adalah user/password MediaMTX per kamera: publish untuk
+// kamera atau encoder yang mengirim stream, read untuk penonton.
+type StreamCredentials struct {
+	PublishUser string `json:"publish_user,omitempty"`
+	PublishPass string `json:"publish_pass,omitempty"`
+	ReadUser    string `json:"read_user"`
+	ReadPass    string `json:"read_pass"`
+}
+
+// NewStreamCredentials membuat kredensial acak; nama user diturunkan dari stream key
+// agar mudah dikenali di log MediaMTX.
+func NewStreamCredentials(streamKey string) (*StreamCredentials, error) {
+	pub, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	read, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &StreamCredentials{
+		PublishUser: streamKey + "-pub",
+		PublishPass: pub,
+		ReadUser:    streamKey + "-view",
+		ReadPass:    read,
+	}, nil
+}
+
+// ReadOnly mengembalikan salinan tanpa kredensial publish (untuk pengguna tanpa camera:write).
+func (c *StreamCredentials) ReadOnly() *StreamCredentials {
+	if c == nil {
+		return nil
+	}
+	return &StreamCredentials{ReadUser: c.ReadUser, ReadPass: c.ReadPass}
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -6,6 +6,32 @@ type User struct {
 	Password     string `json:"password"`
 	PasswordHash string
 	CompanyID    int64  `json:"company_id"`
-	Role         string `json:"role"`                // 'user' or 'company_admin'
-	FCMToken     string `json:"fcm_token,omitempty"` // Untuk menyimpan token FCM
+	Role         string `json:"role"` // 'user' or 'company_admin'
+}
+
+// Mode alert email per user.
+const (
+	EmailAlertsOff     = "off"
+	EmailAlertsInstant = "instant"
+	EmailAlertsDigest  = "digest"
+)
+
+// Bahasa notifikasi per user.
+const (
+	LocaleID      = "id"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleID
+)
+
+// Locales adalah bahasa yang memiliki template notifikasi.
+var Locales = []string{LocaleID, LocaleEN}
+
+// EmailRecipient adalah user yang memilih menerima alert lewat email.
+type EmailRecipient struct {
+	UserID int64
+	Email  string
+	Name   string
+	Mode   string // EmailAlertsInstant atau EmailAlertsDigest
+
+	Settings NotificationSettings
 }
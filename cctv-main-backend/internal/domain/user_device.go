@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Platform perangkat push.
+const (
+	DevicePlatformAndroid = "android"
+	DevicePlatformIOS     = "ios"
+	DevicePlatformWeb     = "web"
+	DevicePlatformUnknown = "unknown"
+)
+
+// Provider token push: token FCM (Android/web/iOS lewat Firebase) atau token
+// APNs mentah untuk aplikasi iOS yang dikirim langsung ke Apple.
+const (
+	PushProviderFCM  = "fcm"
+	PushProviderAPNs = "apns"
+)
+
+// UserDevice adalah satu perangkat yang terdaftar untuk push notification.
+// Token tidak pernah dikirim balik ke klien.
+type UserDevice struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	Token      string    `json:"-"`
+	Platform   string    `json:"platform"`
+	Provider   string    `json:"provider"`
+	DeviceName string    `json:"device_name,omitempty"`
+	AppVersion string    `json:"app_version,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// PushTarget adalah satu token tujuan push beserta provider-nya.
+type PushTarget struct {
+	Token    string
+	Provider string
+	Locale   string // bahasa pemilik perangkat (users.locale)
+}
@@ -0,0 +1,171 @@
+// internal/handlers/events.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"cctv-main-backend/pkg/events"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// heartbeatInterval menjaga koneksi tetap hidup melewati proxy yang menutup koneksi idle.
+const heartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	Hub      *events.Hub
+	Access   CameraScoper
+	Sessions SessionChecker
+	Tickets  *auth.Tickets
+}
+
+// SessionChecker adalah bagian session.Service yang dipakai stream untuk
+// memeriksa ulang sesi di setiap heartbeat.
+type SessionChecker interface {
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+}
+
+func NewEventsHandler(hub *events.Hub, access CameraScoper, sessions SessionChecker, tickets *auth.Tickets) *EventsHandler {
+	return &EventsHandler{Hub: hub, Access: access, Sessions: sessions, Tickets: tickets}
+}
+
+// Ticket melayani POST /api/events/ticket: menukar JWT pemanggil dengan tiket
+// sekali pakai untuk membuka GET /api/events?ticket=<tiket> dari EventSource.
+func (h *EventsHandler) Ticket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	ticket, err := h.Tickets.Issue(claims)
+	if err != nil {
+		http.Error(w, "Gagal membuat tiket", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{
+		"ticket":     ticket,
+		"expires_in": int(h.Tickets.TTL.Seconds()),
+	})
+}
+
+// Stream melayani GET /api/events sebagai Server-Sent Events.
+// Query opsional: types=anomaly.created,camera.offline untuk menyaring tipe event;
+// company_id=<id> untuk pemegang company:manage (default: semua perusahaan).
+// Stream ditutup saat access token kedaluwarsa atau sesinya dicabut; klien
+// membuka ulang dengan token/tiket baru.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming tidak didukung", http.StatusInternalServerError)
+		return
+	}
+
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || !time.Now().Before(exp.Time) {
+		http.Error(w, "Token kedaluwarsa", http.StatusUnauthorized)
+		return
+	}
+	sid, _ := claims["sid"].(float64)
+	cid, _ := claims["company_id"].(float64)
+	companyID := int64(cid)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		companyID = 0
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				companyID = id
+			}
+		}
+	}
+
+	scope, err := h.Access.Scope(r.Context())
+	if err != nil {
+		http.Error(w, "Gagal memeriksa akses kamera", http.StatusInternalServerError)
+		return
+	}
+
+	var types map[string]bool
+	if v := r.URL.Query().Get("types"); v != "" {
+		types = map[string]bool{}
+		for _, t := range strings.Split(v, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: jangan buffer
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	sub := h.Hub.Subscribe(companyID)
+	defer h.Hub.Unsubscribe(sub)
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(exp.Time))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expiry.C:
+			closeStream(w, flusher, "token_expired")
+			return
+		case <-ticker.C:
+			active, err := h.Sessions.IsActive(r.Context(), int64(sid))
+			if err != nil {
+				log.Printf("events: cek sesi %d: %v", int64(sid), err)
+			}
+			if err != nil || !active {
+				closeStream(w, flusher, "session_revoked")
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if types != nil && !types[ev.Type] {
+				continue
+			}
+			// ACL kamera: event kamera yang tidak boleh dilihat user dilewati.
+			if ev.CameraID != 0 && !scope.Allows(ev.CameraID) {
+				continue
+			}
+			payload, err := json.Marshal(ev)
+			if err != nil {
+				log.Printf("marshal event %s: %v", ev.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// closeStream mengirim event "expired" sebelum stream ditutup supaya klien tahu
+// harus mengambil token/tiket baru alih-alih menyambung ulang dengan yang lama.
+func closeStream(w http.ResponseWriter, flusher http.Flusher, reason string) {
+	fmt.Fprintf(w, "event: expired\ndata: {\"reason\":%q}\n\n", reason)
+	flusher.Flush()
+}
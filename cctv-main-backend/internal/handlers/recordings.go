@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
@@ -9,7 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
 	"cctv-main-backend/internal/storage" // s3util kamu
+	"cctv-main-backend/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type RecordingItem struct {
@@ -30,10 +36,16 @@ type RecordingHandler struct {
 	DB     *sql.DB
 	S3     *storage.S3Util // punya method Presign(bucket, key, ttl)
 	Bucket string
+	Access CameraScoper // ACL kamera per user
+}
+
+// CameraScoper adalah bagian access.Service yang dipakai handler rekaman.
+type CameraScoper interface {
+	Scope(ctx context.Context) (domain.CameraScope, error)
 }
 
-func NewRecordingHandler(db *sql.DB, s3 *storage.S3Util, bucket string) *RecordingHandler {
-	return &RecordingHandler{DB: db, S3: s3, Bucket: bucket}
+func NewRecordingHandler(db *sql.DB, s3 *storage.S3Util, bucket string, access CameraScoper) *RecordingHandler {
+	return &RecordingHandler{DB: db, S3: s3, Bucket: bucket, Access: access}
 }
 
 func (h *RecordingHandler) ListRecordings(w http.ResponseWriter, r *http.Request) {
@@ -46,12 +58,34 @@ func (h *RecordingHandler) ListRecordings(w http.ResponseWriter, r *http.Request
 		return
 	}
 	cameraID := parts[2]
-	// Jika path berupa angka (id kamera), konversi ke stream_key terlebih dahulu
+	// Path boleh berupa id kamera atau stream_key; keduanya dipetakan ke kamera
+	// agar kepemilikan perusahaan dan ACL bisa diperiksa.
+	var (
+		camID     int64
+		companyID int64
+		sk        sql.NullString
+		err       error
+	)
 	if isDigits(cameraID) {
-		var sk sql.NullString
-		if err := h.DB.QueryRow(`SELECT stream_key FROM cameras WHERE id=$1`, cameraID).Scan(&sk); err == nil && sk.Valid && sk.String != "" {
-			cameraID = sk.String
-		}
+		err = h.DB.QueryRow(`SELECT id, company_id, stream_key FROM cameras WHERE id=$1`, cameraID).Scan(&camID, &companyID, &sk)
+	} else {
+		err = h.DB.QueryRow(`SELECT id, company_id, stream_key FROM cameras WHERE stream_key=$1`, cameraID).Scan(&camID, &companyID, &sk)
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"camera not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("lookup camera err: %v", err)
+		http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !h.canView(r, camID, companyID) {
+		http.Error(w, `{"error":"camera not found"}`, http.StatusNotFound)
+		return
+	}
+	if sk.Valid && sk.String != "" {
+		cameraID = sk.String
 	}
 
 	// window waktu: default 24 jam terakhir
@@ -117,6 +151,26 @@ func (h *RecordingHandler) ListRecordings(w http.ResponseWriter, r *http.Request
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// canView memeriksa kamera milik perusahaan user dan termasuk dalam scope ACL-nya.
+func (h *RecordingHandler) canView(r *http.Request, cameraID, companyID int64) bool {
+	if !policy.Has(r.Context(), policy.CompanyManage) {
+		claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+		cid, _ := claims["company_id"].(float64)
+		if int64(cid) != companyID {
+			return false
+		}
+	}
+	if h.Access == nil {
+		return true
+	}
+	scope, err := h.Access.Scope(r.Context())
+	if err != nil {
+		log.Printf("camera scope err: %v", err)
+		return false
+	}
+	return scope.Allows(cameraID)
+}
+
 func isDigits(s string) bool {
 	if s == "" {
 		return false
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"time"
+
+	pqx "github.com/lib/pq"
+)
+
+// AddEmailDigest menyimpan alert untuk email digest penerima rc.
+func (r *repository) AddEmailDigest(ctx context.Context, rc domain.EmailRecipient, alert []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO email_digest_queue (user_id, email, name, alert)
+		VALUES (NULLIF($1, 0), $2, $3, $4)`, rc.UserID, rc.Email, rc.Name, alert)
+	return err
+}
+
+// ClaimEmailDigests mengambil semua alert digest yang belum dipegang proses lain
+// dan menahannya selama lease. Alert yang tidak dihapus (pengiriman gagal atau
+// proses mati) diambil lagi setelah lease habis.
+func (r *repository) ClaimEmailDigests(ctx context.Context, lease time.Duration) ([]domain.EmailDigestItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE email_digest_queue
+		SET claimed_until = NOW() + $1 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM email_digest_queue
+			WHERE claimed_until IS NULL OR claimed_until <= NOW()
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, COALESCE(user_id, 0), email, name, alert, created_at`, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.EmailDigestItem
+	for rows.Next() {
+		var it domain.EmailDigestItem
+		if err := rows.Scan(&it.ID, &it.Recipient.UserID, &it.Recipient.Email, &it.Recipient.Name, &it.Alert, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		it.Recipient.Mode = domain.EmailAlertsDigest
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// DeleteEmailDigests menghapus alert yang email digest-nya sudah terkirim.
+func (r *repository) DeleteEmailDigests(ctx context.Context, ids []int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM email_digest_queue WHERE id = ANY($1)`, pqx.Array(ids))
+	return err
+}
@@ -0,0 +1,196 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Dispatcher mengirim notifikasi dari outbox dengan sekumpulan worker. Outbox yang
+// gagal dicoba ulang dengan exponential backoff; penerima yang sudah berhasil
+// dilewati pada percobaan berikutnya, dan setelah MaxAttempts outbox ditandai failed.
+type Dispatcher struct {
+	repo       Repository
+	notifier   notifier.Notifier
+	loadReport func(ctx context.Context, anomalyID int64) (*domain.AnomalyReport, error)
+	wake       chan struct{}
+
+	Workers      int
+	PollInterval time.Duration
+	Lease        time.Duration // batas waktu satu percobaan sebelum outbox boleh diambil ulang
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewDispatcher(repo Repository, n notifier.Notifier, loadReport func(ctx context.Context, anomalyID int64) (*domain.AnomalyReport, error)) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		notifier:     n,
+		loadReport:   loadReport,
+		wake:         make(chan struct{}, 1),
+		Workers:      4,
+		PollInterval: 5 * time.Second,
+		Lease:        2 * time.Minute,
+		MaxAttempts:  6,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+	}
+}
+
+// Wake membangunkan satu worker tanpa menunggu PollInterval (dipanggil setelah anomali tersimpan).
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run menjalankan worker sampai ctx dibatalkan.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.worker(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	for {
+		items, err := d.repo.ClaimOutbox(ctx, 1, d.Lease)
+		if err != nil {
+			log.Printf("outbox: claim: %v", err)
+		}
+		if len(items) > 0 {
+			d.process(ctx, items[0])
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-time.After(d.PollInterval):
+		}
+	}
+}
+
+func (d *Dispatcher) process(ctx context.Context, it OutboxItem) {
+	err := d.deliver(ctx, it)
+	if err == nil {
+		if err := d.repo.CompleteOutbox(ctx, it.ID); err != nil {
+			log.Printf("outbox: complete %d: %v", it.ID, err)
+		}
+		return
+	}
+
+	final := it.Attempts >= d.MaxAttempts
+	if final {
+		log.Printf("outbox: notifikasi %s gagal setelah %d percobaan: %v", it, it.Attempts, err)
+	} else {
+		log.Printf("outbox: notifikasi %s gagal (percobaan %d), dicoba ulang: %v", it, it.Attempts, err)
+	}
+	next := time.Now().Add(d.backoff(it.Attempts))
+	if err := d.repo.FailOutbox(ctx, it.ID, err.Error(), next, final); err != nil {
+		log.Printf("outbox: fail %d: %v", it.ID, err)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, it OutboxItem) error {
+	report, err := d.report(ctx, it)
+	if err != nil {
+		return err
+	}
+	sent, err := d.repo.SentRecipients(ctx, it.ID)
+	if err != nil {
+		return fmt.Errorf("load deliveries: %w", err)
+	}
+	t := &outboxTracker{ctx: ctx, repo: d.repo, outboxID: it.ID, sent: sent}
+	if err := d.notifier.NotifyAnomaly(notifier.WithTracker(ctx, t), report); err != nil {
+		return err
+	}
+	return t.err()
+}
+
+// report memuat laporan anomali outbox, atau membaca laporan semu yang disimpan
+// bersama outbox (kamera offline).
+func (d *Dispatcher) report(ctx context.Context, it OutboxItem) (*domain.AnomalyReport, error) {
+	if it.AnomalyID == 0 {
+		var r domain.AnomalyReport
+		if err := json.Unmarshal(it.Report, &r); err != nil {
+			return nil, fmt.Errorf("decode report: %w", err)
+		}
+		return &r, nil
+	}
+	r, err := d.loadReport(ctx, it.AnomalyID)
+	if err != nil {
+		return nil, fmt.Errorf("load anomaly: %w", err)
+	}
+	return r, nil
+}
+
+// backoff: BaseBackoff, 2×, 4×, ... dibatasi MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.BaseBackoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait
+}
+
+// outboxTracker menyimpan hasil per penerima ke notification_deliveries.
+type outboxTracker struct {
+	ctx      context.Context
+	repo     Repository
+	outboxID int64
+
+	mu        sync.Mutex
+	sent      map[string]bool // terkirim atau ditolak permanen
+	recordErr error
+}
+
+func (t *outboxTracker) Sent(channel, recipient string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sent[channel+"\x00"+recipient]
+}
+
+func (t *outboxTracker) Record(channel, recipient string, err error) {
+	status, msg := DeliverySent, ""
+	if err != nil {
+		status, msg = DeliveryFailed, err.Error()
+		if notifier.IsFinal(err) {
+			status = DeliveryRejected
+		}
+	}
+	dbErr := t.repo.RecordDelivery(t.ctx, t.outboxID, channel, recipient, status, msg)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if dbErr != nil {
+		// Hasil yang tidak tercatat membuat outbox dicoba ulang; lebih baik kirim dua kali daripada hilang.
+		t.recordErr = errors.Join(t.recordErr, fmt.Errorf("record delivery %s/%s: %w", channel, recipient, dbErr))
+		return
+	}
+	if status != DeliveryFailed {
+		t.sent[channel+"\x00"+recipient] = true
+	}
+}
+
+func (t *outboxTracker) err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.recordErr
+}
@@ -0,0 +1,249 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/notifier"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memOutbox meniru notification_outbox dan notification_deliveries di memori.
+type memOutbox struct {
+	Repository
+	mu         sync.Mutex // RecordDelivery dipanggil paralel oleh MultiNotifier
+	items      map[int64]*memOutboxItem
+	deliveries map[string]string // channel + "\x00" + recipient → status
+	recordErr  error
+}
+
+type memOutboxItem struct {
+	OutboxItem
+	status    string
+	lastError string
+	next      time.Time
+}
+
+func newMemOutbox(items ...OutboxItem) *memOutbox {
+	m := &memOutbox{items: map[int64]*memOutboxItem{}, deliveries: map[string]string{}}
+	for _, it := range items {
+		m.items[it.ID] = &memOutboxItem{OutboxItem: it, status: "pending"}
+	}
+	return m
+}
+
+func (m *memOutbox) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxItem, error) {
+	var out []OutboxItem
+	for _, it := range m.items {
+		if it.status == "pending" && len(out) < limit {
+			it.Attempts++
+			out = append(out, it.OutboxItem)
+		}
+	}
+	return out, nil
+}
+
+func (m *memOutbox) SentRecipients(ctx context.Context, outboxID int64) (map[string]bool, error) {
+	sent := map[string]bool{}
+	for key, status := range m.deliveries {
+		if status != DeliveryFailed {
+			sent[key] = true
+		}
+	}
+	return sent, nil
+}
+
+func (m *memOutbox) RecordDelivery(ctx context.Context, outboxID int64, channel, recipient, status, errMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.recordErr != nil {
+		return m.recordErr
+	}
+	m.deliveries[channel+"\x00"+recipient] = status
+	return nil
+}
+
+func (m *memOutbox) CompleteOutbox(ctx context.Context, id int64) error {
+	m.items[id].status = "sent"
+	return nil
+}
+
+func (m *memOutbox) FailOutbox(ctx context.Context, id int64, errMsg string, next time.Time, final bool) error {
+	it := m.items[id]
+	it.lastError, it.next = errMsg, next
+	if final {
+		it.status = "failed"
+	}
+	return nil
+}
+
+// channelQueue adalah channel webhook palsu; fail menentukan hasil per percobaan.
+type channelQueue struct {
+	calls int
+	fail  func(call int) error
+}
+
+func (q *channelQueue) notifier() *notifier.WebhookNotifier {
+	return &notifier.WebhookNotifier{
+		Enqueue: func(ctx context.Context, companyID, cameraID int64, event string, data any) (int, error) {
+			q.calls++
+			if q.fail != nil {
+				return 0, q.fail(q.calls)
+			}
+			return 1, nil
+		},
+	}
+}
+
+func newTestDispatcher(repo Repository, channels map[string]*channelQueue) *Dispatcher {
+	multi := notifier.NewMultiNotifier(time.Second)
+	for name, q := range channels {
+		multi.Add(name, q.notifier())
+	}
+	d := NewDispatcher(repo, multi, func(ctx context.Context, anomalyID int64) (*domain.AnomalyReport, error) {
+		return &domain.AnomalyReport{ID: anomalyID, CameraID: 5, CompanyID: 1}, nil
+	})
+	d.MaxAttempts = 3
+	d.BaseBackoff = 10 * time.Second
+	d.MaxBackoff = time.Minute
+	return d
+}
+
+// claimAndProcess menjalankan satu putaran worker.
+func claimAndProcess(t *testing.T, d *Dispatcher, repo *memOutbox) {
+	t.Helper()
+	items, err := repo.ClaimOutbox(context.Background(), 1, d.Lease)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("ClaimOutbox = %v, %v", items, err)
+	}
+	d.process(context.Background(), items[0])
+}
+
+func TestDispatcherRetriesOnlyFailedChannels(t *testing.T) {
+	repo := newMemOutbox(OutboxItem{ID: 1, AnomalyID: 42})
+	ok := &channelQueue{}
+	flaky := &channelQueue{fail: func(call int) error {
+		if call == 1 {
+			return errors.New("timeout")
+		}
+		return nil
+	}}
+	d := newTestDispatcher(repo, map[string]*channelQueue{"ok": ok, "flaky": flaky})
+
+	start := time.Now()
+	claimAndProcess(t, d, repo)
+	it := repo.items[1]
+	if it.status != "pending" || it.lastError == "" {
+		t.Fatalf("setelah percobaan 1: status %s, error %q", it.status, it.lastError)
+	}
+	if wait := it.next.Sub(start); wait < d.BaseBackoff || wait > d.BaseBackoff+time.Second {
+		t.Fatalf("percobaan berikut %s lagi, want %s", wait, d.BaseBackoff)
+	}
+	if repo.deliveries["ok\x00queue"] != DeliverySent || repo.deliveries["flaky\x00queue"] != DeliveryFailed {
+		t.Fatalf("deliveries = %v", repo.deliveries)
+	}
+
+	claimAndProcess(t, d, repo)
+	if it.status != "sent" {
+		t.Fatalf("setelah percobaan 2: status %s, want sent", it.status)
+	}
+	if ok.calls != 1 || flaky.calls != 2 {
+		t.Fatalf("ok dikirim %d kali, flaky %d kali; want 1 dan 2", ok.calls, flaky.calls)
+	}
+}
+
+func TestDispatcherFinalStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       func(call int) error
+		wantStatus []string // status outbox setelah tiap percobaan
+		wantCalls  int
+		wantRecord string
+	}{
+		{
+			name:       "gagal terus sampai MaxAttempts",
+			fail:       func(int) error { return errors.New("smtp down") },
+			wantStatus: []string{"pending", "pending", "failed"},
+			wantCalls:  3,
+			wantRecord: DeliveryFailed,
+		},
+		{
+			name:       "ditolak permanen tidak dikirim ulang",
+			fail:       func(int) error { return notifier.Final(errors.New("payload ditolak")) },
+			wantStatus: []string{"pending", "sent"},
+			wantCalls:  1,
+			wantRecord: DeliveryRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemOutbox(OutboxItem{ID: 1, AnomalyID: 42})
+			q := &channelQueue{fail: tt.fail}
+			d := newTestDispatcher(repo, map[string]*channelQueue{"webhook": q})
+
+			for i, want := range tt.wantStatus {
+				claimAndProcess(t, d, repo)
+				if got := repo.items[1].status; got != want {
+					t.Fatalf("percobaan %d: status %s, want %s", i+1, got, want)
+				}
+			}
+			if q.calls != tt.wantCalls {
+				t.Fatalf("dikirim %d kali, want %d", q.calls, tt.wantCalls)
+			}
+			if got := repo.deliveries["webhook\x00queue"]; got != tt.wantRecord {
+				t.Fatalf("delivery = %s, want %s", got, tt.wantRecord)
+			}
+		})
+	}
+}
+
+func TestDispatcherRetriesWhenDeliveryNotRecorded(t *testing.T) {
+	repo := newMemOutbox(OutboxItem{ID: 1, AnomalyID: 42})
+	repo.recordErr = errors.New("db down")
+	d := newTestDispatcher(repo, map[string]*channelQueue{"webhook": {}})
+
+	claimAndProcess(t, d, repo)
+	if it := repo.items[1]; it.status != "pending" || it.lastError == "" {
+		t.Fatalf("status %s, error %q; want pending dengan error", it.status, it.lastError)
+	}
+}
+
+func TestDispatcherEmbeddedReport(t *testing.T) {
+	report, _ := json.Marshal(domain.AnomalyReport{CameraID: 5, CompanyID: 1, AnomalyType: domain.AnomalyTypeCameraOffline})
+	repo := newMemOutbox(OutboxItem{ID: 1, Report: report})
+	d := newTestDispatcher(repo, nil)
+	d.loadReport = func(ctx context.Context, anomalyID int64) (*domain.AnomalyReport, error) {
+		t.Fatal("loadReport dipanggil untuk outbox tanpa anomali")
+		return nil, nil
+	}
+
+	got, err := d.report(context.Background(), repo.items[1].OutboxItem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CameraID != 5 || got.AnomalyType != domain.AnomalyTypeCameraOffline {
+		t.Fatalf("report = %+v", got)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{20, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
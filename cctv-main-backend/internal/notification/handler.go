@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+type routesBody struct {
+	Channels []string                   `json:"channels,omitempty"`
+	Routes   []domain.NotificationRoute `json:"routes"`
+}
+
+// GET /api/notification-routes → {"channels": [...], "routes": [...]}
+func (h *Handler) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	routes, err := h.service.ListRoutes(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil aturan notifikasi", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routesBody{Channels: h.service.Channels(), Routes: routes})
+}
+
+// PUT /api/notification-routes  body: {"routes": [{"channel": "push"}, {"channel": "webhook", "anomaly_types": ["fight"]}]}
+// Mengganti semua aturan perusahaan; daftar kosong = semua channel untuk semua anomali.
+func (h *Handler) SetRoutes(w http.ResponseWriter, r *http.Request) {
+	var body routesBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.ReplaceRoutes(r.Context(), companyScope(r), body.Routes); err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownChannel), errors.Is(err, ErrInvalidRoute):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Gagal menyimpan aturan notifikasi", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Aturan notifikasi berhasil diperbarui."))
+}
+
+// GET /api/anomalies/{id}/notifications → status outbox dan hasil kirim per penerima.
+// Token perangkat disamarkan; pemegang company:manage melihat semua perusahaan.
+func (h *Handler) GetAnomalyNotifications(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	anomalyID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	companyID := companyScope(r)
+	if policy.Has(r.Context(), policy.CompanyManage) && r.URL.Query().Get("company_id") == "" {
+		companyID = 0
+	}
+
+	list, err := h.service.ListOutbox(r.Context(), anomalyID, companyID)
+	if err != nil {
+		http.Error(w, "Gagal mengambil status notifikasi", http.StatusInternalServerError)
+		return
+	}
+	if len(list) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	for i := range list {
+		for j := range list[i].Deliveries {
+			list[i].Deliveries[j].Recipient = maskRecipient(list[i].Deliveries[j].Recipient)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// maskRecipient menyamarkan token perangkat (panjang, tanpa "@") menjadi 8 karakter terakhir.
+func maskRecipient(rc string) string {
+	if len(rc) <= 24 || strings.Contains(rc, "@") {
+		return rc
+	}
+	return "…" + rc[len(rc)-8:]
+}
@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// OutboxItem adalah baris outbox yang sedang dipegang dispatcher. Alert tanpa
+// baris anomali (kamera offline) membawa laporannya sendiri di Report (JSON)
+// dengan AnomalyID 0.
+type OutboxItem struct {
+	ID        int64
+	AnomalyID int64
+	Report    []byte
+	Attempts  int
+}
+
+// String dipakai di log dispatcher.
+func (it OutboxItem) String() string {
+	if it.AnomalyID == 0 {
+		return fmt.Sprintf("outbox %d", it.ID)
+	}
+	return fmt.Sprintf("anomaly %d", it.AnomalyID)
+}
+
+// ClaimOutbox mengambil outbox yang jatuh tempo dan menggeser next_attempt_at sejauh
+// lease; outbox milik proses yang mati otomatis dicoba lagi setelah lease habis.
+func (r *repository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE notification_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, COALESCE(anomaly_id, 0), report, attempts`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []OutboxItem
+	for rows.Next() {
+		var it OutboxItem
+		if err := rows.Scan(&it.ID, &it.AnomalyID, &it.Report, &it.Attempts); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// Status hasil kirim per penerima (notification_deliveries.status).
+const (
+	DeliverySent     = "sent"
+	DeliveryFailed   = "failed"   // gagal sementara, dicoba lagi bersama outbox
+	DeliveryRejected = "rejected" // gagal permanen (notifier.Final), tidak dicoba lagi
+)
+
+// SentRecipients mengembalikan penerima yang sudah selesai (terkirim atau ditolak
+// permanen), dengan key channel + "\x00" + recipient.
+func (r *repository) SentRecipients(ctx context.Context, outboxID int64) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT channel, recipient FROM notification_deliveries
+		WHERE outbox_id = $1 AND status IN ('sent', 'rejected')`, outboxID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sent := map[string]bool{}
+	for rows.Next() {
+		var channel, recipient string
+		if err := rows.Scan(&channel, &recipient); err != nil {
+			return nil, err
+		}
+		sent[channel+"\x00"+recipient] = true
+	}
+	return sent, rows.Err()
+}
+
+func (r *repository) RecordDelivery(ctx context.Context, outboxID int64, channel, recipient, status, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO notification_deliveries (outbox_id, channel, recipient, status, last_error)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (outbox_id, channel, recipient) DO UPDATE
+		SET status = EXCLUDED.status, last_error = EXCLUDED.last_error,
+		    attempts = notification_deliveries.attempts + 1, updated_at = NOW()`,
+		outboxID, channel, recipient, status, errMsg)
+	return err
+}
+
+func (r *repository) CompleteOutbox(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_outbox SET status = 'done', last_error = NULL, completed_at = NOW()
+		WHERE id = $1`, id)
+	return err
+}
+
+// FailOutbox menjadwalkan ulang outbox pada next, atau menandainya failed bila final.
+func (r *repository) FailOutbox(ctx context.Context, id int64, errMsg string, next time.Time, final bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_outbox
+		SET status = CASE WHEN $4 THEN 'failed' ELSE 'pending' END,
+		    last_error = $2, next_attempt_at = $3,
+		    completed_at = CASE WHEN $4 THEN NOW() END
+		WHERE id = $1`, id, errMsg, next, final)
+	return err
+}
+
+// ListOutboxByAnomaly mengembalikan outbox anomali beserta hasil per penerima; companyID 0 = semua perusahaan.
+func (r *repository) ListOutboxByAnomaly(ctx context.Context, anomalyID, companyID int64) ([]domain.NotificationOutbox, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.id, o.anomaly_id, o.status, o.attempts, o.next_attempt_at, COALESCE(o.last_error, ''), o.created_at, o.completed_at
+		FROM notification_outbox o
+		JOIN anomaly_reports a ON a.id = o.anomaly_id
+		JOIN cameras c ON c.id = a.camera_id
+		WHERE o.anomaly_id = $1 AND ($2 = 0 OR c.company_id = $2)
+		ORDER BY o.id ASC`, anomalyID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []domain.NotificationOutbox{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var o domain.NotificationOutbox
+		var completedAt sql.NullTime
+		if err := rows.Scan(&o.ID, &o.AnomalyID, &o.Status, &o.Attempts, &o.NextAttemptAt, &o.LastError, &o.CreatedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			o.CompletedAt = &completedAt.Time
+		}
+		o.Deliveries = []domain.NotificationDelivery{}
+		index[o.ID] = len(list)
+		list = append(list, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	drows, err := r.db.QueryContext(ctx, `
+		SELECT d.outbox_id, d.channel, d.recipient, d.status, d.attempts, COALESCE(d.last_error, ''), d.updated_at
+		FROM notification_deliveries d JOIN notification_outbox o ON o.id = d.outbox_id
+		WHERE o.anomaly_id = $1
+		ORDER BY d.channel, d.recipient`, anomalyID)
+	if err != nil {
+		return nil, err
+	}
+	defer drows.Close()
+	for drows.Next() {
+		var outboxID int64
+		var d domain.NotificationDelivery
+		if err := drows.Scan(&outboxID, &d.Channel, &d.Recipient, &d.Status, &d.Attempts, &d.LastError, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[outboxID]; ok {
+			list[i].Deliveries = append(list[i].Deliveries, d)
+		}
+	}
+	return list, drows.Err()
+}
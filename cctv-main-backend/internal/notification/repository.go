@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"time"
+
+	pqx "github.com/lib/pq"
+)
+
+type Repository interface {
+	ListRoutes(ctx context.Context, companyID int64) ([]domain.NotificationRoute, error)
+	// ListRoutesByCameraID mengambil aturan milik perusahaan pemilik kamera.
+	ListRoutesByCameraID(ctx context.Context, cameraID int64) ([]domain.NotificationRoute, error)
+	// ReplaceRoutes mengganti seluruh aturan perusahaan dalam satu transaksi.
+	ReplaceRoutes(ctx context.Context, companyID int64, routes []domain.NotificationRoute) error
+
+	// Outbox notifikasi (lihat outbox.go)
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxItem, error)
+	SentRecipients(ctx context.Context, outboxID int64) (map[string]bool, error)
+	RecordDelivery(ctx context.Context, outboxID int64, channel, recipient, status, errMsg string) error
+	CompleteOutbox(ctx context.Context, id int64) error
+	FailOutbox(ctx context.Context, id int64, errMsg string, next time.Time, final bool) error
+	ListOutboxByAnomaly(ctx context.Context, anomalyID, companyID int64) ([]domain.NotificationOutbox, error)
+
+	// Antrean email digest (lihat digest.go)
+	AddEmailDigest(ctx context.Context, rc domain.EmailRecipient, alert []byte) error
+	ClaimEmailDigests(ctx context.Context, lease time.Duration) ([]domain.EmailDigestItem, error)
+	DeleteEmailDigests(ctx context.Context, ids []int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+const routeColumns = `id, company_id, channel, anomaly_types, min_confidence, created_at`
+
+func (r *repository) query(ctx context.Context, where string, arg int64) ([]domain.NotificationRoute, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+routeColumns+` FROM notification_routes WHERE `+where+` ORDER BY id ASC`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	routes := []domain.NotificationRoute{}
+	for rows.Next() {
+		var rt domain.NotificationRoute
+		var types pqx.StringArray
+		var minConf sql.NullFloat64
+		if err := rows.Scan(&rt.ID, &rt.CompanyID, &rt.Channel, &types, &minConf, &rt.CreatedAt); err != nil {
+			return nil, err
+		}
+		rt.AnomalyTypes = []string(types)
+		if rt.AnomalyTypes == nil {
+			rt.AnomalyTypes = []string{}
+		}
+		if minConf.Valid {
+			rt.MinConfidence = &minConf.Float64
+		}
+		routes = append(routes, rt)
+	}
+	return routes, rows.Err()
+}
+
+func (r *repository) ListRoutes(ctx context.Context, companyID int64) ([]domain.NotificationRoute, error) {
+	return r.query(ctx, `company_id = $1`, companyID)
+}
+
+func (r *repository) ListRoutesByCameraID(ctx context.Context, cameraID int64) ([]domain.NotificationRoute, error) {
+	return r.query(ctx, `company_id = (SELECT company_id FROM cameras WHERE id = $1)`, cameraID)
+}
+
+func (r *repository) ReplaceRoutes(ctx context.Context, companyID int64, routes []domain.NotificationRoute) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notification_routes WHERE company_id = $1`, companyID); err != nil {
+		return err
+	}
+	for _, rt := range routes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_routes (company_id, channel, anomaly_types, min_confidence)
+			VALUES ($1, $2, $3, $4)`,
+			companyID, rt.Channel, pqx.Array(rt.AnomalyTypes), rt.MinConfidence); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownChannel = errors.New("channel notifikasi tidak dikenal")
+	ErrInvalidRoute   = errors.New("aturan routing tidak valid")
+)
+
+type Service interface {
+	// Channels mengembalikan channel yang aktif di server ini.
+	Channels() []string
+	ListRoutes(ctx context.Context, companyID int64) ([]domain.NotificationRoute, error)
+	ReplaceRoutes(ctx context.Context, companyID int64, routes []domain.NotificationRoute) error
+
+	// Route memilih channel untuk laporan; nil berarti semua channel (perusahaan tanpa aturan).
+	Route(ctx context.Context, r *domain.AnomalyReport) ([]string, error)
+
+	// ListOutbox mengembalikan status notifikasi anomali per penerima; companyID 0 = semua perusahaan.
+	ListOutbox(ctx context.Context, anomalyID, companyID int64) ([]domain.NotificationOutbox, error)
+}
+
+type service struct {
+	repo     Repository
+	channels []string
+}
+
+func NewService(repo Repository, channels []string) Service {
+	return &service{repo: repo, channels: channels}
+}
+
+func (s *service) Channels() []string {
+	return s.channels
+}
+
+func (s *service) ListRoutes(ctx context.Context, companyID int64) ([]domain.NotificationRoute, error) {
+	return s.repo.ListRoutes(ctx, companyID)
+}
+
+func (s *service) ReplaceRoutes(ctx context.Context, companyID int64, routes []domain.NotificationRoute) error {
+	for i := range routes {
+		rt := &routes[i]
+		rt.Channel = strings.TrimSpace(rt.Channel)
+		if !s.hasChannel(rt.Channel) {
+			return fmt.Errorf("%w: %s", ErrUnknownChannel, rt.Channel)
+		}
+		if rt.MinConfidence != nil && (*rt.MinConfidence < 0 || *rt.MinConfidence > 1) {
+			return fmt.Errorf("%w: min_confidence harus 0..1", ErrInvalidRoute)
+		}
+		types := make([]string, 0, len(rt.AnomalyTypes))
+		for _, t := range rt.AnomalyTypes {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+		rt.AnomalyTypes = types
+	}
+	return s.repo.ReplaceRoutes(ctx, companyID, routes)
+}
+
+func (s *service) Route(ctx context.Context, r *domain.AnomalyReport) ([]string, error) {
+	routes, err := s.repo.ListRoutesByCameraID(ctx, r.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	selected := []string{}
+	seen := map[string]bool{}
+	for _, rt := range routes {
+		if !seen[rt.Channel] && rt.Matches(r) {
+			seen[rt.Channel] = true
+			selected = append(selected, rt.Channel)
+		}
+	}
+	return selected, nil
+}
+
+func (s *service) ListOutbox(ctx context.Context, anomalyID, companyID int64) ([]domain.NotificationOutbox, error) {
+	return s.repo.ListOutboxByAnomaly(ctx, anomalyID, companyID)
+}
+
+func (s *service) hasChannel(name string) bool {
+	for _, c := range s.channels {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
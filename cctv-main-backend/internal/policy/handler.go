@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if Has(r.Context(), CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+// GET /api/roles
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.ListRoles(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil data peran", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// POST /api/roles  body: {"name": "security guard", "description": "...", "permissions": ["camera:read", ...]}
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var role domain.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	role.CompanyID = companyScope(r)
+
+	id, err := h.service.CreateRole(r.Context(), &role)
+	if err != nil {
+		writeRoleError(w, err, "Gagal membuat peran")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"role_id": id})
+}
+
+// PUT /api/roles/{id}
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	var role domain.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	role.ID = id
+	role.CompanyID = companyScope(r)
+
+	if err := h.service.UpdateRole(r.Context(), &role); err != nil {
+		writeRoleError(w, err, "Gagal memperbarui peran")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Peran berhasil diperbarui."))
+}
+
+// DELETE /api/roles/{id}
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	if err := h.service.DeleteRole(r.Context(), id, companyScope(r)); err != nil {
+		writeRoleError(w, err, "Gagal menghapus peran")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Peran berhasil dihapus."))
+}
+
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrRoleExists), errors.Is(err, ErrRoleInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrRoleNotAssignable):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrInvalidRole), errors.Is(err, ErrReservedRole), errors.Is(err, ErrInvalidPermission):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"context"
+	"sort"
+)
+
+// Permission adalah hak akses berbentuk "resource:aksi".
+type Permission string
+
+const (
+	CameraRead         Permission = "camera:read"
+	CameraWrite        Permission = "camera:write"
+	CameraDelete       Permission = "camera:delete"
+	AnomalyRead        Permission = "anomaly:read"
+	AnomalyWrite       Permission = "anomaly:write"
+	RecordingRead      Permission = "recording:read"
+	UserRead           Permission = "user:read"
+	UserManage         Permission = "user:manage"
+	RoleManage         Permission = "role:manage"
+	NotificationManage Permission = "notification:manage"
+
+	// CompanyManage adalah hak level platform: kelola perusahaan dan akses lintas
+	// perusahaan. Tidak bisa diberikan lewat peran kustom.
+	CompanyManage Permission = "company:manage"
+)
+
+const (
+	RoleSuperadmin   = "superadmin"
+	RoleCompanyAdmin = "company_admin"
+	RoleUser         = "user"
+)
+
+// tenantPermissions adalah hak yang boleh dipakai peran kustom perusahaan.
+var tenantPermissions = []Permission{
+	CameraRead, CameraWrite, CameraDelete,
+	AnomalyRead, AnomalyWrite,
+	RecordingRead,
+	UserRead, UserManage, RoleManage,
+	NotificationManage,
+}
+
+var builtinRoles = map[string][]Permission{
+	RoleSuperadmin:   append(append([]Permission{}, tenantPermissions...), CompanyManage),
+	RoleCompanyAdmin: tenantPermissions,
+	RoleUser:         {CameraRead, AnomalyRead, RecordingRead, UserRead},
+}
+
+var builtinDescriptions = map[string]string{
+	RoleSuperadmin:   "Administrator platform, akses semua perusahaan",
+	RoleCompanyAdmin: "Administrator perusahaan",
+	RoleUser:         "Lihat kamera, anomali, dan rekaman",
+}
+
+// Set adalah kumpulan permission hasil resolve sebuah peran.
+type Set map[Permission]struct{}
+
+func NewSet(perms ...Permission) Set {
+	s := make(Set, len(perms))
+	for _, p := range perms {
+		s[p] = struct{}{}
+	}
+	return s
+}
+
+func (s Set) Has(p Permission) bool {
+	_, ok := s[p]
+	return ok
+}
+
+// SubsetOf melaporkan apakah semua permission s juga dimiliki o.
+func (s Set) SubsetOf(o Set) bool {
+	for p := range s {
+		if !o.Has(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Set) Strings() []string {
+	out := make([]string, 0, len(s))
+	for p := range s {
+		out = append(out, string(p))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func IsBuiltinRole(name string) bool {
+	_, ok := builtinRoles[name]
+	return ok
+}
+
+// IsTenantPermission melaporkan apakah p boleh dipakai di peran kustom.
+func IsTenantPermission(p Permission) bool {
+	for _, t := range tenantPermissions {
+		if t == p {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+// WithPermissions menyimpan permission hasil resolve ke context request.
+func WithPermissions(ctx context.Context, s Set) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext mengambil permission yang dipasang oleh authMiddleware.
+func FromContext(ctx context.Context) Set {
+	s, _ := ctx.Value(ctxKey{}).(Set)
+	return s
+}
+
+// Has adalah pintasan FromContext(ctx).Has(p) untuk dipakai di handler.
+func Has(ctx context.Context, p Permission) bool {
+	return FromContext(ctx).Has(p)
+}
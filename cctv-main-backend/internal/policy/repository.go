@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrRoleNotFound = errors.New("peran tidak ditemukan")
+	ErrRoleExists   = errors.New("nama peran sudah dipakai")
+	ErrRoleInUse    = errors.New("peran masih dipakai oleh pengguna")
+)
+
+type Repository interface {
+	ListRoles(ctx context.Context, companyID int64) ([]domain.Role, error)
+	GetRoleByName(ctx context.Context, companyID int64, name string) (*domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role) (int64, error)
+	UpdateRole(ctx context.Context, role *domain.Role) error
+	DeleteRole(ctx context.Context, id, companyID int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListRoles(ctx context.Context, companyID int64) ([]domain.Role, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, company_id, name, COALESCE(description, ''), permissions, created_at
+		FROM company_roles WHERE company_id = $1 ORDER BY name ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		if err := rows.Scan(&role.ID, &role.CompanyID, &role.Name, &role.Description, pqx.Array(&role.Permissions), &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *repository) GetRoleByName(ctx context.Context, companyID int64, name string) (*domain.Role, error) {
+	var role domain.Role
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, company_id, name, COALESCE(description, ''), permissions, created_at
+		FROM company_roles WHERE company_id = $1 AND name = $2`, companyID, name,
+	).Scan(&role.ID, &role.CompanyID, &role.Name, &role.Description, pqx.Array(&role.Permissions), &role.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *repository) CreateRole(ctx context.Context, role *domain.Role) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO company_roles (company_id, name, description, permissions)
+		VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id`,
+		role.CompanyID, role.Name, role.Description, pqx.Array(role.Permissions),
+	).Scan(&id)
+	if err != nil {
+		if pe, ok := err.(*pqx.Error); ok && string(pe.Code) == "23505" {
+			return 0, ErrRoleExists
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateRole mengganti deskripsi dan permission. Nama tidak bisa diubah karena
+// users.role menyimpan nama peran.
+func (r *repository) UpdateRole(ctx context.Context, role *domain.Role) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE company_roles SET description = NULLIF($1, ''), permissions = $2
+		WHERE id = $3 AND company_id = $4`,
+		role.Description, pqx.Array(role.Permissions), role.ID, role.CompanyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteRole(ctx context.Context, id, companyID int64) error {
+	var inUse bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM users u JOIN company_roles cr ON cr.company_id = u.company_id AND cr.name = u.role
+			WHERE cr.id = $1 AND cr.company_id = $2
+		)`, id, companyID).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrRoleInUse
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM company_roles WHERE id = $1 AND company_id = $2`, id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
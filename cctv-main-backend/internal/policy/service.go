@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidRole       = errors.New("nama peran tidak valid")
+	ErrReservedRole      = errors.New("nama peran bawaan tidak bisa dipakai")
+	ErrInvalidPermission = errors.New("permission tidak valid untuk peran perusahaan")
+	ErrRoleNotAssignable = errors.New("peran tidak boleh diberikan")
+)
+
+var roleName = regexp.MustCompile(`^[a-z0-9][a-z0-9_ -]{0,49}$`)
+
+type Service interface {
+	// Resolve mengubah peran (bawaan atau kustom milik companyID) menjadi Set permission.
+	Resolve(ctx context.Context, role string, companyID int64) (Set, error)
+	// ValidateAssignable memastikan role ada untuk companyID dan semua
+	// permission-nya dimiliki pemanggil.
+	ValidateAssignable(ctx context.Context, companyID int64, role string) error
+	// ValidateManageable memastikan pemanggil memiliki semua permission peran
+	// yang sedang dipegang user target sebelum peran itu diganti atau usernya dihapus.
+	ValidateManageable(ctx context.Context, companyID int64, currentRole string) error
+	ListRoles(ctx context.Context, companyID int64) ([]domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role) (int64, error)
+	UpdateRole(ctx context.Context, role *domain.Role) error
+	DeleteRole(ctx context.Context, id, companyID int64) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Resolve(ctx context.Context, role string, companyID int64) (Set, error) {
+	if perms, ok := builtinRoles[role]; ok {
+		return NewSet(perms...), nil
+	}
+	if companyID <= 0 {
+		return Set{}, nil
+	}
+	custom, err := s.repo.GetRoleByName(ctx, companyID, role)
+	if errors.Is(err, ErrRoleNotFound) {
+		// Peran dihapus/tidak dikenal: tidak punya hak apa pun.
+		return Set{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	set := Set{}
+	for _, p := range custom.Permissions {
+		// Saring ulang untuk berjaga-jaga bila data di DB diubah manual.
+		if IsTenantPermission(Permission(p)) {
+			set[Permission(p)] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+func (s *service) ValidateAssignable(ctx context.Context, companyID int64, role string) error {
+	perms, ok := builtinRoles[role]
+	if !ok {
+		custom, err := s.repo.GetRoleByName(ctx, companyID, role)
+		if err != nil {
+			return err
+		}
+		perms = toPermissions(custom.Permissions)
+	}
+	// Pemanggil tidak boleh memberikan hak yang tidak ia miliki sendiri.
+	if !NewSet(perms...).SubsetOf(FromContext(ctx)) {
+		return ErrRoleNotAssignable
+	}
+	return nil
+}
+
+func (s *service) ValidateManageable(ctx context.Context, companyID int64, currentRole string) error {
+	perms, err := s.Resolve(ctx, currentRole, companyID)
+	if err != nil {
+		return err
+	}
+	if !perms.SubsetOf(FromContext(ctx)) {
+		return ErrRoleNotAssignable
+	}
+	return nil
+}
+
+// ListRoles mengembalikan peran bawaan diikuti peran kustom perusahaan.
+func (s *service) ListRoles(ctx context.Context, companyID int64) ([]domain.Role, error) {
+	names := make([]string, 0, len(builtinRoles))
+	for name := range builtinRoles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	roles := make([]domain.Role, 0, len(names))
+	for _, name := range names {
+		roles = append(roles, domain.Role{
+			Name:        name,
+			Description: builtinDescriptions[name],
+			Permissions: NewSet(builtinRoles[name]...).Strings(),
+			Builtin:     true,
+		})
+	}
+	custom, err := s.repo.ListRoles(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	return append(roles, custom...), nil
+}
+
+func (s *service) CreateRole(ctx context.Context, role *domain.Role) (int64, error) {
+	role.Name = strings.ToLower(strings.TrimSpace(role.Name))
+	if !roleName.MatchString(role.Name) {
+		return 0, ErrInvalidRole
+	}
+	if IsBuiltinRole(role.Name) {
+		return 0, ErrReservedRole
+	}
+	perms, err := normalizePermissions(ctx, role.Permissions)
+	if err != nil {
+		return 0, err
+	}
+	role.Permissions = perms
+	return s.repo.CreateRole(ctx, role)
+}
+
+func (s *service) UpdateRole(ctx context.Context, role *domain.Role) error {
+	perms, err := normalizePermissions(ctx, role.Permissions)
+	if err != nil {
+		return err
+	}
+	role.Permissions = perms
+	return s.repo.UpdateRole(ctx, role)
+}
+
+func (s *service) DeleteRole(ctx context.Context, id, companyID int64) error {
+	return s.repo.DeleteRole(ctx, id, companyID)
+}
+
+// normalizePermissions menyaring permission peran kustom: hanya hak tenant
+// yang juga dimiliki pemanggil, supaya peran tidak bisa dipakai menaikkan hak.
+func normalizePermissions(ctx context.Context, in []string) ([]string, error) {
+	set := Set{}
+	for _, p := range in {
+		perm := Permission(strings.TrimSpace(p))
+		if !IsTenantPermission(perm) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPermission, p)
+		}
+		set[perm] = struct{}{}
+	}
+	if !set.SubsetOf(FromContext(ctx)) {
+		return nil, ErrRoleNotAssignable
+	}
+	return set.Strings(), nil
+}
+
+func toPermissions(in []string) []Permission {
+	out := make([]Permission, 0, len(in))
+	for _, p := range in {
+		out = append(out, Permission(p))
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeRepo struct {
+	Repository
+	roles map[string]*domain.Role
+}
+
+func (f *fakeRepo) GetRoleByName(ctx context.Context, companyID int64, name string) (*domain.Role, error) {
+	if r, ok := f.roles[name]; ok && r.CompanyID == companyID {
+		return r, nil
+	}
+	return nil, ErrRoleNotFound
+}
+
+func (f *fakeRepo) CreateRole(ctx context.Context, role *domain.Role) (int64, error) { return 1, nil }
+func (f *fakeRepo) UpdateRole(ctx context.Context, role *domain.Role) error          { return nil }
+
+func callerCtx(role string) context.Context {
+	return WithPermissions(context.Background(), NewSet(builtinRoles[role]...))
+}
+
+func TestValidateAssignable(t *testing.T) {
+	svc := NewService(&fakeRepo{roles: map[string]*domain.Role{
+		"guard":   {CompanyID: 1, Name: "guard", Permissions: []string{"camera:read", "anomaly:read"}},
+		"manager": {CompanyID: 1, Name: "manager", Permissions: []string{"camera:read", "user:manage", "role:manage"}},
+	}})
+
+	tests := []struct {
+		name    string
+		caller  string
+		role    string
+		company int64
+		want    error
+	}{
+		{"superadmin assigns superadmin", RoleSuperadmin, RoleSuperadmin, 1, nil},
+		{"company admin assigns superadmin", RoleCompanyAdmin, RoleSuperadmin, 1, ErrRoleNotAssignable},
+		{"company admin assigns company admin", RoleCompanyAdmin, RoleCompanyAdmin, 1, nil},
+		{"company admin assigns custom role", RoleCompanyAdmin, "guard", 1, nil},
+		{"user assigns user", RoleUser, RoleUser, 1, nil},
+		{"user assigns company admin", RoleUser, RoleCompanyAdmin, 1, ErrRoleNotAssignable},
+		{"user assigns narrower custom role", RoleUser, "guard", 1, nil},
+		{"user assigns wider custom role", RoleUser, "manager", 1, ErrRoleNotAssignable},
+		{"custom role of other company", RoleCompanyAdmin, "guard", 2, ErrRoleNotFound},
+		{"unknown role", RoleSuperadmin, "nope", 1, ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.ValidateAssignable(callerCtx(tt.caller), tt.company, tt.role)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ValidateAssignable(%q) = %v, want %v", tt.role, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAssignableWithoutPermissions(t *testing.T) {
+	svc := NewService(&fakeRepo{})
+	if err := svc.ValidateAssignable(context.Background(), 1, RoleUser); !errors.Is(err, ErrRoleNotAssignable) {
+		t.Fatalf("ValidateAssignable without permissions = %v, want %v", err, ErrRoleNotAssignable)
+	}
+}
+
+func TestValidateManageable(t *testing.T) {
+	svc := NewService(&fakeRepo{roles: map[string]*domain.Role{
+		"guard":   {CompanyID: 1, Name: "guard", Permissions: []string{"camera:read", "anomaly:read"}},
+		"manager": {CompanyID: 1, Name: "manager", Permissions: []string{"camera:read", "user:manage", "role:manage"}},
+	}})
+
+	tests := []struct {
+		name   string
+		caller string
+		target string
+		want   error
+	}{
+		{"company admin manages user", RoleCompanyAdmin, RoleUser, nil},
+		{"company admin manages company admin", RoleCompanyAdmin, RoleCompanyAdmin, nil},
+		{"company admin manages superadmin", RoleCompanyAdmin, RoleSuperadmin, ErrRoleNotAssignable},
+		{"user manages narrower custom role", RoleUser, "guard", nil},
+		{"user manages wider custom role", RoleUser, "manager", ErrRoleNotAssignable},
+		{"user manages company admin", RoleUser, RoleCompanyAdmin, ErrRoleNotAssignable},
+		{"deleted custom role has no permissions", RoleUser, "removed", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.ValidateManageable(callerCtx(tt.caller), 1, tt.target)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ValidateManageable(%q) = %v, want %v", tt.target, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomRolePermissions(t *testing.T) {
+	svc := NewService(&fakeRepo{})
+
+	tests := []struct {
+		name   string
+		caller string
+		perms  []string
+		want   error
+	}{
+		{"within caller permissions", RoleUser, []string{"camera:read", "anomaly:read"}, nil},
+		{"beyond caller permissions", RoleUser, []string{"camera:read", "user:manage"}, ErrRoleNotAssignable},
+		{"company admin grants all tenant permissions", RoleCompanyAdmin, []string{"user:manage", "role:manage"}, nil},
+		{"platform permission", RoleSuperadmin, []string{"company:manage"}, ErrInvalidPermission},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := callerCtx(tt.caller)
+			_, err := svc.CreateRole(ctx, &domain.Role{CompanyID: 1, Name: "custom", Permissions: tt.perms})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CreateRole = %v, want %v", err, tt.want)
+			}
+			err = svc.UpdateRole(ctx, &domain.Role{ID: 1, CompanyID: 1, Permissions: tt.perms})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateRole = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+// scheduleIDFromPath mengambil {id} dari /api/detection-schedules/{id}.
+func scheduleIDFromPath(path string) int64 {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(parts[2], 10, 64)
+	return id
+}
+
+// decodeSchedule membaca body; enabled bernilai true bila tidak diisi.
+func decodeSchedule(r *http.Request) (*domain.DetectionSchedule, error) {
+	sc := domain.DetectionSchedule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		return nil, err
+	}
+	sc.CompanyID = companyScope(r)
+	return &sc, nil
+}
+
+// GET /api/detection-schedules
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.List(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil jadwal deteksi", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GET /api/detection-schedules/{id}
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	sc, err := h.service.Get(r.Context(), scheduleIDFromPath(r.URL.Path), companyScope(r))
+	if err != nil {
+		writeScheduleError(w, err, "Gagal mengambil jadwal deteksi")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sc)
+}
+
+// POST /api/detection-schedules
+// body: {"name": "Gudang malam", "mode": "arm", "windows": [{"days": ["mon","tue","wed","thu","fri"], "start": "18:00", "end": "07:00"}], "camera_ids": [3], "group_ids": [1]}
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	sc, err := decodeSchedule(r)
+	if err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	id, err := h.service.Create(r.Context(), sc)
+	if err != nil {
+		writeScheduleError(w, err, "Gagal membuat jadwal deteksi")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"schedule_id": id})
+}
+
+// PUT /api/detection-schedules/{id} (mengganti seluruh jadwal termasuk kamera/grup)
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	sc, err := decodeSchedule(r)
+	if err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	sc.ID = scheduleIDFromPath(r.URL.Path)
+	if err := h.service.Update(r.Context(), sc); err != nil {
+		writeScheduleError(w, err, "Gagal memperbarui jadwal deteksi")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Jadwal deteksi berhasil diperbarui."))
+}
+
+// DELETE /api/detection-schedules/{id}
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), scheduleIDFromPath(r.URL.Path), companyScope(r)); err != nil {
+		writeScheduleError(w, err, "Gagal menghapus jadwal deteksi")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Jadwal deteksi berhasil dihapus."))
+}
+
+func writeScheduleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNameExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidSchedule), errors.Is(err, ErrForeignTarget):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
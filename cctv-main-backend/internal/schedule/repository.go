@@ -0,0 +1,231 @@
+package schedule
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrNotFound   = errors.New("jadwal deteksi tidak ditemukan")
+	ErrNameExists = errors.New("nama jadwal sudah dipakai")
+	// ErrForeignTarget dikembalikan bila kamera/grup target bukan milik perusahaan jadwal.
+	ErrForeignTarget = errors.New("kamera atau grup bukan milik perusahaan ini")
+)
+
+type Repository interface {
+	List(ctx context.Context, companyID int64) ([]domain.DetectionSchedule, error)
+	Get(ctx context.Context, id, companyID int64) (*domain.DetectionSchedule, error)
+	Create(ctx context.Context, s *domain.DetectionSchedule) (int64, error)
+	Update(ctx context.Context, s *domain.DetectionSchedule) error
+	Delete(ctx context.Context, id, companyID int64) error
+
+	// ForCameras mengembalikan jadwal aktif per kamera, baik yang dipasang langsung
+	// maupun lewat grup kamera.
+	ForCameras(ctx context.Context, cameraIDs []int64) (map[int64][]domain.DetectionSchedule, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+const scheduleColumns = `s.id, s.company_id, s.name, s.mode, s.windows, s.enabled, s.created_at, s.updated_at,
+	ARRAY(SELECT camera_id FROM detection_schedule_targets WHERE schedule_id = s.id AND camera_id IS NOT NULL ORDER BY camera_id),
+	ARRAY(SELECT group_id FROM detection_schedule_targets WHERE schedule_id = s.id AND group_id IS NOT NULL ORDER BY group_id)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSchedule(row rowScanner, extra ...any) (*domain.DetectionSchedule, error) {
+	var s domain.DetectionSchedule
+	var windows []byte
+	var cameraIDs, groupIDs pqx.Int64Array
+	dest := append(extra, &s.ID, &s.CompanyID, &s.Name, &s.Mode, &windows, &s.Enabled, &s.CreatedAt, &s.UpdatedAt, &cameraIDs, &groupIDs)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(windows, &s.Windows); err != nil {
+		return nil, err
+	}
+	if s.Windows == nil {
+		s.Windows = []domain.ScheduleWindow{}
+	}
+	s.CameraIDs = []int64(cameraIDs)
+	s.GroupIDs = []int64(groupIDs)
+	return &s, nil
+}
+
+func (r *repository) List(ctx context.Context, companyID int64) ([]domain.DetectionSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM detection_schedules s WHERE s.company_id = $1 ORDER BY s.name ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []domain.DetectionSchedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, id, companyID int64) (*domain.DetectionSchedule, error) {
+	s, err := scanSchedule(r.db.QueryRowContext(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM detection_schedules s WHERE s.id = $1 AND s.company_id = $2`, id, companyID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return s, err
+}
+
+func (r *repository) Create(ctx context.Context, s *domain.DetectionSchedule) (int64, error) {
+	windows, err := json.Marshal(s.Windows)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO detection_schedules (company_id, name, mode, windows, enabled)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		s.CompanyID, s.Name, s.Mode, windows, s.Enabled,
+	).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrNameExists
+		}
+		return 0, err
+	}
+	if err := replaceTargets(ctx, tx, id, s); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *repository) Update(ctx context.Context, s *domain.DetectionSchedule) error {
+	windows, err := json.Marshal(s.Windows)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE detection_schedules SET name = $1, mode = $2, windows = $3, enabled = $4, updated_at = NOW()
+		WHERE id = $5 AND company_id = $6`,
+		s.Name, s.Mode, windows, s.Enabled, s.ID, s.CompanyID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrNameExists
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if err := replaceTargets(ctx, tx, s.ID, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceTargets mengganti kamera/grup jadwal setelah memastikan semuanya milik perusahaan jadwal.
+func replaceTargets(ctx context.Context, tx *sql.Tx, id int64, s *domain.DetectionSchedule) error {
+	var foreign int
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM UNNEST($1::bigint[]) AS x(id) WHERE NOT EXISTS (SELECT 1 FROM cameras WHERE id = x.id AND company_id = $3)) +
+			(SELECT COUNT(*) FROM UNNEST($2::bigint[]) AS x(id) WHERE NOT EXISTS (SELECT 1 FROM camera_groups WHERE id = x.id AND company_id = $3))`,
+		pqx.Array(s.CameraIDs), pqx.Array(s.GroupIDs), s.CompanyID,
+	).Scan(&foreign)
+	if err != nil {
+		return err
+	}
+	if foreign > 0 {
+		return ErrForeignTarget
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM detection_schedule_targets WHERE schedule_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO detection_schedule_targets (schedule_id, camera_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		id, pqx.Array(s.CameraIDs)); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO detection_schedule_targets (schedule_id, group_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		id, pqx.Array(s.GroupIDs))
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, id, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM detection_schedules WHERE id = $1 AND company_id = $2`, id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) ForCameras(ctx context.Context, cameraIDs []int64) (map[int64][]domain.DetectionSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.camera_id, `+scheduleColumns+`
+		FROM (
+			SELECT schedule_id, camera_id FROM detection_schedule_targets WHERE camera_id = ANY($1)
+			UNION
+			SELECT t.schedule_id, m.camera_id
+			FROM detection_schedule_targets t JOIN camera_group_members m ON m.group_id = t.group_id
+			WHERE m.camera_id = ANY($1)
+		) t
+		JOIN detection_schedules s ON s.id = t.schedule_id
+		WHERE s.enabled`, pqx.Array(cameraIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byCamera := map[int64][]domain.DetectionSchedule{}
+	for rows.Next() {
+		var cameraID int64
+		s, err := scanSchedule(rows, &cameraID)
+		if err != nil {
+			return nil, err
+		}
+		byCamera[cameraID] = append(byCamera[cameraID], *s)
+	}
+	return byCamera, rows.Err()
+}
+
+func isUniqueViolation(err error) bool {
+	pe, ok := err.(*pqx.Error)
+	return ok && string(pe.Code) == "23505"
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("jadwal deteksi tidak valid")
+
+type Service interface {
+	List(ctx context.Context, companyID int64) ([]domain.DetectionSchedule, error)
+	Get(ctx context.Context, id, companyID int64) (*domain.DetectionSchedule, error)
+	Create(ctx context.Context, s *domain.DetectionSchedule) (int64, error)
+	Update(ctx context.Context, s *domain.DetectionSchedule) error
+	Delete(ctx context.Context, id, companyID int64) error
+
+	// Armed melaporkan apakah kamera sedang armed pada waktu at.
+	Armed(ctx context.Context, cameraID int64, at time.Time) (bool, error)
+	// ArmedMap menghitung status armed untuk banyak kamera sekaligus (daftar kamera).
+	ArmedMap(ctx context.Context, cameraIDs []int64, at time.Time) (map[int64]bool, error)
+}
+
+type service struct {
+	repo Repository
+	loc  *time.Location // zona waktu jendela jadwal (APP_TZ)
+}
+
+func NewService(repo Repository, loc *time.Location) Service {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &service{repo: repo, loc: loc}
+}
+
+func (s *service) List(ctx context.Context, companyID int64) ([]domain.DetectionSchedule, error) {
+	return s.repo.List(ctx, companyID)
+}
+
+func (s *service) Get(ctx context.Context, id, companyID int64) (*domain.DetectionSchedule, error) {
+	return s.repo.Get(ctx, id, companyID)
+}
+
+func (s *service) Create(ctx context.Context, sc *domain.DetectionSchedule) (int64, error) {
+	if err := validate(sc); err != nil {
+		return 0, err
+	}
+	return s.repo.Create(ctx, sc)
+}
+
+func (s *service) Update(ctx context.Context, sc *domain.DetectionSchedule) error {
+	if err := validate(sc); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, sc)
+}
+
+func (s *service) Delete(ctx context.Context, id, companyID int64) error {
+	return s.repo.Delete(ctx, id, companyID)
+}
+
+func (s *service) Armed(ctx context.Context, cameraID int64, at time.Time) (bool, error) {
+	armed, err := s.ArmedMap(ctx, []int64{cameraID}, at)
+	if err != nil {
+		return true, err
+	}
+	return armed[cameraID], nil
+}
+
+func (s *service) ArmedMap(ctx context.Context, cameraIDs []int64, at time.Time) (map[int64]bool, error) {
+	byCamera, err := s.repo.ForCameras(ctx, cameraIDs)
+	if err != nil {
+		return nil, err
+	}
+	local := at.In(s.loc)
+	armed := make(map[int64]bool, len(cameraIDs))
+	for _, id := range cameraIDs {
+		armed[id] = domain.Armed(byCamera[id], local)
+	}
+	return armed, nil
+}
+
+func validate(sc *domain.DetectionSchedule) error {
+	sc.Name = strings.TrimSpace(sc.Name)
+	if sc.Name == "" {
+		return fmt.Errorf("%w: nama wajib diisi", ErrInvalidSchedule)
+	}
+	if sc.Mode == "" {
+		sc.Mode = domain.ScheduleModeArm
+	}
+	if sc.Mode != domain.ScheduleModeArm && sc.Mode != domain.ScheduleModeQuiet {
+		return fmt.Errorf("%w: mode harus arm atau quiet", ErrInvalidSchedule)
+	}
+	if len(sc.Windows) == 0 {
+		return fmt.Errorf("%w: minimal satu jendela waktu", ErrInvalidSchedule)
+	}
+	for i := range sc.Windows {
+		w := &sc.Windows[i]
+		if _, ok := domain.ParseClock(w.Start); !ok {
+			return fmt.Errorf("%w: start %q harus HH:MM", ErrInvalidSchedule, w.Start)
+		}
+		if _, ok := domain.ParseClock(w.End); !ok {
+			return fmt.Errorf("%w: end %q harus HH:MM", ErrInvalidSchedule, w.End)
+		}
+		for j, d := range w.Days {
+			if _, ok := domain.ParseWeekday(d); !ok {
+				return fmt.Errorf("%w: hari %q (pakai mon..sun)", ErrInvalidSchedule, d)
+			}
+			w.Days[j] = strings.ToLower(strings.TrimSpace(d))
+		}
+	}
+	if sc.CameraIDs == nil {
+		sc.CameraIDs = []int64{}
+	}
+	if sc.GroupIDs == nil {
+		sc.GroupIDs = []int64{}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package session
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token tidak valid atau kedaluwarsa")
+	// ErrRefreshTokenReused dikembalikan bila refresh token yang sudah dirotasi dipakai
+	// lagi; sesinya langsung dicabut karena token kemungkinan bocor.
+	ErrRefreshTokenReused = errors.New("refresh token sudah pernah dipakai")
+)
+
+type Repository interface {
+	CreateSession(ctx context.Context, userID int64, userAgent, tokenHash string, expiresAt time.Time) (int64, error)
+	// RotateRefreshToken menandai token lama terpakai dan menyimpan token baru dalam satu transaksi.
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*domain.Session, error)
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+	RevokeSession(ctx context.Context, sessionID, userID int64) error
+	RevokeUserSessions(ctx context.Context, userID int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateSession(ctx context.Context, userID int64, userAgent, tokenHash string, expiresAt time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO auth_sessions (user_id, user_agent, expires_at)
+		VALUES ($1, NULLIF($2, ''), $3) RETURNING id`,
+		userID, userAgent, expiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		id, tokenHash, expiresAt,
+	); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *repository) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*domain.Session, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		sessionID int64
+		usedAt    sql.NullTime
+		tokenExp  time.Time
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT session_id, used_at, expires_at FROM refresh_tokens
+		WHERE token_hash = $1 FOR UPDATE`, oldHash,
+	).Scan(&sessionID, &usedAt, &tokenExp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		// Reuse detection: cabut sesi di luar transaksi ini agar tetap tersimpan.
+		tx.Rollback()
+		if _, err := r.db.ExecContext(ctx,
+			`UPDATE auth_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, sessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(tokenExp) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var s domain.Session
+	var ua sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE auth_sessions SET last_used_at = NOW(), expires_at = $2
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, user_agent, created_at, last_used_at, expires_at`,
+		sessionID, expiresAt,
+	).Scan(&s.ID, &s.UserID, &ua, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.UserAgent = ua.String
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		sessionID, newHash, expiresAt,
+	); err != nil {
+		return nil, err
+	}
+	return &s, tx.Commit()
+}
+
+func (r *repository) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM auth_sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)`, sessionID,
+	).Scan(&ok)
+	return ok, err
+}
+
+func (r *repository) RevokeSession(ctx context.Context, sessionID, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE auth_sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		sessionID, userID)
+	return err
+}
+
+func (r *repository) RevokeUserSessions(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	return err
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+type Service interface {
+	// Start membuat sesi baru dan mengembalikan ID sesi beserta refresh token mentah.
+	Start(ctx context.Context, userID int64, userAgent string) (int64, string, error)
+	// Rotate menukar refresh token dengan yang baru.
+	Rotate(ctx context.Context, refreshToken string) (*domain.Session, string, error)
+	IsActive(ctx context.Context, sessionID int64) (bool, error)
+	Revoke(ctx context.Context, sessionID, userID int64) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
+
+type service struct {
+	repo       Repository
+	refreshTTL time.Duration
+}
+
+func NewService(repo Repository, refreshTTL time.Duration) Service {
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+	return &service{repo: repo, refreshTTL: refreshTTL}
+}
+
+func (s *service) Start(ctx context.Context, userID int64, userAgent string) (int64, string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := s.repo.CreateSession(ctx, userID, userAgent, hash, time.Now().Add(s.refreshTTL))
+	if err != nil {
+		return 0, "", err
+	}
+	return id, token, nil
+}
+
+func (s *service) Rotate(ctx context.Context, refreshToken string) (*domain.Session, string, error) {
+	if refreshToken == "" {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	sess, err := s.repo.RotateRefreshToken(ctx, hashToken(refreshToken), hash, time.Now().Add(s.refreshTTL))
+	if err != nil {
+		return nil, "", err
+	}
+	return sess, token, nil
+}
+
+func (s *service) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	return s.repo.IsActive(ctx, sessionID)
+}
+
+func (s *service) Revoke(ctx context.Context, sessionID, userID int64) error {
+	return s.repo.RevokeSession(ctx, sessionID, userID)
+}
+
+func (s *service) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return s.repo.RevokeUserSessions(ctx, userID)
+}
+
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
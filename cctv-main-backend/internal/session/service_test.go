@@ -0,0 +1,176 @@
+package session
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memRepo meniru perilaku tabel auth_sessions dan refresh_tokens di memori,
+// termasuk reuse detection pada RotateRefreshToken.
+type memRepo struct {
+	mu       sync.Mutex
+	seq      int64
+	sessions map[int64]*memSession
+	tokens   map[string]*memToken // key: hash
+}
+
+type memSession struct {
+	userID    int64
+	expiresAt time.Time
+	revoked   bool
+}
+
+type memToken struct {
+	sessionID int64
+	expiresAt time.Time
+	used      bool
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{sessions: map[int64]*memSession{}, tokens: map[string]*memToken{}}
+}
+
+func (r *memRepo) CreateSession(ctx context.Context, userID int64, userAgent, tokenHash string, expiresAt time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	r.sessions[r.seq] = &memSession{userID: userID, expiresAt: expiresAt}
+	r.tokens[tokenHash] = &memToken{sessionID: r.seq, expiresAt: expiresAt}
+	return r.seq, nil
+}
+
+func (r *memRepo) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tok, ok := r.tokens[oldHash]
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	sess := r.sessions[tok.sessionID]
+	if tok.used {
+		sess.revoked = true
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(tok.expiresAt) || sess.revoked || time.Now().After(sess.expiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	tok.used = true
+	sess.expiresAt = expiresAt
+	r.tokens[newHash] = &memToken{sessionID: tok.sessionID, expiresAt: expiresAt}
+	return &domain.Session{ID: tok.sessionID, UserID: sess.userID, ExpiresAt: expiresAt}, nil
+}
+
+func (r *memRepo) IsActive(ctx context.Context, sessionID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[sessionID]
+	return ok && !s.revoked && time.Now().Before(s.expiresAt), nil
+}
+
+func (r *memRepo) RevokeSession(ctx context.Context, sessionID, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[sessionID]; ok && s.userID == userID {
+		s.revoked = true
+	}
+	return nil
+}
+
+func (r *memRepo) RevokeUserSessions(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.userID == userID {
+			s.revoked = true
+		}
+	}
+	return nil
+}
+
+func TestRotateInvalidatesOldToken(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepo()
+	s := NewService(repo, time.Hour)
+
+	id, first, err := s.Start(ctx, 7, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.tokens[first]; ok {
+		t.Fatal("refresh token mentah tersimpan; yang disimpan harus hash-nya")
+	}
+
+	sess, second, err := s.Rotate(ctx, first)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if sess.ID != id || sess.UserID != 7 || second == "" || second == first {
+		t.Fatalf("Rotate = %+v, %q", sess, second)
+	}
+
+	// Token lama dipakai lagi: dianggap bocor, sesi dicabut.
+	if _, _, err := s.Rotate(ctx, first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Rotate token lama: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if active, _ := s.IsActive(ctx, id); active {
+		t.Fatal("sesi masih aktif setelah refresh token lama dipakai ulang")
+	}
+	// Token baru ikut mati bersama sesinya.
+	if _, _, err := s.Rotate(ctx, second); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Rotate token baru setelah reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRotateRejectsUnknownToken(t *testing.T) {
+	s := NewService(newMemRepo(), time.Hour)
+	for _, tok := range []string{"", "bukan-token"} {
+		if _, _, err := s.Rotate(context.Background(), tok); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("Rotate(%q): err = %v, want ErrInvalidRefreshToken", tok, err)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepo()
+	s := NewService(repo, time.Hour)
+
+	phone, phoneToken, _ := s.Start(ctx, 7, "phone")
+	laptop, _, _ := s.Start(ctx, 7, "laptop")
+	other, _, _ := s.Start(ctx, 8, "other")
+
+	// Logout hanya mencabut sesi pemanggil, dan hanya bila sesi itu miliknya.
+	if err := s.Revoke(ctx, other, 7); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := s.IsActive(ctx, other); !active {
+		t.Fatal("user bisa mencabut sesi milik user lain")
+	}
+	if err := s.Revoke(ctx, phone, 7); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := s.IsActive(ctx, phone); active {
+		t.Fatal("sesi masih aktif setelah logout")
+	}
+	if _, _, err := s.Rotate(ctx, phoneToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh setelah logout: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if active, _ := s.IsActive(ctx, laptop); !active {
+		t.Fatal("logout mencabut sesi lain milik user")
+	}
+
+	// Perubahan peran / penghapusan user mencabut semua sesinya.
+	if err := s.RevokeAllForUser(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := s.IsActive(ctx, laptop); active {
+		t.Fatal("sesi masih aktif setelah RevokeAllForUser")
+	}
+	if active, _ := s.IsActive(ctx, other); !active {
+		t.Fatal("RevokeAllForUser mencabut sesi user lain")
+	}
+}
@@ -0,0 +1,209 @@
+package site
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+// siteIDFromPath mengambil {id} dari /api/sites/{id}[/...].
+func siteIDFromPath(path string) int64 {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(parts[2], 10, 64)
+	return id
+}
+
+// GET /api/sites
+func (h *Handler) ListSites(w http.ResponseWriter, r *http.Request) {
+	sites, err := h.service.ListSites(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil data site", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sites)
+}
+
+// GET /api/sites/{id}
+func (h *Handler) GetSite(w http.ResponseWriter, r *http.Request) {
+	site, err := h.service.GetSite(r.Context(), siteIDFromPath(r.URL.Path), companyScope(r))
+	if err != nil {
+		writeSiteError(w, err, "Gagal mengambil data site")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(site)
+}
+
+// POST /api/sites  body: {"name": "Cabang Bandung", "address": "...", "notify_staff_only": false}
+func (h *Handler) CreateSite(w http.ResponseWriter, r *http.Request) {
+	var site domain.Site
+	if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	site.CompanyID = companyScope(r)
+
+	id, err := h.service.CreateSite(r.Context(), &site)
+	if err != nil {
+		writeSiteError(w, err, "Gagal membuat site")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"site_id": id})
+}
+
+// PUT /api/sites/{id}
+func (h *Handler) UpdateSite(w http.ResponseWriter, r *http.Request) {
+	var site domain.Site
+	if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	site.ID = siteIDFromPath(r.URL.Path)
+	site.CompanyID = companyScope(r)
+
+	if err := h.service.UpdateSite(r.Context(), &site); err != nil {
+		writeSiteError(w, err, "Gagal memperbarui site")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Site berhasil diperbarui."))
+}
+
+// DELETE /api/sites/{id}
+func (h *Handler) DeleteSite(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteSite(r.Context(), siteIDFromPath(r.URL.Path), companyScope(r)); err != nil {
+		writeSiteError(w, err, "Gagal menghapus site")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Site berhasil dihapus."))
+}
+
+// POST /api/sites/{id}/zones  body: {"name": "Lobi"}
+func (h *Handler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	var zone domain.Zone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	zone.SiteID = siteIDFromPath(r.URL.Path)
+
+	id, err := h.service.CreateZone(r.Context(), companyScope(r), &zone)
+	if err != nil {
+		writeSiteError(w, err, "Gagal membuat zone")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"zone_id": id})
+}
+
+// PUT /api/zones/{id}
+func (h *Handler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	var zone domain.Zone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	zone.ID = id
+
+	if err := h.service.UpdateZone(r.Context(), companyScope(r), &zone); err != nil {
+		writeSiteError(w, err, "Gagal memperbarui zone")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Zone berhasil diperbarui."))
+}
+
+// DELETE /api/zones/{id}
+func (h *Handler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+
+	if err := h.service.DeleteZone(r.Context(), id, companyScope(r)); err != nil {
+		writeSiteError(w, err, "Gagal menghapus zone")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Zone berhasil dihapus."))
+}
+
+// GET /api/sites/{id}/staff
+func (h *Handler) GetStaff(w http.ResponseWriter, r *http.Request) {
+	ids, err := h.service.GetStaff(r.Context(), siteIDFromPath(r.URL.Path), companyScope(r))
+	if err != nil {
+		writeSiteError(w, err, "Gagal mengambil staf site")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]int64{"user_ids": ids})
+}
+
+// PUT /api/sites/{id}/staff  body: {"user_ids": [4, 7]}
+func (h *Handler) SetStaff(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserIDs []int64 `json:"user_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetStaff(r.Context(), siteIDFromPath(r.URL.Path), companyScope(r), body.UserIDs); err != nil {
+		writeSiteError(w, err, "Gagal menyimpan staf site")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Staf site berhasil diperbarui."))
+}
+
+func writeSiteError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrSiteNotFound), errors.Is(err, ErrZoneNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNameExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidName), errors.Is(err, ErrForeignUser):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
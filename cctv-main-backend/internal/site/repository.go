@@ -0,0 +1,275 @@
+package site
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrSiteNotFound = errors.New("site tidak ditemukan")
+	ErrZoneNotFound = errors.New("zone tidak ditemukan")
+	ErrNameExists   = errors.New("nama sudah dipakai")
+	ErrForeignUser  = errors.New("pengguna bukan bagian dari perusahaan ini")
+)
+
+type Repository interface {
+	ListSites(ctx context.Context, companyID int64) ([]domain.Site, error)
+	GetSite(ctx context.Context, id, companyID int64) (*domain.Site, error)
+	CreateSite(ctx context.Context, s *domain.Site) (int64, error)
+	UpdateSite(ctx context.Context, s *domain.Site) error
+	DeleteSite(ctx context.Context, id, companyID int64) error
+
+	CreateZone(ctx context.Context, companyID int64, z *domain.Zone) (int64, error)
+	UpdateZone(ctx context.Context, companyID int64, z *domain.Zone) error
+	DeleteZone(ctx context.Context, id, companyID int64) error
+
+	GetStaff(ctx context.Context, siteID, companyID int64) ([]int64, error)
+	SetStaff(ctx context.Context, siteID, companyID int64, userIDs []int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListSites(ctx context.Context, companyID int64) ([]domain.Site, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, company_id, name, COALESCE(address, ''), notify_staff_only, created_at
+		FROM sites WHERE company_id = $1 ORDER BY name ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := []domain.Site{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var s domain.Site
+		if err := rows.Scan(&s.ID, &s.CompanyID, &s.Name, &s.Address, &s.NotifyStaffOnly, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Zones = []domain.Zone{}
+		index[s.ID] = len(sites)
+		sites = append(sites, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	zrows, err := r.db.QueryContext(ctx, `
+		SELECT z.id, z.site_id, z.name, z.created_at
+		FROM zones z JOIN sites s ON s.id = z.site_id
+		WHERE s.company_id = $1 ORDER BY z.name ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer zrows.Close()
+	for zrows.Next() {
+		var z domain.Zone
+		if err := zrows.Scan(&z.ID, &z.SiteID, &z.Name, &z.CreatedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[z.SiteID]; ok {
+			sites[i].Zones = append(sites[i].Zones, z)
+		}
+	}
+	return sites, zrows.Err()
+}
+
+func (r *repository) GetSite(ctx context.Context, id, companyID int64) (*domain.Site, error) {
+	var s domain.Site
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, company_id, name, COALESCE(address, ''), notify_staff_only, created_at
+		FROM sites WHERE id = $1 AND company_id = $2`, id, companyID,
+	).Scan(&s.ID, &s.CompanyID, &s.Name, &s.Address, &s.NotifyStaffOnly, &s.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSiteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.Zones = []domain.Zone{}
+	rows, err := r.db.QueryContext(ctx, `SELECT id, site_id, name, created_at FROM zones WHERE site_id = $1 ORDER BY name ASC`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var z domain.Zone
+		if err := rows.Scan(&z.ID, &z.SiteID, &z.Name, &z.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Zones = append(s.Zones, z)
+	}
+	return &s, rows.Err()
+}
+
+func (r *repository) CreateSite(ctx context.Context, s *domain.Site) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sites (company_id, name, address, notify_staff_only)
+		VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id`,
+		s.CompanyID, s.Name, s.Address, s.NotifyStaffOnly,
+	).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrNameExists
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *repository) UpdateSite(ctx context.Context, s *domain.Site) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sites SET name = $1, address = NULLIF($2, ''), notify_staff_only = $3
+		WHERE id = $4 AND company_id = $5`,
+		s.Name, s.Address, s.NotifyStaffOnly, s.ID, s.CompanyID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrNameExists
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSiteNotFound
+	}
+	return nil
+}
+
+// DeleteSite menghapus site beserta zone-nya; kamera di dalamnya tetap ada
+// dengan site_id/zone_id = NULL.
+func (r *repository) DeleteSite(ctx context.Context, id, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sites WHERE id = $1 AND company_id = $2`, id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSiteNotFound
+	}
+	return nil
+}
+
+func (r *repository) CreateZone(ctx context.Context, companyID int64, z *domain.Zone) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO zones (site_id, name)
+		SELECT id, $2 FROM sites WHERE id = $1 AND company_id = $3
+		RETURNING id`, z.SiteID, z.Name, companyID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrSiteNotFound
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrNameExists
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *repository) UpdateZone(ctx context.Context, companyID int64, z *domain.Zone) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE zones SET name = $1
+		WHERE id = $2 AND site_id IN (SELECT id FROM sites WHERE company_id = $3)`,
+		z.Name, z.ID, companyID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrNameExists
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrZoneNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteZone(ctx context.Context, id, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM zones WHERE id = $1 AND site_id IN (SELECT id FROM sites WHERE company_id = $2)`,
+		id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrZoneNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetStaff(ctx context.Context, siteID, companyID int64) ([]int64, error) {
+	if _, err := r.GetSite(ctx, siteID, companyID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM site_staff WHERE site_id = $1 ORDER BY user_id`, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetStaff mengganti daftar staf site dalam satu transaksi.
+func (r *repository) SetStaff(ctx context.Context, siteID, companyID int64, userIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM sites WHERE id = $1 AND company_id = $2)`, siteID, companyID,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSiteNotFound
+	}
+
+	var foreign int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM UNNEST($1::bigint[]) AS x(id)
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE id = x.id AND company_id = $2)`,
+		pqx.Array(userIDs), companyID,
+	).Scan(&foreign); err != nil {
+		return err
+	}
+	if foreign > 0 {
+		return ErrForeignUser
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM site_staff WHERE site_id = $1`, siteID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO site_staff (site_id, user_id)
+		SELECT $1, x FROM UNNEST($2::bigint[]) AS x ON CONFLICT DO NOTHING`,
+		siteID, pqx.Array(userIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func isUniqueViolation(err error) bool {
+	pe, ok := err.(*pqx.Error)
+	return ok && string(pe.Code) == "23505"
+}
@@ -0,0 +1,92 @@
+package site
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidName = errors.New("nama wajib diisi")
+
+type Service interface {
+	ListSites(ctx context.Context, companyID int64) ([]domain.Site, error)
+	GetSite(ctx context.Context, id, companyID int64) (*domain.Site, error)
+	CreateSite(ctx context.Context, s *domain.Site) (int64, error)
+	UpdateSite(ctx context.Context, s *domain.Site) error
+	DeleteSite(ctx context.Context, id, companyID int64) error
+
+	CreateZone(ctx context.Context, companyID int64, z *domain.Zone) (int64, error)
+	UpdateZone(ctx context.Context, companyID int64, z *domain.Zone) error
+	DeleteZone(ctx context.Context, id, companyID int64) error
+
+	GetStaff(ctx context.Context, siteID, companyID int64) ([]int64, error)
+	SetStaff(ctx context.Context, siteID, companyID int64, userIDs []int64) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) ListSites(ctx context.Context, companyID int64) ([]domain.Site, error) {
+	return s.repo.ListSites(ctx, companyID)
+}
+
+func (s *service) GetSite(ctx context.Context, id, companyID int64) (*domain.Site, error) {
+	return s.repo.GetSite(ctx, id, companyID)
+}
+
+func (s *service) CreateSite(ctx context.Context, site *domain.Site) (int64, error) {
+	site.Name = strings.TrimSpace(site.Name)
+	if site.Name == "" {
+		return 0, ErrInvalidName
+	}
+	return s.repo.CreateSite(ctx, site)
+}
+
+func (s *service) UpdateSite(ctx context.Context, site *domain.Site) error {
+	site.Name = strings.TrimSpace(site.Name)
+	if site.Name == "" {
+		return ErrInvalidName
+	}
+	return s.repo.UpdateSite(ctx, site)
+}
+
+func (s *service) DeleteSite(ctx context.Context, id, companyID int64) error {
+	return s.repo.DeleteSite(ctx, id, companyID)
+}
+
+func (s *service) CreateZone(ctx context.Context, companyID int64, z *domain.Zone) (int64, error) {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return 0, ErrInvalidName
+	}
+	return s.repo.CreateZone(ctx, companyID, z)
+}
+
+func (s *service) UpdateZone(ctx context.Context, companyID int64, z *domain.Zone) error {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return ErrInvalidName
+	}
+	return s.repo.UpdateZone(ctx, companyID, z)
+}
+
+func (s *service) DeleteZone(ctx context.Context, id, companyID int64) error {
+	return s.repo.DeleteZone(ctx, id, companyID)
+}
+
+func (s *service) GetStaff(ctx context.Context, siteID, companyID int64) ([]int64, error) {
+	return s.repo.GetStaff(ctx, siteID, companyID)
+}
+
+func (s *service) SetStaff(ctx context.Context, siteID, companyID int64, userIDs []int64) error {
+	if userIDs == nil {
+		userIDs = []int64{}
+	}
+	return s.repo.SetStaff(ctx, siteID, companyID, userIDs)
+}
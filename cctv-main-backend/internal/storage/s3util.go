@@ -5,6 +5,9 @@ import (
     "bytes"
     "context"
     "fmt"
+    "net/url"
+    "path"
+    "strings"
     "time"
 
     "github.com/aws/aws-sdk-go-v2/aws"
@@ -103,6 +106,22 @@ func (u *S3Util) Presign(bucket, key string, ttl time.Duration) (string, error)
 	return u.PublicURL(bucket, key), nil
 }
 
+// SplitObjectURL memecah URL/path objek ("/bucket/key" atau "http://host/bucket/key")
+// menjadi bucket dan key.
+func SplitObjectURL(u string) (bucket string, key string, ok bool) {
+	p, err := url.Parse(u)
+	if err != nil {
+		return "", "", false
+	}
+	seg := strings.Split(strings.Trim(p.Path, "/"), "/")
+	if len(seg) < 2 {
+		return "", "", false
+	}
+	bucket = seg[0]
+	key = path.Clean(strings.Join(seg[1:], "/"))
+	return bucket, key, true
+}
+
 // PublicURL membangun URL publik non-presign: publicBase/bucket/key.
 func (u *S3Util) PublicURL(bucket, key string) string {
 	base := u.publicBase
@@ -3,6 +3,8 @@ package user
 import (
     "bytes"
     "cctv-main-backend/internal/domain"
+    "cctv-main-backend/internal/policy"
+    "cctv-main-backend/internal/session"
     "cctv-main-backend/pkg/auth"
     "encoding/json"
     "errors"
@@ -16,10 +18,11 @@ import (
 
 type Handler struct {
 	service Service
+	roles   policy.Service
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, roles policy.Service) *Handler {
+	return &Handler{service: service, roles: roles}
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -42,15 +45,74 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
         input.Password = password
     }
 
-	token, err := h.service.Login(&input)
+	tokens, err := h.service.Login(&input, r.UserAgent())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		if errors.Is(err, ErrInvalidCredentials) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Gagal membuat sesi login", http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]string{"token": token}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// POST /api/auth/refresh  body: {"refresh_token": "..."}
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.RefreshToken == "" {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.service.Refresh(payload.RefreshToken)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Gagal memperbarui token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// POST /api/auth/logout  body (opsional): {"all": true} untuk keluar dari semua perangkat
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+	sessionID, _ := claims["sid"].(float64)
+
+	var payload struct {
+		All bool `json:"all"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	var err error
+	if payload.All {
+		err = h.service.LogoutAll(int64(userID))
+	} else {
+		err = h.service.Logout(int64(sessionID), int64(userID))
+	}
+	if err != nil {
+		http.Error(w, "Gagal logout", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
@@ -86,18 +148,23 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
         user.Role = "user"
     }
 
-    // Enforce company scoping for non-superadmin: use company_id from JWT
+    // Enforce company scoping for non-platform admins: use company_id from JWT
     if claims, ok := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims); ok {
-        if role, _ := claims["role"].(string); role != "superadmin" {
+        if !policy.Has(r.Context(), policy.CompanyManage) {
             if cid, ok2 := claims["company_id"].(float64); ok2 {
                 user.CompanyID = int64(cid)
             }
         }
     }
 
-	if user.Role == "" {
-		user.Role = "user"
-	}
+    if err := h.roles.ValidateAssignable(r.Context(), user.CompanyID, user.Role); err != nil {
+        if errors.Is(err, policy.ErrRoleNotAssignable) {
+            http.Error(w, "Anda tidak boleh memberikan peran: "+user.Role, http.StatusForbidden)
+            return
+        }
+        http.Error(w, "Peran tidak valid: "+user.Role, http.StatusBadRequest)
+        return
+    }
 
     err := h.service.Register(&user)
     if err != nil {
@@ -116,10 +183,9 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
     claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
-    role, _ := claims["role"].(string)
 
-    // Superadmin: allow optional ?company_id= to filter, otherwise list all users
-    if role == "superadmin" {
+    // Platform admin: allow optional ?company_id= to filter, otherwise list all users
+    if policy.Has(r.Context(), policy.CompanyManage) {
         if v := r.URL.Query().Get("company_id"); v != "" {
             if id, err := strconv.ParseInt(v, 10, 64); err == nil {
                 users, err := h.service.FindUsersByCompany(id)
@@ -150,12 +216,6 @@ func (h *Handler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
 	companyID, _ := claims["company_id"].(float64)
-	role, _ := claims["role"].(string)
-
-	if role != "company_admin" {
-		http.Error(w, "Anda tidak punya izin untuk melakukan aksi ini", http.StatusForbidden)
-		return
-	}
 
 	parts := strings.Split(r.URL.Path, "/")
 	userID, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
@@ -166,6 +226,17 @@ func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	newRole := reqBody["role"]
+	if !h.checkManageable(w, r, userID, int64(companyID)) {
+		return
+	}
+	if err := h.roles.ValidateAssignable(r.Context(), int64(companyID), newRole); err != nil {
+		if errors.Is(err, policy.ErrRoleNotAssignable) {
+			http.Error(w, "Anda tidak boleh memberikan peran: "+newRole, http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Peran tidak valid: "+newRole, http.StatusBadRequest)
+		return
+	}
 
 	if err := h.service.UpdateRole(userID, int64(companyID), newRole); err != nil {
 		http.Error(w, "Pengguna tidak ditemukan atau bukan bagian dari perusahaan Anda", http.StatusNotFound)
@@ -178,15 +249,12 @@ func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
 	companyID, _ := claims["company_id"].(float64)
-	role, _ := claims["role"].(string)
-
-	if role != "company_admin" {
-		http.Error(w, "Anda tidak punya izin untuk melakukan aksi ini", http.StatusForbidden)
-		return
-	}
 
 	parts := strings.Split(r.URL.Path, "/")
 	userID, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if !h.checkManageable(w, r, userID, int64(companyID)) {
+		return
+	}
 
 	if err := h.service.Delete(userID, int64(companyID)); err != nil {
 		http.Error(w, "Pengguna tidak ditemukan atau bukan bagian dari perusahaan Anda", http.StatusNotFound)
@@ -196,6 +264,26 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Pengguna berhasil dihapus."))
 }
 
+// checkManageable menolak perubahan pada user yang permission perannya saat ini
+// tidak seluruhnya dimiliki pemanggil, supaya admin terbatas tidak bisa
+// menurunkan atau menghapus user yang lebih berkuasa.
+func (h *Handler) checkManageable(w http.ResponseWriter, r *http.Request, userID, companyID int64) bool {
+	target, err := h.service.FindUser(userID, companyID)
+	if err != nil {
+		http.Error(w, "Pengguna tidak ditemukan atau bukan bagian dari perusahaan Anda", http.StatusNotFound)
+		return false
+	}
+	if err := h.roles.ValidateManageable(r.Context(), companyID, target.Role); err != nil {
+		if errors.Is(err, policy.ErrRoleNotAssignable) {
+			http.Error(w, "Anda tidak boleh mengubah pengguna dengan peran: "+target.Role, http.StatusForbidden)
+			return false
+		}
+		http.Error(w, "Gagal memeriksa peran pengguna", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 func (h *Handler) UpdateFCMToken(w http.ResponseWriter, r *http.Request) {
 	// Ambil ID pengguna dari token JWT yang sudah divalidasi
 	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
@@ -220,3 +308,210 @@ func (h *Handler) UpdateFCMToken(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Token FCM berhasil diperbarui."))
 }
+
+// GET /api/users/me/email-alerts → {"mode": "off|instant|digest"}
+// PUT /api/users/me/email-alerts  body: {"mode": "digest"}
+func (h *Handler) EmailAlerts(w http.ResponseWriter, r *http.Request) {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload struct {
+			Mode string `json:"mode"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.SetEmailAlerts(r.Context(), int64(userID), payload.Mode); err != nil {
+			if errors.Is(err, ErrInvalidEmailAlerts) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Gagal menyimpan pengaturan email", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mode, err := h.service.GetEmailAlerts(r.Context(), int64(userID))
+	if err != nil {
+		http.Error(w, "Gagal mengambil pengaturan email", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"mode": mode})
+}
+
+// GET /api/users/me/locale → {"locale": "id|en"}
+// PUT /api/users/me/locale  body: {"locale": "en"}
+func (h *Handler) Locale(w http.ResponseWriter, r *http.Request) {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload struct {
+			Locale string `json:"locale"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.SetLocale(r.Context(), int64(userID), payload.Locale); err != nil {
+			if errors.Is(err, ErrInvalidLocale) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Gagal menyimpan bahasa", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+
+	locale, err := h.service.GetLocale(r.Context(), int64(userID))
+	if err != nil {
+		http.Error(w, "Gagal mengambil bahasa", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"locale": locale})
+}
+
+// GET    /api/users/me/devices → perangkat push milik pemanggil
+// POST   /api/users/me/devices  body: {"token": "...", "platform": "android|ios|web", "device_name": "Pixel 8", "app_version": "1.4.0"}
+// DELETE /api/users/me/devices  body: {"token": "..."} (dipakai aplikasi saat logout)
+func (h *Handler) Devices(w http.ResponseWriter, r *http.Request) {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := h.service.ListDevices(r.Context(), int64(userID))
+		if err != nil {
+			http.Error(w, "Gagal mengambil perangkat", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	case http.MethodPost:
+		var payload struct {
+			Token      string `json:"token"`
+			Platform   string `json:"platform"`
+			Provider   string `json:"provider"`
+			DeviceName string `json:"device_name"`
+			AppVersion string `json:"app_version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+		d := domain.UserDevice{
+			UserID:     int64(userID),
+			Token:      payload.Token,
+			Platform:   payload.Platform,
+			Provider:   payload.Provider,
+			DeviceName: payload.DeviceName,
+			AppVersion: payload.AppVersion,
+		}
+		if err := h.service.RegisterDevice(r.Context(), &d); err != nil {
+			writeDeviceError(w, err, "Gagal mendaftarkan perangkat")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d)
+	case http.MethodDelete:
+		var payload struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.UnregisterDevice(r.Context(), int64(userID), 0, payload.Token); err != nil {
+			writeDeviceError(w, err, "Gagal menghapus perangkat")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Perangkat berhasil dihapus."))
+	default:
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+	}
+}
+
+// DELETE /api/users/me/devices/{id} → cabut perangkat dari daftar
+func (h *Handler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	deviceID, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil || deviceID <= 0 {
+		http.Error(w, "ID perangkat tidak valid", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.UnregisterDevice(r.Context(), int64(userID), deviceID, ""); err != nil {
+		writeDeviceError(w, err, "Gagal menghapus perangkat")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Perangkat berhasil dihapus."))
+}
+
+func writeDeviceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidDevice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDeviceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// GET /api/users/me/notification-settings
+// PUT /api/users/me/notification-settings  body: {"camera_ids": [3], "site_ids": [1], "min_confidence": 0.8, "anomaly_types": ["intrusion"],
+//     "channels": ["push"], "quiet_hours": [{"days": [], "start": "22:00", "end": "06:00"}], "email_mode": "digest"}
+// Field yang tidak dikirim pada PUT mempertahankan nilai sebelumnya.
+func (h *Handler) NotificationSettings(w http.ResponseWriter, r *http.Request) {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	userID, _ := claims["user_id"].(float64)
+
+	settings, err := h.service.GetNotificationSettings(r.Context(), int64(userID))
+	if err != nil {
+		http.Error(w, "Gagal mengambil pengaturan notifikasi", http.StatusInternalServerError)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(settings); err != nil {
+			http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.SetNotificationSettings(r.Context(), int64(userID), settings); err != nil {
+			if errors.Is(err, ErrInvalidSettings) || errors.Is(err, ErrInvalidEmailAlerts) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Gagal menyimpan pengaturan notifikasi", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Metode tidak diizinkan", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// customRoles menyediakan peran kustom untuk policy.Service asli.
+type customRoles struct {
+	policy.Repository
+	roles map[string]*domain.Role
+}
+
+func (c *customRoles) GetRoleByName(ctx context.Context, companyID int64, name string) (*domain.Role, error) {
+	if r, ok := c.roles[name]; ok && r.CompanyID == companyID {
+		return r, nil
+	}
+	return nil, policy.ErrRoleNotFound
+}
+
+func TestManageUserRequiresTargetPermissions(t *testing.T) {
+	roles := policy.NewService(&customRoles{roles: map[string]*domain.Role{
+		// Manajer user: hak user biasa ditambah user:manage, tanpa hak admin lain.
+		"user_manager": {CompanyID: 10, Name: "user_manager", Permissions: []string{"camera:read", "anomaly:read", "recording:read", "user:read", "user:manage"}},
+	}})
+
+	tests := []struct {
+		name   string
+		caller string
+		target string
+		method string
+		body   string
+		want   int
+	}{
+		{"ubah peran user biasa", "user_manager", policy.RoleUser, http.MethodPut, `{"role":"user_manager"}`, http.StatusOK},
+		{"turunkan company admin", "user_manager", policy.RoleCompanyAdmin, http.MethodPut, `{"role":"user"}`, http.StatusForbidden},
+		{"hapus company admin", "user_manager", policy.RoleCompanyAdmin, http.MethodDelete, "", http.StatusForbidden},
+		{"hapus user biasa", "user_manager", policy.RoleUser, http.MethodDelete, "", http.StatusOK},
+		{"company admin hapus company admin", policy.RoleCompanyAdmin, policy.RoleCompanyAdmin, http.MethodDelete, "", http.StatusOK},
+		{"company admin turunkan superadmin", policy.RoleCompanyAdmin, policy.RoleSuperadmin, http.MethodPut, `{"role":"user"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, sessions := newTestService(t)
+			repo.users[2].Role = tt.target
+			h := NewHandler(svc, roles)
+
+			caller, err := roles.Resolve(context.Background(), tt.caller, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.WithValue(context.Background(), auth.UserClaimsKey, jwt.MapClaims{"user_id": float64(1), "company_id": float64(10)})
+			ctx = policy.WithPermissions(ctx, caller)
+			req := httptest.NewRequest(tt.method, "/api/users/2", strings.NewReader(tt.body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			if tt.method == http.MethodDelete {
+				h.DeleteUser(rec, req)
+			} else {
+				h.UpdateUserRole(rec, req)
+			}
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusForbidden {
+				if u, ok := repo.users[2]; !ok || u.Role != tt.target {
+					t.Fatal("user target berubah walau permintaan ditolak")
+				}
+				if len(sessions.revokedUsers) != 0 {
+					t.Fatal("sesi user target dicabut walau permintaan ditolak")
+				}
+			}
+		})
+	}
+}
+
+func TestManageUserOfOtherCompany(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	repo.users[3] = &domain.User{ID: 3, CompanyID: 20, Role: policy.RoleUser}
+	h := NewHandler(svc, policy.NewService(&customRoles{}))
+
+	ctx := context.WithValue(context.Background(), auth.UserClaimsKey, jwt.MapClaims{"company_id": float64(10)})
+	ctx = policy.WithPermissions(ctx, policy.NewSet(policy.UserManage))
+	rec := httptest.NewRecorder()
+	h.DeleteUser(rec, httptest.NewRequest(http.MethodDelete, "/api/users/3", nil).WithContext(ctx))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if _, ok := repo.users[3]; !ok {
+		t.Fatal("user perusahaan lain terhapus")
+	}
+}
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"time"
+)
+
+// Recipients memilih penerima alert per channel berdasarkan preferensi notifikasi
+// masing-masing user (kamera/site, confidence, tipe, channel, jam tenang).
+// Dipakai sebagai hook notifier push dan email.
+type Recipients struct {
+	repo Repository
+	loc  *time.Location // zona waktu jam tenang (APP_TZ)
+}
+
+func NewRecipients(repo Repository, loc *time.Location) *Recipients {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Recipients{repo: repo, loc: loc}
+}
+
+// PushTargets mengembalikan token perangkat (tanpa duplikat) yang boleh menerima laporan.
+func (rc *Recipients) PushTargets(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.PushTarget, error) {
+	candidates, err := rc.repo.GetPushRecipients(ctx, companyID, r.CameraID)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	siteID, err := rc.repo.GetCameraSiteID(ctx, r.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(rc.loc)
+	seen := map[string]bool{}
+	var targets []domain.PushTarget
+	for _, c := range candidates {
+		if seen[c.Token] || !c.Settings.Allows(r, siteID, domain.UserChannelPush, now) {
+			continue
+		}
+		seen[c.Token] = true
+		targets = append(targets, domain.PushTarget{Token: c.Token, Provider: c.Provider, Locale: c.Locale})
+	}
+	return targets, nil
+}
+
+// EmailRecipients mengembalikan user opt-in email yang preferensinya menerima laporan.
+func (rc *Recipients) EmailRecipients(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.EmailRecipient, error) {
+	candidates, err := rc.repo.GetEmailRecipients(ctx, companyID, r.CameraID)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	siteID, err := rc.repo.GetCameraSiteID(ctx, r.CameraID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(rc.loc)
+	var list []domain.EmailRecipient
+	for _, c := range candidates {
+		if c.Settings.Allows(r, siteID, domain.UserChannelEmail, now) {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
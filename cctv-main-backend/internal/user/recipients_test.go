@@ -0,0 +1,124 @@
+package user
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"testing"
+	"time"
+)
+
+// recipientRepo mengembalikan kandidat penerima tetap untuk kamera mana pun.
+type recipientRepo struct {
+	Repository
+	push   []domain.PushRecipient
+	email  []domain.EmailRecipient
+	siteID *int64
+}
+
+func (r *recipientRepo) GetPushRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.PushRecipient, error) {
+	return r.push, nil
+}
+
+func (r *recipientRepo) GetEmailRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.EmailRecipient, error) {
+	return r.email, nil
+}
+
+func (r *recipientRepo) GetCameraSiteID(ctx context.Context, cameraID int64) (*int64, error) {
+	return r.siteID, nil
+}
+
+func settings(mod func(s *domain.NotificationSettings)) domain.NotificationSettings {
+	s := domain.DefaultNotificationSettings()
+	mod(&s)
+	return s
+}
+
+func TestRecipients(t *testing.T) {
+	site := int64(3)
+	half := 0.5
+	// Jendela 24 jam setiap hari: selalu jam tenang, apa pun jam test dijalankan.
+	alwaysQuiet := []domain.ScheduleWindow{{Start: "00:00", End: "00:00"}}
+	repo := &recipientRepo{
+		siteID: &site,
+		push: []domain.PushRecipient{
+			{UserID: 1, Token: "semua", Settings: domain.DefaultNotificationSettings()},
+			{UserID: 1, Token: "semua", Settings: domain.DefaultNotificationSettings()}, // duplikat
+			{UserID: 2, Token: "site", Settings: settings(func(s *domain.NotificationSettings) { s.SiteIDs = []int64{3} })},
+			{UserID: 3, Token: "kamera-lain", Settings: settings(func(s *domain.NotificationSettings) { s.CameraIDs = []int64{9} })},
+			{UserID: 4, Token: "email-saja", Settings: settings(func(s *domain.NotificationSettings) { s.Channels = []string{domain.UserChannelEmail} })},
+			{UserID: 5, Token: "tenang", Settings: settings(func(s *domain.NotificationSettings) { s.QuietHours = alwaysQuiet })},
+			{UserID: 6, Token: "yakin", Settings: settings(func(s *domain.NotificationSettings) { s.MinConfidence = &half })},
+		},
+		email: []domain.EmailRecipient{
+			{UserID: 1, Email: "a@example.com", Settings: domain.DefaultNotificationSettings()},
+			{UserID: 4, Email: "b@example.com", Settings: settings(func(s *domain.NotificationSettings) { s.Channels = []string{domain.UserChannelPush} })},
+			{UserID: 6, Email: "c@example.com", Settings: settings(func(s *domain.NotificationSettings) { s.AnomalyTypes = []string{"intrusion"} })},
+		},
+	}
+	rc := NewRecipients(repo, time.UTC)
+
+	tests := []struct {
+		name      string
+		report    *domain.AnomalyReport
+		wantPush  []string
+		wantEmail []string
+	}{
+		{
+			name:      "laporan biasa",
+			report:    &domain.AnomalyReport{CameraID: 5, AnomalyType: "fire", Confidence: 0.8},
+			wantPush:  []string{"semua", "site", "yakin"},
+			wantEmail: []string{"a@example.com"},
+		},
+		{
+			name:      "confidence rendah",
+			report:    &domain.AnomalyReport{CameraID: 5, AnomalyType: "intrusion", Confidence: 0.3},
+			wantPush:  []string{"semua", "site"},
+			wantEmail: []string{"a@example.com", "c@example.com"},
+		},
+		{
+			name:      "kamera offline lolos filter confidence dan tipe",
+			report:    &domain.AnomalyReport{CameraID: 5, AnomalyType: domain.AnomalyTypeCameraOffline},
+			wantPush:  []string{"semua", "site", "yakin"},
+			wantEmail: []string{"a@example.com", "c@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := rc.PushTargets(context.Background(), 1, tt.report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var push []string
+			for _, tg := range targets {
+				push = append(push, tg.Token)
+			}
+			if !equalStrings(push, tt.wantPush) {
+				t.Errorf("push = %v, want %v", push, tt.wantPush)
+			}
+
+			list, err := rc.EmailRecipients(context.Background(), 1, tt.report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var email []string
+			for _, r := range list {
+				email = append(email, r.Email)
+			}
+			if !equalStrings(email, tt.wantEmail) {
+				t.Errorf("email = %v, want %v", email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
     "cctv-main-backend/internal/domain"
     "context"
     "database/sql"
+    "encoding/json"
     "errors"
 
     pqx "github.com/lib/pq"
@@ -12,15 +13,36 @@ import (
 type Repository interface {
     CreateUser(user *domain.User) error
     GetUserByEmail(email string) (*domain.User, error)
+    GetUserByID(id int64) (*domain.User, error)
     GetUsersByCompanyID(companyID int64) ([]domain.User, error)
     GetAllUsers() ([]domain.User, error)
     UpdateUserRole(userID, companyID int64, role string) error
     DeleteUser(userID, companyID int64) error
-    UpdateFCMToken(userID int64, fcmToken string) error
     GetAdminFCMTokensByCompany(ctx context.Context, companyID int64) ([]string, error)
     DeleteFCMTokenByValue(ctx context.Context, token string) error
     // All roles tokens for a company (non-empty)
-    GetFCMTokensByCompanyAllRoles(ctx context.Context, companyID int64) ([]string, error)
+    GetFCMTokensByCompanyAllRoles(ctx context.Context, companyID, cameraID int64) ([]string, error)
+
+    // Perangkat push per user (user_devices)
+    // UpsertDevice mendaftarkan token; token yang sudah ada dipindah ke user ini dan diperbarui.
+    UpsertDevice(ctx context.Context, d *domain.UserDevice) error
+    ListDevices(ctx context.Context, userID int64) ([]domain.UserDevice, error)
+    DeleteDevice(ctx context.Context, userID, deviceID int64) error
+    DeleteDeviceByToken(ctx context.Context, userID int64, token string) error
+
+    GetEmailAlerts(ctx context.Context, userID int64) (string, error)
+    SetEmailAlerts(ctx context.Context, userID int64, mode string) error
+    GetLocale(ctx context.Context, userID int64) (string, error)
+    SetLocale(ctx context.Context, userID int64, locale string) error
+    // GetEmailRecipients mengembalikan user yang opt-in alert email dan boleh menerima alert kamera.
+    GetEmailRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.EmailRecipient, error)
+
+    GetNotificationSettings(ctx context.Context, userID int64) (*domain.NotificationSettings, error)
+    SaveNotificationSettings(ctx context.Context, userID int64, s *domain.NotificationSettings) error
+    // GetPushRecipients mengembalikan token perangkat user yang boleh menerima alert kamera,
+    // beserta preferensinya (disaring oleh Recipients).
+    GetPushRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.PushRecipient, error)
+    GetCameraSiteID(ctx context.Context, cameraID int64) (*int64, error)
 }
 
 type repository struct {
@@ -43,6 +65,20 @@ func (r *repository) GetUserByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
+func (r *repository) GetUserByID(id int64) (*domain.User, error) {
+	var user domain.User
+	var companyID sql.NullInt64
+	query := `SELECT id, email, company_id, role FROM users WHERE id=$1`
+
+	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Email, &companyID, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	user.CompanyID = companyID.Int64
+
+	return &user, nil
+}
+
 func (r *repository) CreateUser(user *domain.User) error {
     // Allow NULL company_id when not provided (e.g., superadmin user without a specific company)
     var companyArg interface{}
@@ -109,24 +145,11 @@ func (r *repository) DeleteUser(userID, companyID int64) error {
 	return nil
 }
 
-func (r *repository) UpdateFCMToken(userID int64, fcmToken string) error {
-	query := `UPDATE users SET fcm_token = $1 WHERE id = $2`
-	result, err := r.db.Exec(query, fcmToken, userID)
-	if err != nil {
-		return err
-	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
-}
-
 func (r *repository) GetAdminFCMTokensByCompany(ctx context.Context, companyID int64) ([]string, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT fcm_token
-		FROM users
-		WHERE company_id=$1 AND role='company_admin' AND fcm_token IS NOT NULL AND fcm_token <> ''`,
+		SELECT d.token
+		FROM user_devices d JOIN users u ON u.id = d.user_id
+		WHERE u.company_id=$1 AND u.role='company_admin'`,
 		companyID,
 	)
 	if err != nil {
@@ -145,7 +168,7 @@ func (r *repository) GetAdminFCMTokensByCompany(ctx context.Context, companyID i
 }
 
 func (r *repository) DeleteFCMTokenByValue(ctx context.Context, token string) error {
-    _, err := r.db.ExecContext(ctx, `UPDATE users SET fcm_token = NULL WHERE fcm_token = $1`, token)
+    _, err := r.db.ExecContext(ctx, `DELETE FROM user_devices WHERE token = $1`, token)
     return err
 }
 
@@ -163,14 +186,30 @@ func (r *repository) GetAllUsers() ([]domain.User, error) {
     return users, nil
 }
 
-// GetFCMTokensByCompanyAllRoles returns all non-empty FCM tokens for users in a company,
+// alertAudience membatasi user u ($1 = company) ke yang boleh melihat kamera $2
+// (ACL per kamera) dan, bila site kamera notify_staff_only, ke staf site tersebut.
+// $2 = 0 berarti tanpa batasan kamera.
+const alertAudience = `($2 = 0 OR EXISTS (
+              SELECT 1 FROM user_accessible_cameras a WHERE a.user_id = u.id AND a.camera_id = $2
+          ))
+          AND ($2 = 0 OR NOT EXISTS (
+              SELECT 1 FROM cameras c JOIN sites s ON s.id = c.site_id
+              WHERE c.id = $2 AND s.notify_staff_only
+          ) OR EXISTS (
+              SELECT 1 FROM cameras c JOIN site_staff ss ON ss.site_id = c.site_id
+              WHERE c.id = $2 AND ss.user_id = u.id
+          ))`
+
+// GetFCMTokensByCompanyAllRoles returns the FCM tokens of every device of users in a company,
 // regardless of role. Useful when wanting to notify all members.
-func (r *repository) GetFCMTokensByCompanyAllRoles(ctx context.Context, companyID int64) ([]string, error) {
+// When cameraID > 0 only users allowed to see that camera (per-camera ACL) are returned,
+// and if the camera's site has notify_staff_only set, only that site's staff.
+func (r *repository) GetFCMTokensByCompanyAllRoles(ctx context.Context, companyID, cameraID int64) ([]string, error) {
     rows, err := r.db.QueryContext(ctx, `
-        SELECT fcm_token
-        FROM users
-        WHERE company_id = $1 AND fcm_token IS NOT NULL AND fcm_token <> ''
-    `, companyID)
+        SELECT d.token
+        FROM user_devices d JOIN users u ON u.id = d.user_id
+        WHERE u.company_id = $1
+          AND `+alertAudience, companyID, cameraID)
     if err != nil {
         return nil, err
     }
@@ -185,3 +224,222 @@ func (r *repository) GetFCMTokensByCompanyAllRoles(ctx context.Context, companyI
     }
     return tokens, rows.Err()
 }
+
+func (r *repository) GetEmailAlerts(ctx context.Context, userID int64) (string, error) {
+	var mode string
+	err := r.db.QueryRowContext(ctx, `SELECT email_alerts FROM users WHERE id = $1`, userID).Scan(&mode)
+	return mode, err
+}
+
+func (r *repository) SetEmailAlerts(ctx context.Context, userID int64, mode string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET email_alerts = $2 WHERE id = $1`, userID, mode)
+	return err
+}
+
+func (r *repository) GetLocale(ctx context.Context, userID int64) (string, error) {
+	var locale string
+	err := r.db.QueryRowContext(ctx, `SELECT locale FROM users WHERE id = $1`, userID).Scan(&locale)
+	return locale, err
+}
+
+func (r *repository) SetLocale(ctx context.Context, userID int64, locale string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET locale = $2 WHERE id = $1`, userID, locale)
+	return err
+}
+
+func (r *repository) GetEmailRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.EmailRecipient, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, u.email, COALESCE(u.display_name, ''), u.email_alerts, `+settingsColumns+`
+		FROM users u LEFT JOIN user_notification_settings p ON p.user_id = u.id
+		WHERE u.company_id = $1 AND u.email_alerts <> 'off'
+		  AND `+alertAudience, companyID, cameraID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []domain.EmailRecipient
+	for rows.Next() {
+		var rc domain.EmailRecipient
+		settings, err := scanSettings(rows, &rc.UserID, &rc.Email, &rc.Name, &rc.Mode)
+		if err != nil {
+			return nil, err
+		}
+		settings.EmailMode = rc.Mode
+		rc.Settings = *settings
+		list = append(list, rc)
+	}
+	return list, rows.Err()
+}
+
+// settingsColumns membaca preferensi p (LEFT JOIN user_notification_settings); NULL
+// berarti user belum menyimpan preferensi.
+const settingsColumns = `p.user_id IS NOT NULL, p.camera_ids, p.site_ids, p.min_confidence, p.anomaly_types, p.channels, p.quiet_hours`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSettings(row rowScanner, extra ...any) (*domain.NotificationSettings, error) {
+	var found bool
+	var cameraIDs, siteIDs pqx.Int64Array
+	var types, channels pqx.StringArray
+	var minConf sql.NullFloat64
+	var quiet []byte
+	dest := append(extra, &found, &cameraIDs, &siteIDs, &minConf, &types, &channels, &quiet)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	s := domain.DefaultNotificationSettings()
+	if !found {
+		return &s, nil
+	}
+	s.CameraIDs = []int64(cameraIDs)
+	s.SiteIDs = []int64(siteIDs)
+	s.AnomalyTypes = []string(types)
+	s.Channels = []string(channels)
+	if minConf.Valid {
+		s.MinConfidence = &minConf.Float64
+	}
+	if err := json.Unmarshal(quiet, &s.QuietHours); err != nil {
+		return nil, err
+	}
+	if s.QuietHours == nil {
+		s.QuietHours = []domain.ScheduleWindow{}
+	}
+	return &s, nil
+}
+
+func (r *repository) GetNotificationSettings(ctx context.Context, userID int64) (*domain.NotificationSettings, error) {
+	var mode string
+	s, err := scanSettings(r.db.QueryRowContext(ctx, `
+		SELECT u.email_alerts, `+settingsColumns+`
+		FROM users u LEFT JOIN user_notification_settings p ON p.user_id = u.id
+		WHERE u.id = $1`, userID), &mode)
+	if err != nil {
+		return nil, err
+	}
+	s.EmailMode = mode
+	return s, nil
+}
+
+func (r *repository) SaveNotificationSettings(ctx context.Context, userID int64, s *domain.NotificationSettings) error {
+	quiet, err := json.Marshal(s.QuietHours)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_notification_settings (user_id, camera_ids, site_ids, min_confidence, anomaly_types, channels, quiet_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			camera_ids = EXCLUDED.camera_ids, site_ids = EXCLUDED.site_ids, min_confidence = EXCLUDED.min_confidence,
+			anomaly_types = EXCLUDED.anomaly_types, channels = EXCLUDED.channels, quiet_hours = EXCLUDED.quiet_hours,
+			updated_at = NOW()`,
+		userID, pqx.Array(s.CameraIDs), pqx.Array(s.SiteIDs), s.MinConfidence,
+		pqx.Array(s.AnomalyTypes), pqx.Array(s.Channels), quiet); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET email_alerts = $2 WHERE id = $1`, userID, s.EmailMode); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) GetPushRecipients(ctx context.Context, companyID, cameraID int64) ([]domain.PushRecipient, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, d.token, d.provider, u.locale, `+settingsColumns+`
+		FROM user_devices d JOIN users u ON u.id = d.user_id
+		LEFT JOIN user_notification_settings p ON p.user_id = u.id
+		WHERE u.company_id = $1
+		  AND `+alertAudience, companyID, cameraID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []domain.PushRecipient
+	for rows.Next() {
+		var rc domain.PushRecipient
+		settings, err := scanSettings(rows, &rc.UserID, &rc.Token, &rc.Provider, &rc.Locale)
+		if err != nil {
+			return nil, err
+		}
+		rc.Settings = *settings
+		list = append(list, rc)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) GetCameraSiteID(ctx context.Context, cameraID int64) (*int64, error) {
+	var siteID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `SELECT site_id FROM cameras WHERE id = $1`, cameraID).Scan(&siteID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if !siteID.Valid {
+		return nil, nil
+	}
+	return &siteID.Int64, nil
+}
+
+// ErrDeviceNotFound: perangkat tidak ada atau milik user lain.
+var ErrDeviceNotFound = errors.New("perangkat tidak ditemukan")
+
+func (r *repository) UpsertDevice(ctx context.Context, d *domain.UserDevice) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO user_devices (user_id, token, platform, provider, device_name, app_version)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, provider = EXCLUDED.provider,
+			device_name = COALESCE(EXCLUDED.device_name, user_devices.device_name),
+			app_version = COALESCE(EXCLUDED.app_version, user_devices.app_version),
+			last_seen_at = NOW()
+		RETURNING id, created_at, last_seen_at`,
+		d.UserID, d.Token, d.Platform, d.Provider, d.DeviceName, d.AppVersion,
+	).Scan(&d.ID, &d.CreatedAt, &d.LastSeenAt)
+}
+
+func (r *repository) ListDevices(ctx context.Context, userID int64) ([]domain.UserDevice, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, platform, provider, COALESCE(device_name, ''), COALESCE(app_version, ''), created_at, last_seen_at
+		FROM user_devices WHERE user_id = $1 ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []domain.UserDevice{}
+	for rows.Next() {
+		var d domain.UserDevice
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Provider, &d.DeviceName, &d.AppVersion, &d.CreatedAt, &d.LastSeenAt); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) DeleteDevice(ctx context.Context, userID, deviceID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_devices WHERE id = $1 AND user_id = $2`, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteDeviceByToken(ctx context.Context, userID int64, token string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_devices WHERE token = $1 AND user_id = $2`, token, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
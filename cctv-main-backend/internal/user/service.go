@@ -2,59 +2,132 @@ package user
 
 import (
 	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/session"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("kunci-rahasia-yang-sangat-aman-dan-panjang")
+var (
+	ErrInvalidCredentials = errors.New("email atau password salah")
+	ErrInvalidEmailAlerts = errors.New("mode alert email harus off, instant, atau digest")
+	ErrInvalidLocale      = errors.New("bahasa harus id atau en")
+	ErrInvalidDevice      = errors.New("token wajib diisi, platform harus android, ios, atau web, dan provider fcm atau apns (khusus ios)")
+	ErrInvalidSettings    = errors.New("pengaturan notifikasi tidak valid")
+)
 
 type Service interface {
     Register(user *domain.User) error
-    Login(input *domain.User) (string, error)
+    Login(input *domain.User, userAgent string) (*domain.AuthTokens, error)
+    Refresh(refreshToken string) (*domain.AuthTokens, error)
+    Logout(sessionID, userID int64) error
+    LogoutAll(userID int64) error
     FindUsersByCompany(companyID int64) ([]domain.User, error)
     FindAllUsers() ([]domain.User, error)
+    // FindUser mengembalikan user milik companyID; user perusahaan lain dianggap tidak ada.
+    FindUser(userID, companyID int64) (*domain.User, error)
     UpdateRole(userID, companyID int64, role string) error
     Delete(userID, companyID int64) error
+    // SaveFCMToken adalah endpoint lama: token kosong diabaikan.
     SaveFCMToken(userID int64, fcmToken string) error
+    RegisterDevice(ctx context.Context, d *domain.UserDevice) error
+    ListDevices(ctx context.Context, userID int64) ([]domain.UserDevice, error)
+    // UnregisterDevice menghapus perangkat milik user berdasarkan id atau token.
+    UnregisterDevice(ctx context.Context, userID, deviceID int64, token string) error
+    GetNotificationSettings(ctx context.Context, userID int64) (*domain.NotificationSettings, error)
+    SetNotificationSettings(ctx context.Context, userID int64, settings *domain.NotificationSettings) error
+    GetEmailAlerts(ctx context.Context, userID int64) (string, error)
+    SetEmailAlerts(ctx context.Context, userID int64, mode string) error
+    GetLocale(ctx context.Context, userID int64) (string, error)
+    SetLocale(ctx context.Context, userID int64, locale string) error
 }
 
 type service struct {
-	repo Repository
+	repo      Repository
+	sessions  session.Service
+	keys      *auth.KeySet
+	accessTTL time.Duration
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService membuat service user. accessTTL adalah umur access token; sesi
+// panjangnya dijaga oleh refresh token di session.Service.
+func NewService(repo Repository, sessions session.Service, keys *auth.KeySet, accessTTL time.Duration) Service {
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	return &service{repo: repo, sessions: sessions, keys: keys, accessTTL: accessTTL}
 }
 
-func (s *service) Login(input *domain.User) (string, error) {
+func (s *service) Login(input *domain.User, userAgent string) (*domain.AuthTokens, error) {
 	user, err := s.repo.GetUserByEmail(input.Email)
 	if err != nil {
-		return "", errors.New("email atau password salah")
+		return nil, ErrInvalidCredentials
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
 	if err != nil {
-		return "", errors.New("email atau password salah")
+		return nil, ErrInvalidCredentials
+	}
+
+	sessionID, refreshToken, err := s.sessions.Start(context.Background(), user.ID, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, sessionID, refreshToken)
+}
+
+func (s *service) Refresh(refreshToken string) (*domain.AuthTokens, error) {
+	sess, newRefresh, err := s.sessions.Rotate(context.Background(), refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	// Ambil ulang user agar role/company di access token baru selalu terkini.
+	user, err := s.repo.GetUserByID(sess.UserID)
+	if err != nil {
+		return nil, session.ErrInvalidRefreshToken
 	}
+	return s.issueTokens(user, sess.ID, newRefresh)
+}
+
+func (s *service) Logout(sessionID, userID int64) error {
+	return s.sessions.Revoke(context.Background(), sessionID, userID)
+}
+
+func (s *service) LogoutAll(userID int64) error {
+	return s.sessions.RevokeAllForUser(context.Background(), userID)
+}
 
+func (s *service) issueTokens(user *domain.User, sessionID int64, refreshToken string) (*domain.AuthTokens, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id":    user.ID,
 		"email":      user.Email,
 		"company_id": user.CompanyID,
 		"role":       user.Role,
-		"exp":        time.Now().Add(time.Hour * 72).Unix(),
+		"sid":        sessionID,
+		"iat":        now.Unix(),
+		"exp":        now.Add(s.accessTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return tokenString, nil
+	return &domain.AuthTokens{
+		Token:        tokenString,
+		AccessToken:  tokenString,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
 }
 
 func (s *service) Register(user *domain.User) error {
@@ -74,14 +147,176 @@ func (s *service) FindAllUsers() ([]domain.User, error) {
     return s.repo.GetAllUsers()
 }
 
+func (s *service) FindUser(userID, companyID int64) (*domain.User, error) {
+	u, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.CompanyID != companyID {
+		return nil, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+// UpdateRole mengganti peran lalu mencabut semua sesi user tersebut supaya
+// access token dengan peran lama tidak bisa dipakai lagi.
 func (s *service) UpdateRole(userID, companyID int64, role string) error {
-	return s.repo.UpdateUserRole(userID, companyID, role)
+	if err := s.repo.UpdateUserRole(userID, companyID, role); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllForUser(context.Background(), userID)
 }
 
+// Delete menghapus user lalu mencabut sesinya, sehingga authMiddleware langsung
+// menolak access token yang masih beredar. Di database sesi juga terhapus lewat
+// ON DELETE CASCADE; pencabutan eksplisit tidak bergantung pada skema itu.
 func (s *service) Delete(userID, companyID int64) error {
-	return s.repo.DeleteUser(userID, companyID)
+	if err := s.repo.DeleteUser(userID, companyID); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllForUser(context.Background(), userID)
 }
 
 func (s *service) SaveFCMToken(userID int64, fcmToken string) error {
-	return s.repo.UpdateFCMToken(userID, fcmToken)
+	if strings.TrimSpace(fcmToken) == "" {
+		// Klien lama mengirim token kosong saat logout; menghapus semua perangkat
+		// akan mematikan notifikasi di perangkat lain user. Perangkat dicabut lewat
+		// DELETE /api/users/me/devices.
+		return nil
+	}
+	return s.RegisterDevice(context.Background(), &domain.UserDevice{UserID: userID, Token: fcmToken})
+}
+
+func (s *service) RegisterDevice(ctx context.Context, d *domain.UserDevice) error {
+	d.Token = strings.TrimSpace(d.Token)
+	d.Platform = strings.ToLower(strings.TrimSpace(d.Platform))
+	if d.Platform == "" {
+		d.Platform = domain.DevicePlatformUnknown
+	}
+	switch d.Platform {
+	case domain.DevicePlatformAndroid, domain.DevicePlatformIOS, domain.DevicePlatformWeb, domain.DevicePlatformUnknown:
+	default:
+		return ErrInvalidDevice
+	}
+	d.Provider = strings.ToLower(strings.TrimSpace(d.Provider))
+	switch d.Provider {
+	case "":
+		d.Provider = domain.PushProviderFCM
+	case domain.PushProviderFCM:
+	case domain.PushProviderAPNs:
+		// token APNs hanya ada di perangkat Apple
+		if d.Platform != domain.DevicePlatformIOS {
+			return ErrInvalidDevice
+		}
+	default:
+		return ErrInvalidDevice
+	}
+	if d.Token == "" || len(d.Token) > 512 || len(d.DeviceName) > 100 || len(d.AppVersion) > 50 {
+		return ErrInvalidDevice
+	}
+	return s.repo.UpsertDevice(ctx, d)
+}
+
+func (s *service) ListDevices(ctx context.Context, userID int64) ([]domain.UserDevice, error) {
+	return s.repo.ListDevices(ctx, userID)
+}
+
+func (s *service) UnregisterDevice(ctx context.Context, userID, deviceID int64, token string) error {
+	if deviceID > 0 {
+		return s.repo.DeleteDevice(ctx, userID, deviceID)
+	}
+	if token = strings.TrimSpace(token); token == "" {
+		return ErrInvalidDevice
+	}
+	return s.repo.DeleteDeviceByToken(ctx, userID, token)
+}
+
+func (s *service) GetEmailAlerts(ctx context.Context, userID int64) (string, error) {
+	return s.repo.GetEmailAlerts(ctx, userID)
+}
+
+func (s *service) SetEmailAlerts(ctx context.Context, userID int64, mode string) error {
+	switch mode {
+	case domain.EmailAlertsOff, domain.EmailAlertsInstant, domain.EmailAlertsDigest:
+	default:
+		return ErrInvalidEmailAlerts
+	}
+	return s.repo.SetEmailAlerts(ctx, userID, mode)
+}
+
+func (s *service) GetLocale(ctx context.Context, userID int64) (string, error) {
+	return s.repo.GetLocale(ctx, userID)
+}
+
+func (s *service) SetLocale(ctx context.Context, userID int64, locale string) error {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	for _, l := range domain.Locales {
+		if l == locale {
+			return s.repo.SetLocale(ctx, userID, locale)
+		}
+	}
+	return ErrInvalidLocale
+}
+
+func (s *service) GetNotificationSettings(ctx context.Context, userID int64) (*domain.NotificationSettings, error) {
+	return s.repo.GetNotificationSettings(ctx, userID)
+}
+
+func (s *service) SetNotificationSettings(ctx context.Context, userID int64, settings *domain.NotificationSettings) error {
+	if err := validateSettings(settings); err != nil {
+		return err
+	}
+	return s.repo.SaveNotificationSettings(ctx, userID, settings)
+}
+
+func validateSettings(st *domain.NotificationSettings) error {
+	if st.MinConfidence != nil && (*st.MinConfidence < 0 || *st.MinConfidence > 1) {
+		return fmt.Errorf("%w: min_confidence harus 0..1", ErrInvalidSettings)
+	}
+	switch st.EmailMode {
+	case domain.EmailAlertsOff, domain.EmailAlertsInstant, domain.EmailAlertsDigest:
+	default:
+		return ErrInvalidEmailAlerts
+	}
+	channels := []string{}
+	for _, c := range st.Channels {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != domain.UserChannelPush && c != domain.UserChannelEmail {
+			return fmt.Errorf("%w: channel %q (pakai push atau email)", ErrInvalidSettings, c)
+		}
+		channels = append(channels, c)
+	}
+	st.Channels = channels
+	types := []string{}
+	for _, t := range st.AnomalyTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	st.AnomalyTypes = types
+	for i := range st.QuietHours {
+		w := &st.QuietHours[i]
+		if _, ok := domain.ParseClock(w.Start); !ok {
+			return fmt.Errorf("%w: start %q harus HH:MM", ErrInvalidSettings, w.Start)
+		}
+		if _, ok := domain.ParseClock(w.End); !ok {
+			return fmt.Errorf("%w: end %q harus HH:MM", ErrInvalidSettings, w.End)
+		}
+		for j, d := range w.Days {
+			if _, ok := domain.ParseWeekday(d); !ok {
+				return fmt.Errorf("%w: hari %q (pakai mon..sun)", ErrInvalidSettings, d)
+			}
+			w.Days[j] = strings.ToLower(strings.TrimSpace(d))
+		}
+	}
+	if st.CameraIDs == nil {
+		st.CameraIDs = []int64{}
+	}
+	if st.SiteIDs == nil {
+		st.SiteIDs = []int64{}
+	}
+	if st.QuietHours == nil {
+		st.QuietHours = []domain.ScheduleWindow{}
+	}
+	return nil
 }
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/session"
+	"cctv-main-backend/pkg/auth"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memUsers menyimpan user di memori; metode Repository lain tidak dipakai test ini.
+type memUsers struct {
+	Repository
+	users   map[int64]*domain.User
+	devices []domain.UserDevice
+}
+
+func (r *memUsers) UpsertDevice(ctx context.Context, d *domain.UserDevice) error {
+	r.devices = append(r.devices, *d)
+	return nil
+}
+
+func (r *memUsers) GetUserByID(id int64) (*domain.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memUsers) UpdateUserRole(userID, companyID int64, role string) error {
+	u, ok := r.users[userID]
+	if !ok || u.CompanyID != companyID {
+		return sql.ErrNoRows
+	}
+	u.Role = role
+	return nil
+}
+
+func (r *memUsers) DeleteUser(userID, companyID int64) error {
+	u, ok := r.users[userID]
+	if !ok || u.CompanyID != companyID {
+		return sql.ErrNoRows
+	}
+	delete(r.users, userID)
+	return nil
+}
+
+// fakeSessions mencatat pencabutan sesi.
+type fakeSessions struct {
+	session.Service
+	revokedUsers    []int64
+	revokedSessions []int64
+}
+
+func (f *fakeSessions) Revoke(ctx context.Context, sessionID, userID int64) error {
+	f.revokedSessions = append(f.revokedSessions, sessionID)
+	return nil
+}
+
+func (f *fakeSessions) RevokeAllForUser(ctx context.Context, userID int64) error {
+	f.revokedUsers = append(f.revokedUsers, userID)
+	return nil
+}
+
+func newTestService(t *testing.T) (Service, *memUsers, *fakeSessions) {
+	t.Helper()
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet("test", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memUsers{users: map[int64]*domain.User{
+		1: {ID: 1, CompanyID: 10, Email: "admin@example.com", Role: "admin"},
+		2: {ID: 2, CompanyID: 10, Email: "staff@example.com", Role: "user"},
+	}}
+	sessions := &fakeSessions{}
+	return NewService(repo, sessions, keys, time.Minute), repo, sessions
+}
+
+func TestUpdateRoleRevokesSessions(t *testing.T) {
+	s, repo, sessions := newTestService(t)
+	if err := s.UpdateRole(2, 10, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.users[2].Role != "viewer" {
+		t.Fatalf("role = %s, want viewer", repo.users[2].Role)
+	}
+	if len(sessions.revokedUsers) != 1 || sessions.revokedUsers[0] != 2 {
+		t.Fatalf("sesi yang dicabut = %v, want [2]", sessions.revokedUsers)
+	}
+
+	// Gagal mengubah (user perusahaan lain): tidak ada yang dicabut.
+	if err := s.UpdateRole(2, 99, "viewer"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("err = %v, want sql.ErrNoRows", err)
+	}
+	if len(sessions.revokedUsers) != 1 {
+		t.Fatalf("sesi dicabut walau perubahan gagal: %v", sessions.revokedUsers)
+	}
+}
+
+func TestDeleteRevokesSessions(t *testing.T) {
+	s, repo, sessions := newTestService(t)
+	if err := s.Delete(2, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.users[2]; ok {
+		t.Fatal("user tidak terhapus")
+	}
+	if len(sessions.revokedUsers) != 1 || sessions.revokedUsers[0] != 2 {
+		t.Fatalf("sesi yang dicabut = %v, want [2]", sessions.revokedUsers)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	s, _, sessions := newTestService(t)
+	if err := s.Logout(42, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions.revokedSessions) != 1 || sessions.revokedSessions[0] != 42 {
+		t.Fatalf("sesi yang dicabut = %v, want [42]", sessions.revokedSessions)
+	}
+	if len(sessions.revokedUsers) != 0 {
+		t.Fatal("logout mencabut semua sesi user")
+	}
+}
+
+func TestSaveFCMTokenLegacy(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	repo.devices = []domain.UserDevice{{UserID: 2, Token: "ponsel"}, {UserID: 2, Token: "tablet"}}
+
+	// Logout dari aplikasi lama mengirim token kosong: perangkat lain tetap terdaftar.
+	for _, tok := range []string{"", "  "} {
+		if err := s.SaveFCMToken(2, tok); err != nil {
+			t.Fatalf("SaveFCMToken(%q): %v", tok, err)
+		}
+	}
+	if len(repo.devices) != 2 {
+		t.Fatalf("perangkat = %v, want tetap 2", repo.devices)
+	}
+
+	if err := s.SaveFCMToken(2, "laptop"); err != nil {
+		t.Fatal(err)
+	}
+	if d := repo.devices[len(repo.devices)-1]; d.Token != "laptop" || d.Platform != domain.DevicePlatformUnknown || d.Provider != domain.PushProviderFCM {
+		t.Fatalf("perangkat baru = %+v", d)
+	}
+}
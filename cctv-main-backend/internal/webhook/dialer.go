@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes adalah rentang yang tidak boleh dituju webhook selain yang
+// sudah dicakup netip.Addr (loopback, privat, link-local, multicast).
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, termasuk broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64 ke IPv4 (bisa menunjuk ke alamat privat)
+}
+
+// blockedAddr melaporkan apakah ip tidak boleh dihubungi webhook: loopback,
+// jaringan privat (termasuk jaringan docker 172.16.0.0/12 dan host.docker.internal),
+// link-local (termasuk metadata cloud 169.254.169.254), dan rentang khusus lain.
+func blockedAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialControl dipanggil setelah DNS di-resolve dan tepat sebelum koneksi dibuat,
+// sehingga alamat yang diperiksa adalah alamat yang benar-benar dihubungi. DNS
+// rebinding (nama yang resolve ke IP publik saat validasi lalu ke IP internal
+// saat kirim) maupun redirect ke alamat internal ikut tertolak.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("alamat webhook tidak valid: %s", address)
+	}
+	if blockedAddr(ap.Addr()) {
+		return fmt.Errorf("alamat webhook %s diblokir (jaringan internal)", ap.Addr())
+	}
+	return nil
+}
+
+// newClient membuat klien HTTP untuk pengiriman webhook. Bila allowPrivate
+// false, koneksi ke alamat internal ditolak; proxy dari environment tidak
+// dipakai karena alamat tujuan akhirnya tidak bisa diperiksa.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = dialControl
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Dispatcher mengirim pengiriman webhook yang tertunda secara asinkron dengan
+// retry exponential backoff; setelah MaxAttempts gagal, pengiriman ditandai dead.
+// Koneksi ke loopback, jaringan privat, dan link-local ditolak (lihat dialControl)
+// kecuali allowPrivate, yang hanya untuk pengembangan lokal.
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+
+	Workers      int           // pengiriman paralel per putaran
+	PollInterval time.Duration // jeda saat antrean kosong
+	MaxAttempts  int
+	BaseBackoff  time.Duration // jeda sebelum percobaan ke-2; berlipat dua tiap gagal
+	MaxBackoff   time.Duration
+}
+
+func NewDispatcher(repo Repository, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		client:       newClient(10*time.Second, allowPrivate),
+		Workers:      4,
+		PollInterval: 2 * time.Second,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+	}
+}
+
+// Sign menghitung header X-Webhook-Signature: "sha256=" + hex(HMAC-SHA256(secret, "<timestamp>.<body>")).
+// Penerima memverifikasi dengan menghitung ulang nilai yang sama dari header X-Webhook-Timestamp dan body mentah.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run memproses antrean sampai ctx dibatalkan.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		n, err := d.runOnce(ctx)
+		if err != nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+		// Antrean masih penuh: langsung ambil batch berikutnya.
+		if err == nil && n >= d.Workers {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.PollInterval):
+		}
+	}
+}
+
+func (d *Dispatcher) runOnce(ctx context.Context) (int, error) {
+	// Lease lebih panjang dari timeout HTTP agar pengiriman yang masih berjalan tidak diambil ulang.
+	batch, err := d.repo.ClaimDue(ctx, d.Workers, d.client.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, c := range batch {
+		wg.Add(1)
+		go func(c ClaimedDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, c)
+		}(c)
+	}
+	wg.Wait()
+	return len(batch), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, c ClaimedDelivery) {
+	code, err := d.post(ctx, c)
+	if err == nil {
+		if err := d.repo.MarkDelivered(ctx, c.ID, code); err != nil {
+			log.Printf("webhook: mark delivered %d: %v", c.ID, err)
+		}
+		return
+	}
+
+	dead := c.Attempts >= d.MaxAttempts
+	next := time.Now().Add(d.backoff(c.Attempts))
+	if dead {
+		log.Printf("webhook: delivery %d ke webhook %d dead setelah %d percobaan: %v", c.ID, c.WebhookID, c.Attempts, err)
+	}
+	if err := d.repo.MarkFailed(ctx, c.ID, code, err.Error(), next, dead); err != nil {
+		log.Printf("webhook: mark failed %d: %v", c.ID, err)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, c ClaimedDelivery) (int, error) {
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(c.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cctv-webhook/1.0")
+	req.Header.Set("X-Webhook-Event", c.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(c.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(c.Secret, ts, c.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint membalas %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff mengembalikan jeda setelah percobaan ke-attempts: Base, 2×Base, 4×Base, ... dibatasi MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.BaseBackoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait
+}
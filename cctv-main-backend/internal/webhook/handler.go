@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/internal/policy"
+	"cctv-main-backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// companyScope mengambil company_id dari token; pemegang CompanyManage boleh
+// memilih perusahaan lain lewat ?company_id=.
+func companyScope(r *http.Request) int64 {
+	claims, _ := r.Context().Value(auth.UserClaimsKey).(jwt.MapClaims)
+	companyID, _ := claims["company_id"].(float64)
+	if policy.Has(r.Context(), policy.CompanyManage) {
+		if v := r.URL.Query().Get("company_id"); v != "" {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return id
+			}
+		}
+	}
+	return int64(companyID)
+}
+
+// pathID mengambil segmen ke-i dari /api/webhooks/{id}/deliveries/{deliveryID}/redeliver.
+func pathID(path string, i int) int64 {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) <= i {
+		return 0
+	}
+	id, _ := strconv.ParseInt(parts[i], 10, 64)
+	return id
+}
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+func (req webhookRequest) toWebhook() *domain.Webhook {
+	wh := &domain.Webhook{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      true,
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	return wh
+}
+
+// GET /api/webhooks
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.service.List(r.Context(), companyScope(r))
+	if err != nil {
+		http.Error(w, "Gagal mengambil data webhook", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// GET /api/webhooks/{id}
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	wh, err := h.service.Get(r.Context(), pathID(r.URL.Path, 2), companyScope(r))
+	if err != nil {
+		writeWebhookError(w, err, "Gagal mengambil data webhook")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wh)
+}
+
+// POST /api/webhooks  body: {"url": "https://...", "events": ["anomaly.created"], "secret": "(opsional)"}
+// Respons memuat secret; simpan karena tidak ditampilkan lagi.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	wh := req.toWebhook()
+	wh.CompanyID = companyScope(r)
+
+	created, err := h.service.Create(r.Context(), wh)
+	if err != nil {
+		writeWebhookError(w, err, "Gagal membuat webhook")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// PUT /api/webhooks/{id}  body sama dengan POST; secret kosong = tidak diganti.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Request body tidak valid", http.StatusBadRequest)
+		return
+	}
+	wh := req.toWebhook()
+	wh.ID = pathID(r.URL.Path, 2)
+	wh.CompanyID = companyScope(r)
+
+	if err := h.service.Update(r.Context(), wh); err != nil {
+		writeWebhookError(w, err, "Gagal memperbarui webhook")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Webhook berhasil diperbarui."))
+}
+
+// DELETE /api/webhooks/{id}
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), pathID(r.URL.Path, 2), companyScope(r)); err != nil {
+		writeWebhookError(w, err, "Gagal menghapus webhook")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Webhook berhasil dihapus."))
+}
+
+// GET /api/webhooks/{id}/deliveries?status=dead&limit=50
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	list, err := h.service.ListDeliveries(r.Context(), pathID(r.URL.Path, 2), companyScope(r), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		writeWebhookError(w, err, "Gagal mengambil log pengiriman webhook")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// POST /api/webhooks/{id}/deliveries/{deliveryID}/redeliver
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Redeliver(r.Context(), pathID(r.URL.Path, 4), pathID(r.URL.Path, 2), companyScope(r))
+	if err != nil {
+		writeWebhookError(w, err, "Gagal mengantrekan ulang pengiriman")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("Pengiriman diantrekan ulang."))
+}
+
+func writeWebhookError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrDeliveryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidEvent), errors.Is(err, ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
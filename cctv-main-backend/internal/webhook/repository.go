@@ -0,0 +1,290 @@
+package webhook
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	pqx "github.com/lib/pq"
+)
+
+var (
+	ErrNotFound         = errors.New("webhook tidak ditemukan")
+	ErrDeliveryNotFound = errors.New("pengiriman webhook tidak ditemukan")
+)
+
+// ClaimedDelivery adalah pengiriman yang sedang dipegang dispatcher beserta tujuan dan kuncinya.
+type ClaimedDelivery struct {
+	domain.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+type Repository interface {
+	List(ctx context.Context, companyID int64) ([]domain.Webhook, error)
+	Get(ctx context.Context, id, companyID int64) (*domain.Webhook, error)
+	Create(ctx context.Context, wh *domain.Webhook) (int64, error)
+	Update(ctx context.Context, wh *domain.Webhook) error
+	Delete(ctx context.Context, id, companyID int64) error
+
+	// Enqueue membuat satu pengiriman untuk setiap webhook aktif perusahaan yang
+	// berlangganan event; mengembalikan jumlah pengiriman yang dibuat.
+	Enqueue(ctx context.Context, companyID int64, event string, payload []byte) (int, error)
+	// EnqueueTx sama dengan Enqueue tetapi di dalam transaksi pemanggil, supaya
+	// pengiriman hanya ada bila perubahan yang memicunya ikut di-commit.
+	EnqueueTx(ctx context.Context, tx *sql.Tx, companyID int64, event string, payload []byte) (int, error)
+	ListDeliveries(ctx context.Context, webhookID, companyID int64, status string, limit int) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID, webhookID, companyID int64) error
+
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, errMsg string, next time.Time, dead bool) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+const webhookColumns = `id, company_id, url, events, COALESCE(description, ''), active, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(...any) error }, wh *domain.Webhook) error {
+	var evs pqx.StringArray
+	if err := row.Scan(&wh.ID, &wh.CompanyID, &wh.URL, &evs, &wh.Description, &wh.Active, &wh.CreatedAt, &wh.UpdatedAt); err != nil {
+		return err
+	}
+	wh.Events = []string(evs)
+	if wh.Events == nil {
+		wh.Events = []string{}
+	}
+	return nil
+}
+
+func (r *repository) List(ctx context.Context, companyID int64) ([]domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE company_id = $1 ORDER BY id ASC`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []domain.Webhook{}
+	for rows.Next() {
+		var wh domain.Webhook
+		if err := scanWebhook(rows, &wh); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, wh)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, id, companyID int64) (*domain.Webhook, error) {
+	var wh domain.Webhook
+	err := scanWebhook(r.db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND company_id = $2`, id, companyID), &wh)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wh, nil
+}
+
+func (r *repository) Create(ctx context.Context, wh *domain.Webhook) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (company_id, url, secret, events, description, active)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6) RETURNING id`,
+		wh.CompanyID, wh.URL, wh.Secret, pqx.Array(wh.Events), wh.Description, wh.Active,
+	).Scan(&id)
+	return id, err
+}
+
+// Update mengganti url, events, deskripsi, dan status aktif; secret hanya diganti bila diisi.
+// Mengaktifkan kembali webhook mengantrekan ulang pengiriman yang di-park selama nonaktif.
+func (r *repository) Update(ctx context.Context, wh *domain.Webhook) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE webhooks
+		SET url = $1, events = $2, description = NULLIF($3, ''), active = $4,
+		    secret = COALESCE(NULLIF($5, ''), secret), updated_at = NOW()
+		WHERE id = $6 AND company_id = $7`,
+		wh.URL, pqx.Array(wh.Events), wh.Description, wh.Active, wh.Secret, wh.ID, wh.CompanyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if wh.Active {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = 'pending', next_attempt_at = NOW()
+			WHERE webhook_id = $1 AND status = 'parked'`, wh.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *repository) Delete(ctx context.Context, id, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND company_id = $2`, id, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const enqueueQuery = `
+	INSERT INTO webhook_deliveries (webhook_id, event, payload)
+	SELECT id, $2, $3 FROM webhooks
+	WHERE company_id = $1 AND active AND (cardinality(events) = 0 OR $2 = ANY(events))`
+
+func (r *repository) Enqueue(ctx context.Context, companyID int64, event string, payload []byte) (int, error) {
+	return countRows(r.db.ExecContext(ctx, enqueueQuery, companyID, event, payload))
+}
+
+func (r *repository) EnqueueTx(ctx context.Context, tx *sql.Tx, companyID int64, event string, payload []byte) (int, error) {
+	return countRows(tx.ExecContext(ctx, enqueueQuery, companyID, event, payload))
+}
+
+func countRows(result sql.Result, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func (r *repository) ListDeliveries(ctx context.Context, webhookID, companyID int64, status string, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := r.Get(ctx, webhookID, companyID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+		       last_status_code, COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT $3`, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var code sql.NullInt64
+		var deliveredAt sql.NullTime
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&code, &d.LastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		if code.Valid {
+			c := int(code.Int64)
+			d.LastStatusCode = &c
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// Redeliver mengantrekan ulang pengiriman (termasuk yang sudah dead) dengan jatah percobaan baru.
+func (r *repository) Redeliver(ctx context.Context, deliveryID, webhookID, companyID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		FROM webhooks w
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.id = d.webhook_id AND w.company_id = $3`,
+		deliveryID, webhookID, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// ClaimDue mengambil pengiriman yang jatuh tempo dan menggeser next_attempt_at sejauh
+// lease, sehingga replika lain tidak mengambilnya dan pengiriman yang tertinggal karena
+// proses mati otomatis dicoba lagi setelah lease habis. Pengiriman milik webhook yang
+// nonaktif di-park lebih dulu agar tidak terkirim dan tidak menghalangi antrean.
+func (r *repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries d SET status = 'parked'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND NOT w.active
+		  AND d.status = 'pending' AND d.next_attempt_at <= NOW()`); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND w.active AND d.id IN (
+			SELECT dd.id FROM webhook_deliveries dd
+			JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE dd.status = 'pending' AND dd.next_attempt_at <= NOW() AND ww.active
+			ORDER BY dd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ClaimedDelivery
+	for rows.Next() {
+		var c ClaimedDelivery
+		var payload []byte
+		if err := rows.Scan(&c.ID, &c.WebhookID, &c.Event, &payload, &c.Attempts, &c.URL, &c.Secret); err != nil {
+			return nil, err
+		}
+		c.Payload = payload
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (r *repository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1`, id, statusCode)
+	return err
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id int64, statusCode int, errMsg string, next time.Time, dead bool) error {
+	status := domain.WebhookDeliveryPending
+	if dead {
+		status = domain.WebhookDeliveryDead
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, last_status_code = NULLIF($3, 0), last_error = $4, next_attempt_at = $5
+		WHERE id = $1`, id, status, statusCode, errMsg, next)
+	return err
+}
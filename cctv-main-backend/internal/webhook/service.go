@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/events"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidURL   = errors.New("url webhook harus http(s) absolut")
+	ErrInvalidEvent = errors.New("tipe event webhook tidak dikenal")
+	ErrInvalidQuery = errors.New("parameter query tidak valid")
+)
+
+// EventTypes adalah event yang bisa dilanggan webhook.
+var EventTypes = []string{
+	events.AnomalyCreated,
+	events.AnomalyStatusChanged,
+	events.CameraOnline,
+	events.CameraOffline,
+}
+
+const (
+	defaultDeliveryPage = 50
+	maxDeliveryPage     = 200
+)
+
+// Envelope adalah isi body yang dikirim ke endpoint webhook.
+type Envelope struct {
+	Event     string    `json:"event"`
+	CompanyID int64     `json:"company_id"`
+	CameraID  int64     `json:"camera_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data"`
+}
+
+type Service interface {
+	List(ctx context.Context, companyID int64) ([]domain.Webhook, error)
+	Get(ctx context.Context, id, companyID int64) (*domain.Webhook, error)
+	// Create mengembalikan webhook lengkap dengan secret (dibangkitkan bila kosong).
+	Create(ctx context.Context, wh *domain.Webhook) (*domain.Webhook, error)
+	Update(ctx context.Context, wh *domain.Webhook) error
+	Delete(ctx context.Context, id, companyID int64) error
+
+	ListDeliveries(ctx context.Context, webhookID, companyID int64, status string, limit int) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID, webhookID, companyID int64) error
+
+	// Enqueue mengantrekan event untuk semua webhook perusahaan yang berlangganan.
+	// Dipanggil langsung oleh sumber event (notifier, service anomali, monitor
+	// kamera), bukan lewat hub, supaya event tidak hilang saat hub penuh.
+	Enqueue(ctx context.Context, companyID, cameraID int64, event string, data any) (int, error)
+	// EnqueueTx mengantrekan event di dalam transaksi yang mengubah datanya.
+	EnqueueTx(ctx context.Context, tx *sql.Tx, companyID, cameraID int64, event string, data any) (int, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) List(ctx context.Context, companyID int64) ([]domain.Webhook, error) {
+	return s.repo.List(ctx, companyID)
+}
+
+func (s *service) Get(ctx context.Context, id, companyID int64) (*domain.Webhook, error) {
+	return s.repo.Get(ctx, id, companyID)
+}
+
+func (s *service) Create(ctx context.Context, wh *domain.Webhook) (*domain.Webhook, error) {
+	if err := validate(wh); err != nil {
+		return nil, err
+	}
+	if wh.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		wh.Secret = secret
+	}
+	id, err := s.repo.Create(ctx, wh)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.repo.Get(ctx, id, wh.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	created.Secret = wh.Secret
+	return created, nil
+}
+
+func (s *service) Update(ctx context.Context, wh *domain.Webhook) error {
+	if err := validate(wh); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, wh)
+}
+
+func (s *service) Delete(ctx context.Context, id, companyID int64) error {
+	return s.repo.Delete(ctx, id, companyID)
+}
+
+func (s *service) ListDeliveries(ctx context.Context, webhookID, companyID int64, status string, limit int) ([]domain.WebhookDelivery, error) {
+	switch status {
+	case "", domain.WebhookDeliveryPending, domain.WebhookDeliveryDelivered, domain.WebhookDeliveryDead, domain.WebhookDeliveryParked:
+	default:
+		return nil, fmt.Errorf("%w: status %s", ErrInvalidQuery, status)
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryPage
+	}
+	if limit > maxDeliveryPage {
+		limit = maxDeliveryPage
+	}
+	return s.repo.ListDeliveries(ctx, webhookID, companyID, status, limit)
+}
+
+func (s *service) Redeliver(ctx context.Context, deliveryID, webhookID, companyID int64) error {
+	return s.repo.Redeliver(ctx, deliveryID, webhookID, companyID)
+}
+
+func (s *service) Enqueue(ctx context.Context, companyID, cameraID int64, event string, data any) (int, error) {
+	payload, err := envelope(companyID, cameraID, event, data)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.Enqueue(ctx, companyID, event, payload)
+}
+
+func (s *service) EnqueueTx(ctx context.Context, tx *sql.Tx, companyID, cameraID int64, event string, data any) (int, error) {
+	payload, err := envelope(companyID, cameraID, event, data)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.EnqueueTx(ctx, tx, companyID, event, payload)
+}
+
+func envelope(companyID, cameraID int64, event string, data any) ([]byte, error) {
+	return json.Marshal(Envelope{
+		Event:     event,
+		CompanyID: companyID,
+		CameraID:  cameraID,
+		Time:      time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+func validate(wh *domain.Webhook) error {
+	wh.URL = strings.TrimSpace(wh.URL)
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if wh.Events == nil {
+		wh.Events = []string{}
+	}
+	for _, ev := range wh.Events {
+		if !isEventType(ev) {
+			return fmt.Errorf("%w: %s", ErrInvalidEvent, ev)
+		}
+	}
+	return nil
+}
+
+func isEventType(ev string) bool {
+	for _, t := range EventTypes {
+		if t == ev {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key adalah satu kunci JWT yang dikenali lewat header "kid".
+// Kunci tanpa signKey hanya dipakai untuk verifikasi (mis. kunci lama saat rotasi).
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// KeySet menandatangani token dengan kunci aktif dan memverifikasi token dengan
+// semua kunci yang masih terdaftar, sehingga rotasi tidak memaksa semua user logout.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeySet(activeKID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*Key{}}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("kunci JWT tanpa kid")
+		}
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("kid JWT duplikat: %s", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+	active, ok := ks.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("kid aktif %q tidak ditemukan", activeKID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("kid aktif %q tidak punya kunci privat/secret untuk menandatangani", activeKID)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+// NewHMACKey membuat kunci HS256 dari secret.
+func NewHMACKey(kid string, secret []byte) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("secret JWT %q terlalu pendek (minimal 32 byte)", kid)
+	}
+	return &Key{ID: kid, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// NewAsymmetricKey membuat kunci RS256 atau EdDSA dari PEM. privatePEM boleh kosong
+// untuk kunci verify-only; publicPEM boleh kosong bila privatePEM diisi.
+func NewAsymmetricKey(kid, alg string, privatePEM, publicPEM []byte) (*Key, error) {
+	k := &Key{ID: kid}
+	switch alg {
+	case "RS256":
+		k.Method = jwt.SigningMethodRS256
+		if len(privatePEM) > 0 {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("kunci privat RS256 %q: %w", kid, err)
+			}
+			k.signKey, k.verifyKey = priv, &priv.PublicKey
+		}
+		if len(publicPEM) > 0 {
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("kunci publik RS256 %q: %w", kid, err)
+			}
+			k.verifyKey = pub
+		}
+	case "EdDSA":
+		k.Method = jwt.SigningMethodEdDSA
+		if len(privatePEM) > 0 {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("kunci privat EdDSA %q: %w", kid, err)
+			}
+			k.signKey, k.verifyKey = priv, priv.(crypto.Signer).Public()
+		}
+		if len(publicPEM) > 0 {
+			pub, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("kunci publik EdDSA %q: %w", kid, err)
+			}
+			k.verifyKey = pub
+		}
+	default:
+		return nil, fmt.Errorf("algoritma JWT %q tidak didukung (HS256, RS256, EdDSA)", alg)
+	}
+	if k.verifyKey == nil {
+		return nil, fmt.Errorf("kunci %q butuh private_key atau public_key", kid)
+	}
+	return k, nil
+}
+
+// Sign menandatangani claims dengan kunci aktif dan mengisi header "kid".
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signKey)
+}
+
+// Parse memverifikasi token memakai kunci sesuai "kid" dan mengembalikan claims-nya.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("kid tidak dikenal: %q", kid)
+		}
+		// Cegah alg-confusion: algoritma token harus sama dengan algoritma kunci.
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("algoritma %s tidak cocok untuk kid %q", token.Method.Alg(), kid)
+		}
+		return k.verifyKey, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("token tidak valid")
+	}
+	return claims, nil
+}
+
+// JWKS mengembalikan kunci publik (RS256/EdDSA) dalam format JSON Web Key Set agar
+// service lain bisa memverifikasi token tanpa memegang secret. Kunci HMAC tidak pernah diekspos.
+func (ks *KeySet) JWKS() map[string]any {
+	keys := []map[string]string{}
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "use": "sig", "alg": "RS256", "kid": k.ID,
+				"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP", "use": "sig", "alg": "EdDSA", "crv": "Ed25519", "kid": k.ID,
+				"x": base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]any{"keys": keys}
+}
+
+// keyFile adalah format file JWT_KEYS_FILE:
+//
+//	{"active": "2025-10", "keys": [
+//	  {"kid": "2025-10", "alg": "EdDSA", "private_key_file": "/run/secrets/jwt-ed25519.pem"},
+//	  {"kid": "2025-04", "alg": "HS256", "secret": "..."}
+//	]}
+type keyFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		KID            string `json:"kid"`
+		Alg            string `json:"alg"`
+		Secret         string `json:"secret"`
+		PrivateKey     string `json:"private_key"`
+		PrivateKeyFile string `json:"private_key_file"`
+		PublicKey      string `json:"public_key"`
+		PublicKeyFile  string `json:"public_key_file"`
+	} `json:"keys"`
+}
+
+// LoadKeySet membaca kunci JWT dari JWT_KEYS_FILE, atau dari environment:
+//
+//	JWT_SECRET            secret HS256 aktif (minimal 32 byte)
+//	JWT_KID               kid untuk JWT_SECRET (default "default")
+//	JWT_PREVIOUS_SECRETS  kunci lama verify-only, format "kid1:secret1,kid2:secret2"
+func LoadKeySet() (*KeySet, error) {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		return loadKeyFile(path)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("kunci JWT belum dikonfigurasi: set JWT_SECRET atau JWT_KEYS_FILE")
+	}
+	kid := os.Getenv("JWT_KID")
+	if kid == "" {
+		kid = "default"
+	}
+	active, err := NewHMACKey(kid, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	keys := []*Key{active}
+	if prev := os.Getenv("JWT_PREVIOUS_SECRETS"); prev != "" {
+		for _, item := range strings.Split(prev, ",") {
+			pkid, psecret, ok := strings.Cut(strings.TrimSpace(item), ":")
+			if !ok || pkid == "" {
+				return nil, fmt.Errorf("JWT_PREVIOUS_SECRETS tidak valid: %q", item)
+			}
+			k, err := NewHMACKey(pkid, []byte(psecret))
+			if err != nil {
+				return nil, err
+			}
+			k.signKey = nil // kunci lama hanya untuk verifikasi
+			keys = append(keys, k)
+		}
+	}
+	return NewKeySet(kid, keys...)
+}
+
+func loadKeyFile(path string) (*KeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("baca JWT_KEYS_FILE: %w", err)
+	}
+	var f keyFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse JWT_KEYS_FILE: %w", err)
+	}
+	keys := make([]*Key, 0, len(f.Keys))
+	for _, e := range f.Keys {
+		if e.Alg == "" || e.Alg == "HS256" {
+			k, err := NewHMACKey(e.KID, []byte(e.Secret))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+			continue
+		}
+		priv, err := pemFromValueOrFile(e.PrivateKey, e.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := pemFromValueOrFile(e.PublicKey, e.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k, err := NewAsymmetricKey(e.KID, e.Alg, priv, pub)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return NewKeySet(f.Active, keys...)
+}
+
+func pemFromValueOrFile(value, path string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tickets menerbitkan tiket sekali pakai berumur pendek untuk endpoint streaming.
+// EventSource di browser tidak bisa mengirim header Authorization, jadi klien
+// menukar JWT-nya dengan tiket lalu membuka stream dengan ?ticket=. Dengan begitu
+// JWT tidak pernah muncul di URL (log proxy, riwayat browser).
+//
+// Tiket disimpan di memori proses, sama seperti hub event yang dilayaninya.
+type Tickets struct {
+	TTL time.Duration
+
+	mu      sync.Mutex
+	tickets map[string]ticket
+}
+
+type ticket struct {
+	claims    jwt.MapClaims
+	expiresAt time.Time
+}
+
+func NewTickets(ttl time.Duration) *Tickets {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Tickets{TTL: ttl, tickets: map[string]ticket{}}
+}
+
+// Issue menyimpan claims pemanggil dan mengembalikan tiket mentah.
+func (t *Tickets) Issue(claims jwt.MapClaims) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Buang tiket kedaluwarsa yang tidak pernah ditukar.
+	for k, v := range t.tickets {
+		if now.After(v.expiresAt) {
+			delete(t.tickets, k)
+		}
+	}
+	t.tickets[id] = ticket{claims: claims, expiresAt: now.Add(t.TTL)}
+	return id, nil
+}
+
+// Redeem mengembalikan claims milik tiket dan menghapusnya, sehingga tiket
+// hanya bisa dipakai sekali. ok false bila tiket tidak dikenal atau kedaluwarsa.
+func (t *Tickets) Redeem(id string) (jwt.MapClaims, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tk, ok := t.tickets[id]
+	if !ok {
+		return nil, false
+	}
+	delete(t.tickets, id)
+	if time.Now().After(tk.expiresAt) {
+		return nil, false
+	}
+	return tk.claims, true
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config mengatur koneksi dan pool database.
+type Config struct {
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Retry saat startup: ConnectAttempts kali, jeda awal ConnectBackoff lalu
+	// digandakan tiap percobaan sampai maksimal MaxConnectBackoff.
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	MaxConnectBackoff time.Duration
+}
+
+// DefaultConfig berisi nilai bawaan pool; DSN sengaja dikosongkan.
+func DefaultConfig() Config {
+	return Config{
+		MaxOpenConns:      25,
+		MaxIdleConns:      5,
+		ConnMaxLifetime:   30 * time.Minute,
+		ConnMaxIdleTime:   5 * time.Minute,
+		ConnectAttempts:   10,
+		ConnectBackoff:    time.Second,
+		MaxConnectBackoff: 30 * time.Second,
+	}
+}
+
+// fileConfig adalah format file JSON yang ditunjuk DB_CONFIG_FILE.
+// Durasi ditulis sebagai string time.ParseDuration, mis. "30m".
+type fileConfig struct {
+	DSN               string `json:"dsn"`
+	MaxOpenConns      *int   `json:"max_open_conns"`
+	MaxIdleConns      *int   `json:"max_idle_conns"`
+	ConnMaxLifetime   string `json:"conn_max_lifetime"`
+	ConnMaxIdleTime   string `json:"conn_max_idle_time"`
+	ConnectAttempts   *int   `json:"connect_attempts"`
+	ConnectBackoff    string `json:"connect_backoff"`
+	MaxConnectBackoff string `json:"max_connect_backoff"`
+}
+
+// LoadConfig membangun Config dari nilai bawaan, lalu file DB_CONFIG_FILE (opsional),
+// lalu environment variable. Environment selalu menang atas file.
+//
+//	POSTGRES_DSN (atau DATABASE_URL), DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS,
+//	DB_CONN_MAX_LIFETIME, DB_CONN_MAX_IDLE_TIME, DB_CONNECT_ATTEMPTS,
+//	DB_CONNECT_BACKOFF, DB_MAX_CONNECT_BACKOFF
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if path := os.Getenv("DB_CONFIG_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("baca DB_CONFIG_FILE: %w", err)
+		}
+		var fc fileConfig
+		if err := json.Unmarshal(b, &fc); err != nil {
+			return cfg, fmt.Errorf("parse DB_CONFIG_FILE: %w", err)
+		}
+		if err := fc.apply(&cfg); err != nil {
+			return cfg, fmt.Errorf("DB_CONFIG_FILE: %w", err)
+		}
+	}
+
+	if v := os.Getenv("POSTGRES_DSN"); v != "" {
+		cfg.DSN = v
+	} else if v := os.Getenv("DATABASE_URL"); v != "" {
+		cfg.DSN = v
+	}
+	ints := []struct {
+		key string
+		dst *int
+	}{
+		{"DB_MAX_OPEN_CONNS", &cfg.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &cfg.MaxIdleConns},
+		{"DB_CONNECT_ATTEMPTS", &cfg.ConnectAttempts},
+	}
+	for _, it := range ints {
+		if v := os.Getenv(it.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s tidak valid: %q", it.key, v)
+			}
+			*it.dst = n
+		}
+	}
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", &cfg.ConnMaxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", &cfg.ConnMaxIdleTime},
+		{"DB_CONNECT_BACKOFF", &cfg.ConnectBackoff},
+		{"DB_MAX_CONNECT_BACKOFF", &cfg.MaxConnectBackoff},
+	}
+	for _, it := range durations {
+		if v := os.Getenv(it.key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s tidak valid: %q", it.key, v)
+			}
+			*it.dst = d
+		}
+	}
+	return cfg, nil
+}
+
+func (fc fileConfig) apply(cfg *Config) error {
+	if fc.DSN != "" {
+		cfg.DSN = fc.DSN
+	}
+	if fc.MaxOpenConns != nil {
+		cfg.MaxOpenConns = *fc.MaxOpenConns
+	}
+	if fc.MaxIdleConns != nil {
+		cfg.MaxIdleConns = *fc.MaxIdleConns
+	}
+	if fc.ConnectAttempts != nil {
+		cfg.ConnectAttempts = *fc.ConnectAttempts
+	}
+	durations := []struct {
+		name string
+		val  string
+		dst  *time.Duration
+	}{
+		{"conn_max_lifetime", fc.ConnMaxLifetime, &cfg.ConnMaxLifetime},
+		{"conn_max_idle_time", fc.ConnMaxIdleTime, &cfg.ConnMaxIdleTime},
+		{"connect_backoff", fc.ConnectBackoff, &cfg.ConnectBackoff},
+		{"max_connect_backoff", fc.MaxConnectBackoff, &cfg.MaxConnectBackoff},
+	}
+	for _, it := range durations {
+		if it.val == "" {
+			continue
+		}
+		d, err := time.ParseDuration(it.val)
+		if err != nil {
+			return fmt.Errorf("%s tidak valid: %q", it.name, it.val)
+		}
+		*it.dst = d
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS recordings;
+DROP TABLE IF EXISTS anomaly_reports;
+DROP TABLE IF EXISTS cameras;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS companies;
@@ -0,0 +1,54 @@
+-- Skema awal. Memakai IF NOT EXISTS agar database lama yang dibuat oleh
+-- database.Migrate versi sebelumnya bisa langsung diadopsi tanpa error.
+CREATE TABLE IF NOT EXISTS companies (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    company_id INTEGER REFERENCES companies(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL DEFAULT 'user', -- 'user' | 'company_admin' | 'superadmin'
+    fcm_token VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS cameras (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    location VARCHAR(255),
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    stream_key TEXT,
+    rtsp_source TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+ALTER TABLE cameras ADD COLUMN IF NOT EXISTS stream_key TEXT;
+ALTER TABLE cameras ADD COLUMN IF NOT EXISTS rtsp_source TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS cameras_stream_key_uniq ON cameras (stream_key);
+
+CREATE TABLE IF NOT EXISTS anomaly_reports (
+    id SERIAL PRIMARY KEY,
+    camera_id INTEGER NOT NULL REFERENCES cameras(id) ON DELETE CASCADE,
+    anomaly_type VARCHAR(50) NOT NULL,
+    confidence FLOAT NOT NULL,
+    video_clip_url TEXT,
+    reported_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS recordings (
+    id          bigserial PRIMARY KEY,
+    camera_id   text NOT NULL,
+    started_at  timestamptz NOT NULL,
+    ended_at    timestamptz NOT NULL,
+    s3_key      text NOT NULL,
+    size_bytes  bigint,
+    created_at  timestamptz DEFAULT now()
+);
+-- unik per segmen per kamera
+CREATE UNIQUE INDEX IF NOT EXISTS recordings_cam_start_uniq ON recordings (camera_id, started_at);
+-- indeks untuk range query
+CREATE INDEX IF NOT EXISTS recordings_cam_time_idx ON recordings (camera_id, started_at);
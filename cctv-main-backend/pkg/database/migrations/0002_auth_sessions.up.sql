@@ -0,0 +1,24 @@
+-- Sesi login: satu baris per login/perangkat. Access token membawa claim "sid"
+-- yang dicek authMiddleware, sehingga sesi bisa dicabut sebelum token kedaluwarsa.
+CREATE TABLE auth_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX auth_sessions_user_idx ON auth_sessions (user_id);
+
+-- Refresh token hanya disimpan sebagai hash SHA-256. Setiap refresh menandai
+-- token lama sebagai used_at dan menerbitkan token baru (rotasi).
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX refresh_tokens_session_idx ON refresh_tokens (session_id);
@@ -0,0 +1,11 @@
+-- Peran kustom per perusahaan (mis. "security guard"). users.role menyimpan nama
+-- peran bawaan ('user', 'company_admin', 'superadmin') atau nama dari tabel ini.
+CREATE TABLE company_roles (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    description TEXT,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, name)
+);
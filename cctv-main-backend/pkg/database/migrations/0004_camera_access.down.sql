@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS user_accessible_cameras;
+DROP TABLE IF EXISTS user_camera_grants;
+DROP TABLE IF EXISTS camera_group_members;
+DROP TABLE IF EXISTS camera_groups;
+ALTER TABLE users DROP COLUMN IF EXISTS camera_access_restricted;
@@ -0,0 +1,50 @@
+-- Pembatasan kamera per user. User dengan camera_access_restricted = FALSE (default)
+-- tetap melihat semua kamera perusahaannya; bila TRUE hanya kamera yang diberikan
+-- langsung atau lewat grup kamera.
+ALTER TABLE users ADD COLUMN camera_access_restricted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE camera_groups (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, name)
+);
+
+CREATE TABLE camera_group_members (
+    group_id INTEGER NOT NULL REFERENCES camera_groups(id) ON DELETE CASCADE,
+    camera_id INTEGER NOT NULL REFERENCES cameras(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, camera_id)
+);
+CREATE INDEX camera_group_members_camera_idx ON camera_group_members (camera_id);
+
+CREATE TABLE user_camera_grants (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    camera_id INTEGER REFERENCES cameras(id) ON DELETE CASCADE,
+    group_id INTEGER REFERENCES camera_groups(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((camera_id IS NULL) <> (group_id IS NULL))
+);
+CREATE UNIQUE INDEX user_camera_grants_camera_uniq ON user_camera_grants (user_id, camera_id) WHERE camera_id IS NOT NULL;
+CREATE UNIQUE INDEX user_camera_grants_group_uniq ON user_camera_grants (user_id, group_id) WHERE group_id IS NOT NULL;
+
+-- Satu sumber kebenaran "user X boleh melihat kamera Y", dipakai oleh query
+-- kamera, anomali, rekaman, dan pencarian token push.
+CREATE VIEW user_accessible_cameras AS
+    SELECT u.id AS user_id, c.id AS camera_id
+    FROM users u JOIN cameras c ON c.company_id = u.company_id
+    WHERE NOT u.camera_access_restricted
+    UNION
+    SELECT u.id, c.id
+    FROM users u
+    JOIN user_camera_grants g ON g.user_id = u.id AND g.camera_id IS NOT NULL
+    JOIN cameras c ON c.id = g.camera_id AND c.company_id = u.company_id
+    WHERE u.camera_access_restricted
+    UNION
+    SELECT u.id, c.id
+    FROM users u
+    JOIN user_camera_grants g ON g.user_id = u.id AND g.group_id IS NOT NULL
+    JOIN camera_group_members m ON m.group_id = g.group_id
+    JOIN cameras c ON c.id = m.camera_id AND c.company_id = u.company_id
+    WHERE u.camera_access_restricted;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS site_staff;
+DROP INDEX IF EXISTS cameras_zone_idx;
+DROP INDEX IF EXISTS cameras_site_idx;
+ALTER TABLE cameras DROP COLUMN IF EXISTS zone_id, DROP COLUMN IF EXISTS site_id;
+DROP TABLE IF EXISTS zones;
+DROP TABLE IF EXISTS sites;
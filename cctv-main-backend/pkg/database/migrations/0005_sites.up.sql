@@ -0,0 +1,32 @@
+-- Hierarki lokasi: company → site (cabang) → zone → camera.
+CREATE TABLE sites (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    -- Bila TRUE, notifikasi kamera di site ini hanya dikirim ke staf site.
+    notify_staff_only BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, name)
+);
+
+CREATE TABLE zones (
+    id SERIAL PRIMARY KEY,
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (site_id, name)
+);
+
+ALTER TABLE cameras
+    ADD COLUMN site_id INTEGER REFERENCES sites(id) ON DELETE SET NULL,
+    ADD COLUMN zone_id INTEGER REFERENCES zones(id) ON DELETE SET NULL;
+CREATE INDEX cameras_site_idx ON cameras (site_id);
+CREATE INDEX cameras_zone_idx ON cameras (zone_id);
+
+CREATE TABLE site_staff (
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (site_id, user_id)
+);
+CREATE INDEX site_staff_user_idx ON site_staff (user_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS anomaly_events;
+DROP INDEX IF EXISTS anomaly_reports_status_idx;
+ALTER TABLE anomaly_reports
+    DROP COLUMN IF EXISTS resolved_at,
+    DROP COLUMN IF EXISTS acknowledged_at,
+    DROP COLUMN IF EXISTS resolution_notes,
+    DROP COLUMN IF EXISTS assignee_id,
+    DROP COLUMN IF EXISTS status;
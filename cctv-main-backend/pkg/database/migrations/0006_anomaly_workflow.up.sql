@@ -0,0 +1,24 @@
+-- Alur penanganan anomali: status, penanggung jawab, catatan, dan riwayat transisi.
+ALTER TABLE anomaly_reports
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'new'
+        CHECK (status IN ('new', 'acknowledged', 'in_progress', 'resolved', 'false_positive')),
+    ADD COLUMN assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN resolution_notes TEXT,
+    ADD COLUMN acknowledged_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN resolved_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX anomaly_reports_status_idx ON anomaly_reports (status);
+
+CREATE TABLE anomaly_events (
+    id BIGSERIAL PRIMARY KEY,
+    anomaly_id INTEGER NOT NULL REFERENCES anomaly_reports(id) ON DELETE CASCADE,
+    -- actor_id NULL bila user sudah dihapus; actor_email disimpan sebagai jejak audit.
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    actor_email VARCHAR(255),
+    action VARCHAR(30) NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20),
+    assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX anomaly_events_anomaly_idx ON anomaly_events (anomaly_id, created_at);
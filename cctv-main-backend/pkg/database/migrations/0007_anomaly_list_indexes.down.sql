@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS anomaly_reports_confidence_idx;
+DROP INDEX IF EXISTS anomaly_reports_camera_reported_idx;
+DROP INDEX IF EXISTS anomaly_reports_reported_idx;
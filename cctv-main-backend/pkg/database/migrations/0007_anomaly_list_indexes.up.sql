@@ -0,0 +1,4 @@
+-- Index untuk keyset pagination /api/anomalies (ORDER BY kolom, id) dan filter kamera.
+CREATE INDEX IF NOT EXISTS anomaly_reports_reported_idx ON anomaly_reports (reported_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS anomaly_reports_camera_reported_idx ON anomaly_reports (camera_id, reported_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS anomaly_reports_confidence_idx ON anomaly_reports (confidence DESC, id DESC);
@@ -0,0 +1,13 @@
+-- Aturan routing notifikasi per perusahaan. Sebuah channel dipakai bila minimal satu
+-- aturannya cocok; perusahaan tanpa aturan menerima notifikasi di semua channel.
+CREATE TABLE notification_routes (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    channel VARCHAR(30) NOT NULL,
+    -- Array kosong berarti semua tipe anomali.
+    anomaly_types TEXT[] NOT NULL DEFAULT '{}',
+    -- NULL berarti tanpa batas confidence.
+    min_confidence REAL CHECK (min_confidence BETWEEN 0 AND 1),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX notification_routes_company_idx ON notification_routes (company_id);
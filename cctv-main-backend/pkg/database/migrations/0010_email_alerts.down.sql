@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_digest_queue;
+ALTER TABLE users DROP COLUMN IF EXISTS email_alerts;
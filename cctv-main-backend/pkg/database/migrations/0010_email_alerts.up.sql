@@ -0,0 +1,18 @@
+-- Opt-in alert email per user: off (default), instant, atau digest berkala.
+ALTER TABLE users
+    ADD COLUMN email_alerts VARCHAR(10) NOT NULL DEFAULT 'off'
+        CHECK (email_alerts IN ('off', 'instant', 'digest'));
+
+-- Antrean alert email mode digest. Baris dihapus setelah email ringkasannya
+-- terkirim, jadi alert tidak hilang bila proses mati atau SMTP gagal.
+CREATE TABLE email_digest_queue (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    alert JSONB NOT NULL,
+    -- Diisi saat diambil proses pengirim; baris yang lease-nya habis diambil lagi.
+    claimed_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX email_digest_queue_email_idx ON email_digest_queue (email, id);
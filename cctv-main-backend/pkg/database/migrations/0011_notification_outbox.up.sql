@@ -0,0 +1,33 @@
+-- Outbox notifikasi: ditulis dalam transaksi yang sama dengan anomali sehingga
+-- alert tidak hilang bila proses mati sebelum notifikasi terkirim.
+CREATE TABLE notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    anomaly_id INTEGER REFERENCES anomaly_reports(id) ON DELETE CASCADE,
+    -- Alert tanpa baris anomaly_reports (kamera offline): laporan semunya disimpan sebagai JSON.
+    report JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'done', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT notification_outbox_source_check CHECK (anomaly_id IS NOT NULL OR report IS NOT NULL)
+);
+CREATE INDEX notification_outbox_due_idx ON notification_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX notification_outbox_anomaly_idx ON notification_outbox (anomaly_id);
+
+-- Hasil per penerima (token FCM, alamat email, antrean webhook) per channel.
+-- Penerima berstatus sent dilewati saat outbox dicoba ulang; rejected berarti
+-- gagal permanen (token invalid, payload ditolak) dan juga tidak dicoba lagi.
+CREATE TABLE notification_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    outbox_id BIGINT NOT NULL REFERENCES notification_outbox(id) ON DELETE CASCADE,
+    channel VARCHAR(30) NOT NULL,
+    recipient TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'failed', 'rejected')),
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (outbox_id, channel, recipient)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE cameras DROP COLUMN IF EXISTS alert_cooldown_seconds;
+DROP INDEX IF EXISTS anomaly_reports_dedup_idx;
+ALTER TABLE anomaly_reports
+    DROP COLUMN IF EXISTS video_clip_urls,
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS occurrences;
@@ -0,0 +1,19 @@
+-- Penggabungan alert: laporan berulang untuk kamera + tipe yang sama dalam jendela
+-- cooldown dilipat ke satu insiden (occurrences, reported_at = pertama, last_seen_at = terakhir).
+ALTER TABLE anomaly_reports
+    ADD COLUMN occurrences INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN last_seen_at TIMESTAMP WITH TIME ZONE;
+UPDATE anomaly_reports SET last_seen_at = reported_at;
+ALTER TABLE anomaly_reports
+    ALTER COLUMN last_seen_at SET NOT NULL,
+    ALTER COLUMN last_seen_at SET DEFAULT NOW();
+-- Semua klip insiden: video_clip_url tetap klip pertama, klip dari laporan yang
+-- dilipat ke insiden ditambahkan di video_clip_urls.
+ALTER TABLE anomaly_reports ADD COLUMN video_clip_urls TEXT[] NOT NULL DEFAULT '{}';
+UPDATE anomaly_reports SET video_clip_urls = ARRAY[video_clip_url]
+WHERE video_clip_url IS NOT NULL AND video_clip_url <> '';
+CREATE INDEX anomaly_reports_dedup_idx ON anomaly_reports (camera_id, anomaly_type, last_seen_at DESC);
+
+-- Jendela cooldown per kamera (detik); NULL = default server, 0 = tanpa penggabungan.
+ALTER TABLE cameras
+    ADD COLUMN alert_cooldown_seconds INTEGER CHECK (alert_cooldown_seconds >= 0);
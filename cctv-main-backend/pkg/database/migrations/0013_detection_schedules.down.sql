@@ -0,0 +1,3 @@
+ALTER TABLE anomaly_reports DROP COLUMN IF EXISTS suppressed;
+DROP TABLE IF EXISTS detection_schedule_targets;
+DROP TABLE IF EXISTS detection_schedules;
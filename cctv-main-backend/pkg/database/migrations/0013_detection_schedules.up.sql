@@ -0,0 +1,28 @@
+-- Jadwal deteksi mingguan: mode arm (alert hanya di dalam jendela) atau quiet
+-- (alert diredam di dalam jendela), dipasang ke kamera atau grup kamera.
+CREATE TABLE detection_schedules (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    mode VARCHAR(10) NOT NULL DEFAULT 'arm' CHECK (mode IN ('arm', 'quiet')),
+    -- [{"days": ["mon", ...], "start": "18:00", "end": "07:00"}], jam lokal APP_TZ
+    windows JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, name)
+);
+
+CREATE TABLE detection_schedule_targets (
+    schedule_id INTEGER NOT NULL REFERENCES detection_schedules(id) ON DELETE CASCADE,
+    camera_id INTEGER REFERENCES cameras(id) ON DELETE CASCADE,
+    group_id INTEGER REFERENCES camera_groups(id) ON DELETE CASCADE,
+    CHECK ((camera_id IS NULL) <> (group_id IS NULL))
+);
+CREATE UNIQUE INDEX detection_schedule_targets_camera_uniq ON detection_schedule_targets (schedule_id, camera_id) WHERE camera_id IS NOT NULL;
+CREATE UNIQUE INDEX detection_schedule_targets_group_uniq ON detection_schedule_targets (schedule_id, group_id) WHERE group_id IS NOT NULL;
+CREATE INDEX detection_schedule_targets_camera_idx ON detection_schedule_targets (camera_id);
+CREATE INDEX detection_schedule_targets_group_idx ON detection_schedule_targets (group_id);
+
+-- Laporan saat kamera tidak armed tetap disimpan, tetapi tanpa notifikasi.
+ALTER TABLE anomaly_reports ADD COLUMN suppressed BOOLEAN NOT NULL DEFAULT FALSE;
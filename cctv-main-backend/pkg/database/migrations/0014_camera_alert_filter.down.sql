@@ -0,0 +1,4 @@
+ALTER TABLE cameras
+    DROP COLUMN IF EXISTS filter_action,
+    DROP COLUMN IF EXISTS anomaly_types,
+    DROP COLUMN IF EXISTS min_confidence;
@@ -0,0 +1,7 @@
+-- Filter laporan per kamera: ambang confidence dan tipe anomali yang diterima.
+-- Laporan yang tidak lolos dibuang (drop) atau disimpan tanpa notifikasi (store).
+ALTER TABLE cameras
+    ADD COLUMN min_confidence REAL CHECK (min_confidence BETWEEN 0 AND 1),
+    ADD COLUMN anomaly_types TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN filter_action VARCHAR(10) NOT NULL DEFAULT 'drop'
+        CHECK (filter_action IN ('drop', 'store'));
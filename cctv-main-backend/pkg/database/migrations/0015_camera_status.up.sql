@@ -0,0 +1,24 @@
+-- Status streaming kamera dari monitor MediaMTX (satu baris per kamera).
+CREATE TABLE camera_status (
+    camera_id INTEGER PRIMARY KEY REFERENCES cameras(id) ON DELETE CASCADE,
+    online BOOLEAN NOT NULL DEFAULT FALSE,
+    -- waktu transisi online/offline terakhir
+    since TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE,
+    bitrate_kbps INTEGER,
+    codec VARCHAR(30),
+    width INTEGER,
+    height INTEGER,
+    -- notifikasi "kamera offline" sudah dikirim untuk periode offline ini
+    offline_notified_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Riwayat transisi online/offline.
+CREATE TABLE camera_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    camera_id INTEGER NOT NULL REFERENCES cameras(id) ON DELETE CASCADE,
+    online BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX camera_status_events_camera_idx ON camera_status_events (camera_id, created_at);
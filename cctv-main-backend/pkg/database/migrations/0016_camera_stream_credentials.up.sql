@@ -0,0 +1,10 @@
+-- Kredensial publish/read per kamera untuk path MediaMTX (diperiksa lewat
+-- authMethod http di /internal/mediamtx/auth).
+CREATE TABLE camera_stream_credentials (
+    camera_id INTEGER PRIMARY KEY REFERENCES cameras(id) ON DELETE CASCADE,
+    publish_user VARCHAR(100) NOT NULL,
+    publish_pass VARCHAR(100) NOT NULL,
+    read_user VARCHAR(100) NOT NULL,
+    read_pass VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN fcm_token VARCHAR(255);
+UPDATE users u SET fcm_token = d.token
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, token FROM user_devices
+    WHERE length(token) <= 255 ORDER BY user_id, last_seen_at DESC
+) d
+WHERE d.user_id = u.id;
+DROP TABLE IF EXISTS user_devices;
@@ -0,0 +1,20 @@
+-- Token push per perangkat: satu user bisa login di beberapa ponsel/tablet.
+-- Token unik global; perangkat yang login sebagai user lain dipindahkan ke user itu.
+CREATE TABLE user_devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token VARCHAR(512) NOT NULL UNIQUE,
+    platform VARCHAR(20) NOT NULL DEFAULT 'unknown'
+        CHECK (platform IN ('android', 'ios', 'web', 'unknown')),
+    device_name VARCHAR(100),
+    app_version VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX user_devices_user_idx ON user_devices (user_id);
+
+INSERT INTO user_devices (user_id, token)
+SELECT id, fcm_token FROM users WHERE fcm_token IS NOT NULL AND fcm_token <> ''
+ON CONFLICT (token) DO NOTHING;
+
+ALTER TABLE users DROP COLUMN fcm_token;
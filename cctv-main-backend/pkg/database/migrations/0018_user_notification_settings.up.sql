@@ -0,0 +1,12 @@
+-- Preferensi notifikasi per user; user tanpa baris memakai default (semua kamera,
+-- semua tipe, push + email, tanpa jam tenang). Mode email tetap di users.email_alerts.
+CREATE TABLE user_notification_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    camera_ids BIGINT[] NOT NULL DEFAULT '{}',
+    site_ids BIGINT[] NOT NULL DEFAULT '{}',
+    min_confidence REAL CHECK (min_confidence BETWEEN 0 AND 1),
+    anomaly_types TEXT[] NOT NULL DEFAULT '{}',
+    channels TEXT[] NOT NULL DEFAULT '{push,email}',
+    quiet_hours JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE user_devices DROP COLUMN provider;
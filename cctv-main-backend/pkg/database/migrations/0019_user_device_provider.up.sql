@@ -0,0 +1,5 @@
+-- Provider token push: 'fcm' (default, termasuk token lama) atau 'apns' untuk
+-- token perangkat iOS yang dikirim langsung ke Apple tanpa Firebase.
+ALTER TABLE user_devices
+    ADD COLUMN provider VARCHAR(10) NOT NULL DEFAULT 'fcm'
+        CHECK (provider IN ('fcm', 'apns'));
@@ -0,0 +1,4 @@
+-- Bahasa notifikasi per user; template push tersedia untuk Indonesia dan Inggris.
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT 'id'
+        CHECK (locale IN ('id', 'en'));
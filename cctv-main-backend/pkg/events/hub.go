@@ -0,0 +1,110 @@
+// Package events adalah pub/sub in-process untuk event real-time (SSE /api/events).
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tipe event yang dikirim ke klien.
+const (
+	AnomalyCreated       = "anomaly.created"
+	AnomalyStatusChanged = "anomaly.status_changed"
+	AnomalyRepeated      = "anomaly.repeated" // laporan dilipat ke insiden yang masih terbuka
+	CameraOnline         = "camera.online"
+	CameraOffline        = "camera.offline"
+)
+
+// Event adalah satu pesan di hub. CompanyID dan CameraID dipakai untuk
+// menyaring pelanggan; Data dikirim apa adanya sebagai JSON.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	CompanyID int64     `json:"company_id"`
+	CameraID  int64     `json:"camera_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// Publisher adalah sisi hub yang dipakai service untuk menerbitkan event.
+type Publisher interface {
+	Publish(ev Event)
+}
+
+// Subscription menerima event untuk satu koneksi klien.
+type Subscription struct {
+	C <-chan Event
+
+	ch        chan Event
+	companyID int64
+	dropped   atomic.Uint64
+}
+
+// Dropped mengembalikan jumlah event yang dibuang karena klien terlalu lambat.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	nextID atomic.Uint64
+	buffer int
+}
+
+// NewHub membuat hub; buffer adalah kapasitas antrean per pelanggan.
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	return &Hub{subs: map[*Subscription]struct{}{}, buffer: buffer}
+}
+
+// Subscribe mendaftarkan pelanggan untuk event companyID (0 = semua perusahaan).
+// Panggil Unsubscribe saat koneksi ditutup.
+func (h *Hub) Subscribe(companyID int64) *Subscription {
+	ch := make(chan Event, h.buffer)
+	sub := &Subscription{C: ch, ch: ch, companyID: companyID}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+	h.mu.Unlock()
+}
+
+// Publish tidak pernah memblokir: bila antrean pelanggan penuh, event dibuang
+// untuk pelanggan tersebut agar SaveReport tidak ikut tertahan.
+func (h *Hub) Publish(ev Event) {
+	ev.ID = h.nextID.Add(1)
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if sub.companyID != 0 && sub.companyID != ev.CompanyID {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers mengembalikan jumlah koneksi aktif (untuk health/debug).
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
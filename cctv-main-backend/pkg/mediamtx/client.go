@@ -0,0 +1,182 @@
+// Package mediamtx adalah klien kecil untuk control API MediaMTX (v3).
+package mediamtx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client memanggil control API MediaMTX, mis. http://mediamtx:9997.
+type Client struct {
+	BaseURL  string
+	Username string // opsional, user authInternalUsers dengan permission api
+	Password string
+	HTTP     *http.Client
+}
+
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Username: username,
+		Password: password,
+		HTTP:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Path adalah status satu path (stream) di MediaMTX.
+type Path struct {
+	Name          string     `json:"name"`
+	Ready         bool       `json:"ready"`
+	ReadyTime     *time.Time `json:"readyTime"`
+	Tracks        []string   `json:"tracks"`
+	Tracks2       []Track    `json:"tracks2"` // MediaMTX baru: codec beserta resolusi
+	BytesReceived uint64     `json:"bytesReceived"`
+}
+
+type Track struct {
+	Codec      string `json:"codec"`
+	CodecProps struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"codecProps"`
+}
+
+// Video mengembalikan codec dan resolusi track video pertama; resolusi 0 bila
+// versi MediaMTX belum melaporkannya.
+func (p Path) Video() (codec string, width, height int) {
+	for _, t := range p.Tracks2 {
+		if t.CodecProps.Width > 0 {
+			return t.Codec, t.CodecProps.Width, t.CodecProps.Height
+		}
+	}
+	for _, t := range p.Tracks {
+		switch t {
+		case "H264", "H265", "AV1", "VP8", "VP9", "MJPEG", "MPEG-4 Video", "MPEG-1/2 Video":
+			return t, 0, 0
+		}
+	}
+	return "", 0, 0
+}
+
+// APIError adalah respons non-2xx dari control API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mediamtx api: status %d: %s", e.StatusCode, e.Message)
+}
+
+// ListPaths mengambil semua path (semua halaman).
+func (c *Client) ListPaths(ctx context.Context) ([]Path, error) {
+	var all []Path
+	for page := 0; ; page++ {
+		var resp struct {
+			PageCount int    `json:"pageCount"`
+			Items     []Path `json:"items"`
+		}
+		q := url.Values{"itemsPerPage": {"500"}, "page": {fmt.Sprint(page)}}
+		if err := c.do(ctx, http.MethodGet, "/v3/paths/list?"+q.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Items...)
+		if page+1 >= resp.PageCount {
+			return all, nil
+		}
+	}
+}
+
+// PathConf adalah bagian konfigurasi path yang diatur backend. Source "publisher"
+// berarti path menerima stream yang di-push; URL rtsp:// berarti MediaMTX menarik
+// stream dari kamera IP.
+type PathConf struct {
+	Name           string `json:"name,omitempty"`
+	Source         string `json:"source"`
+	SourceOnDemand bool   `json:"sourceOnDemand"`
+}
+
+// ListPathConfs mengambil konfigurasi semua path (semua halaman).
+func (c *Client) ListPathConfs(ctx context.Context) ([]PathConf, error) {
+	var all []PathConf
+	for page := 0; ; page++ {
+		var resp struct {
+			PageCount int        `json:"pageCount"`
+			Items     []PathConf `json:"items"`
+		}
+		q := url.Values{"itemsPerPage": {"500"}, "page": {fmt.Sprint(page)}}
+		if err := c.do(ctx, http.MethodGet, "/v3/config/paths/list?"+q.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Items...)
+		if page+1 >= resp.PageCount {
+			return all, nil
+		}
+	}
+}
+
+// AddPath menambahkan konfigurasi path; bila path sudah ada konfigurasinya diperbarui.
+func (c *Client) AddPath(ctx context.Context, name string, conf PathConf) error {
+	conf.Name = ""
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	err = c.do(ctx, http.MethodPost, "/v3/config/paths/add/"+url.PathEscape(name), bytes.NewReader(b), nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Message, "already exists") {
+		return c.do(ctx, http.MethodPatch, "/v3/config/paths/patch/"+url.PathEscape(name), bytes.NewReader(b), nil)
+	}
+	return err
+}
+
+// DeletePath menghapus konfigurasi path; path yang tidak ada tidak dianggap error.
+func (c *Client) DeletePath(ctx context.Context, name string) error {
+	err := c.do(ctx, http.MethodDelete, "/v3/config/paths/delete/"+url.PathEscape(name), nil, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(raw, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(raw))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
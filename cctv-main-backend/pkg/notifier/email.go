@@ -0,0 +1,368 @@
+package notifier
+
+import (
+	"bytes"
+	"cctv-main-backend/internal/domain"
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*
+var defaultTemplates embed.FS
+
+// maxDigestAlerts membatasi jumlah baris per email digest; sisanya hanya dihitung.
+const maxDigestAlerts = 200
+
+// digestLease adalah lama alert digest ditahan satu proses saat mengirim; bila
+// proses mati di tengah jalan, alert dikirim putaran berikutnya setelah lease habis.
+const digestLease = 15 * time.Minute
+
+// EmailConfig adalah konfigurasi SMTP dan template untuk Email.
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	// TemplateDir opsional: alert.html, alert.txt, digest.html, digest.txt di
+	// direktori ini menggantikan template bawaan.
+	TemplateDir    string
+	DigestInterval time.Duration
+	Location       *time.Location // zona waktu untuk menampilkan jam kejadian
+}
+
+// EmailAlert adalah data satu anomali yang tersedia di template.
+type EmailAlert struct {
+	AnomalyID   int64
+	CameraID    int64
+	CameraName  string
+	Location    string
+	AnomalyType string
+	Confidence  float64
+	ReportedAt  time.Time
+	ClipURL     string
+}
+
+type alertData struct {
+	Recipient domain.EmailRecipient
+	Alert     EmailAlert
+}
+
+type digestData struct {
+	Recipient domain.EmailRecipient
+	Alerts    []EmailAlert
+	Total     int
+	Since     time.Time
+	Until     time.Time
+}
+
+type digestBatch struct {
+	recipient domain.EmailRecipient
+	alerts    []EmailAlert
+	total     int
+	since     time.Time
+	ids       []int64
+}
+
+// DigestQueue menyimpan alert mode digest sampai email ringkasannya terkirim
+// (notification.Repository).
+type DigestQueue interface {
+	AddEmailDigest(ctx context.Context, rc domain.EmailRecipient, alert []byte) error
+	ClaimEmailDigests(ctx context.Context, lease time.Duration) ([]domain.EmailDigestItem, error)
+	DeleteEmailDigests(ctx context.Context, ids []int64) error
+}
+
+// Email mengirim alert anomali lewat SMTP. Penerima dengan mode instant mendapat
+// satu email per anomali; mode digest disimpan di Digests dan dikirim tiap
+// DigestInterval oleh RunDigest.
+type Email struct {
+	cfg  EmailConfig
+	html *htmltemplate.Template
+	text *texttemplate.Template
+
+	// Hooks dari repo user/kamera dan storage
+	GetRecipients          func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.EmailRecipient, error)
+	GetCompanyIDByCameraID func(ctx context.Context, cameraID int64) (int64, error)
+	GetCamera              func(ctx context.Context, cameraID int64) (*domain.Camera, error)
+	PresignClip            func(clipURL string) (string, error)
+
+	// Antrean digest; tanpa antrean penerima mode digest gagal dikirimi.
+	Digests DigestQueue
+}
+
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP host kosong")
+	}
+	if cfg.From == "" {
+		return nil, errors.New("alamat pengirim (From) kosong")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.DigestInterval <= 0 {
+		cfg.DigestInterval = 15 * time.Minute
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+
+	e := &Email{cfg: cfg}
+	funcs := map[string]any{
+		"percent":   func(c float64) string { return fmt.Sprintf("%.0f%%", c*100) },
+		"localtime": func(t time.Time) string { return t.In(cfg.Location).Format("02 Jan 2006 15:04 MST") },
+		"sub":       func(a, b int) int { return a - b },
+	}
+
+	var err error
+	if e.html, err = htmltemplate.New("email").Funcs(funcs).ParseFS(templateFS(cfg.TemplateDir), "*.html"); err != nil {
+		return nil, fmt.Errorf("parse template html: %w", err)
+	}
+	if e.text, err = texttemplate.New("email").Funcs(funcs).ParseFS(templateFS(cfg.TemplateDir), "*.txt"); err != nil {
+		return nil, fmt.Errorf("parse template teks: %w", err)
+	}
+	for _, name := range []string{"alert", "digest"} {
+		if e.html.Lookup(name+".html") == nil || e.text.Lookup(name+".txt") == nil {
+			return nil, fmt.Errorf("template %s.html/%s.txt tidak ditemukan", name, name)
+		}
+	}
+	return e, nil
+}
+
+// templateFS memakai dir bila diisi, selain itu template bawaan.
+func templateFS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	sub, _ := fs.Sub(defaultTemplates, "templates")
+	return sub
+}
+
+func (e *Email) Send(report *domain.AnomalyReport) error {
+	return e.NotifyAnomaly(context.Background(), report)
+}
+
+func (e *Email) NotifyAnomaly(ctx context.Context, r *domain.AnomalyReport) error {
+	if e.GetRecipients == nil {
+		return errors.New("dependency GetRecipients nil")
+	}
+	companyID := r.CompanyID
+	if companyID == 0 && e.GetCompanyIDByCameraID != nil {
+		id, err := e.GetCompanyIDByCameraID(ctx, r.CameraID)
+		if err != nil {
+			return fmt.Errorf("map camera->company: %w", err)
+		}
+		companyID = id
+	}
+	recipients, err := e.GetRecipients(ctx, companyID, r)
+	if err != nil {
+		return fmt.Errorf("get email recipients: %w", err)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	alert := e.buildAlert(ctx, r)
+	var errs []error
+	sent := 0
+	for _, rc := range recipients {
+		if alreadySent(ctx, rc.Email) {
+			continue
+		}
+		if rc.Mode == domain.EmailAlertsDigest {
+			// Tercatat terkirim setelah alert tersimpan di antrean digest; email
+			// ringkasannya dikirim (dan diulang bila gagal) oleh RunDigest.
+			err := e.queueDigest(ctx, rc, alert)
+			record(ctx, rc.Email, err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: antrekan digest: %w", rc.Email, err))
+			}
+			continue
+		}
+		subject := fmt.Sprintf("[CCTV] %s - %s", alert.AnomalyType, alert.CameraName)
+		err := e.send(rc, subject, "alert", alertData{Recipient: rc, Alert: alert})
+		record(ctx, rc.Email, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rc.Email, err))
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("Email: anomaly %d dikirim ke %d penerima", r.ID, sent)
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Email) buildAlert(ctx context.Context, r *domain.AnomalyReport) EmailAlert {
+	alert := EmailAlert{
+		AnomalyID:   r.ID,
+		CameraID:    r.CameraID,
+		CameraName:  fmt.Sprintf("Kamera %d", r.CameraID),
+		AnomalyType: r.AnomalyType,
+		Confidence:  r.Confidence,
+		ReportedAt:  r.ReportedAt,
+	}
+	if e.GetCamera != nil {
+		if cam, err := e.GetCamera(ctx, r.CameraID); err == nil {
+			alert.CameraName = cam.Name
+			alert.Location = cam.Location
+		}
+	}
+	if r.VideoClipURL != "" && e.PresignClip != nil {
+		if u, err := e.PresignClip(r.VideoClipURL); err == nil {
+			alert.ClipURL = u
+		} else {
+			log.Printf("Email: presign clip anomaly %d: %v", r.ID, err)
+		}
+	}
+	return alert
+}
+
+func (e *Email) queueDigest(ctx context.Context, rc domain.EmailRecipient, alert EmailAlert) error {
+	if e.Digests == nil {
+		return errors.New("antrean digest tidak dikonfigurasi")
+	}
+	b, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	return e.Digests.AddEmailDigest(ctx, rc, b)
+}
+
+// RunDigest mengirim email ringkasan tiap DigestInterval sampai ctx dibatalkan.
+func (e *Email) RunDigest(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.DigestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.FlushDigest(ctx); err != nil {
+				log.Printf("Email: digest: %v", err)
+			}
+		}
+	}
+}
+
+// FlushDigest mengirim satu email ringkasan per penerima untuk semua alert di
+// antrean. Alert baru dihapus dari antrean setelah email-nya diterima server
+// SMTP; yang gagal dikirim ulang pada putaran berikutnya.
+func (e *Email) FlushDigest(ctx context.Context) error {
+	if e.Digests == nil {
+		return nil
+	}
+	items, err := e.Digests.ClaimEmailDigests(ctx, digestLease)
+	if err != nil {
+		return fmt.Errorf("ambil antrean digest: %w", err)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	var order []string
+	batches := map[string]*digestBatch{}
+	for _, it := range items {
+		var alert EmailAlert
+		if err := json.Unmarshal(it.Alert, &alert); err != nil {
+			log.Printf("Email: alert digest %d rusak: %v", it.ID, err)
+			continue
+		}
+		b, ok := batches[it.Recipient.Email]
+		if !ok {
+			b = &digestBatch{recipient: it.Recipient, since: alert.ReportedAt}
+			batches[it.Recipient.Email] = b
+			order = append(order, it.Recipient.Email)
+		}
+		b.ids = append(b.ids, it.ID)
+		b.total++
+		if len(b.alerts) < maxDigestAlerts {
+			b.alerts = append(b.alerts, alert)
+		}
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, email := range order {
+		b := batches[email]
+		subject := fmt.Sprintf("[CCTV] Ringkasan %d anomali", b.total)
+		data := digestData{Recipient: b.recipient, Alerts: b.alerts, Total: b.total, Since: b.since, Until: now}
+		if err := e.send(b.recipient, subject, "digest", data); err != nil {
+			errs = append(errs, fmt.Errorf("kirim ke %s: %w", email, err))
+			continue
+		}
+		if err := e.Digests.DeleteEmailDigests(ctx, b.ids); err != nil {
+			errs = append(errs, fmt.Errorf("hapus antrean %s: %w", email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send merender template name (.txt dan .html) menjadi email multipart/alternative.
+func (e *Email) send(rc domain.EmailRecipient, subject, name string, data any) error {
+	var text, html bytes.Buffer
+	if err := e.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return err
+	}
+	if err := e.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return err
+	}
+
+	boundary := randomBoundary()
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", rc.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	writePart(&msg, boundary, "text/plain", text.String())
+	writePart(&msg, boundary, "text/html", html.String())
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	return smtp.SendMail(addr, auth, envelopeAddress(e.cfg.From), []string{rc.Email}, msg.Bytes())
+}
+
+func writePart(w *bytes.Buffer, boundary, contentType, body string) {
+	fmt.Fprintf(w, "--%s\r\n", boundary)
+	fmt.Fprintf(w, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(w, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+	w.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	w.WriteString("\r\n")
+}
+
+// envelopeAddress mengambil alamat dari "Nama <alamat>" untuk MAIL FROM.
+func envelopeAddress(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">")
+	}
+	return from
+}
+
+func randomBoundary() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "cctv-" + hex.EncodeToString(b)
+}
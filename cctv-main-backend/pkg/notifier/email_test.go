@@ -0,0 +1,328 @@
+package notifier
+
+import (
+	"bufio"
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpMessage adalah satu email yang diterima fakeSMTP.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTP adalah server SMTP minimal (tanpa TLS/AUTH) untuk menguji Email.
+// Bila reject diisi, DATA ditolak dengan 451 dan pesan tidak disimpan.
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu     sync.Mutex
+	msgs   []smtpMessage
+	reject bool
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) port() int { return s.ln.Addr().(*net.TCPAddr).Port }
+
+func (s *fakeSMTP) setReject(v bool) {
+	s.mu.Lock()
+	s.reject = v
+	s.mu.Unlock()
+}
+
+func (s *fakeSMTP) messages() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.msgs...)
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	reply("220 fake ESMTP")
+
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = smtpMessage{From: strings.Trim(strings.TrimSpace(line)[10:], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			rejected := s.reject
+			if !rejected {
+				s.msgs = append(s.msgs, msg)
+			}
+			s.mu.Unlock()
+			if rejected {
+				reply("451 try again later")
+			} else {
+				reply("250 queued")
+			}
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// memDigests adalah DigestQueue di memori.
+type memDigests struct {
+	mu     sync.Mutex
+	seq    int64
+	items  []domain.EmailDigestItem
+	addErr error
+}
+
+func (q *memDigests) AddEmailDigest(ctx context.Context, rc domain.EmailRecipient, alert []byte) error {
+	if q.addErr != nil {
+		return q.addErr
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	q.items = append(q.items, domain.EmailDigestItem{ID: q.seq, Recipient: rc, Alert: alert, CreatedAt: time.Now()})
+	return nil
+}
+
+func (q *memDigests) ClaimEmailDigests(ctx context.Context, lease time.Duration) ([]domain.EmailDigestItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]domain.EmailDigestItem(nil), q.items...), nil
+}
+
+func (q *memDigests) DeleteEmailDigests(ctx context.Context, ids []int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	drop := map[int64]bool{}
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := q.items[:0]
+	for _, it := range q.items {
+		if !drop[it.ID] {
+			kept = append(kept, it)
+		}
+	}
+	q.items = kept
+	return nil
+}
+
+func (q *memDigests) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// memTracker mencatat hasil record per penerima.
+type memTracker struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+func (t *memTracker) Sent(channel, recipient string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	err, ok := t.errs[recipient]
+	return ok && err == nil
+}
+
+func (t *memTracker) Record(channel, recipient string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errs[recipient] = err
+}
+
+func newTestEmail(t *testing.T, srv *fakeSMTP, recipients ...domain.EmailRecipient) (*Email, *memDigests) {
+	t.Helper()
+	e, err := NewEmail(EmailConfig{Host: "127.0.0.1", Port: srv.port(), From: "CCTV Alerts <alerts@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &memDigests{}
+	e.Digests = q
+	e.GetRecipients = func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.EmailRecipient, error) {
+		return recipients, nil
+	}
+	e.GetCamera = func(ctx context.Context, cameraID int64) (*domain.Camera, error) {
+		return &domain.Camera{ID: cameraID, Name: "Gerbang Utama", Location: "Lobi"}, nil
+	}
+	return e, q
+}
+
+func testReport(id int64) *domain.AnomalyReport {
+	return &domain.AnomalyReport{
+		ID:          id,
+		CameraID:    3,
+		CompanyID:   1,
+		AnomalyType: "intrusion",
+		Confidence:  0.91,
+		ReportedAt:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestEmailInstant(t *testing.T) {
+	srv := newFakeSMTP(t)
+	e, q := newTestEmail(t, srv, domain.EmailRecipient{UserID: 1, Email: "ops@example.com", Name: "Ops", Mode: domain.EmailAlertsInstant})
+
+	if err := e.NotifyAnomaly(context.Background(), testReport(10)); err != nil {
+		t.Fatalf("NotifyAnomaly: %v", err)
+	}
+	msgs := srv.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d emails, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.From != "alerts@example.com" || len(m.To) != 1 || m.To[0] != "ops@example.com" {
+		t.Fatalf("envelope = %s -> %v", m.From, m.To)
+	}
+	for _, want := range []string{
+		"Subject: [CCTV] intrusion - Gerbang Utama",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain",
+		"Content-Type: text/html",
+		"Lobi",
+		"91%",
+	} {
+		if !strings.Contains(m.Data, want) {
+			t.Errorf("email tidak berisi %q:\n%s", want, m.Data)
+		}
+	}
+	if q.len() != 0 {
+		t.Fatalf("instant recipient queued %d digest alerts", q.len())
+	}
+}
+
+func TestEmailInstantSMTPFailure(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.setReject(true)
+	e, _ := newTestEmail(t, srv, domain.EmailRecipient{UserID: 1, Email: "ops@example.com", Mode: domain.EmailAlertsInstant})
+
+	tr := &memTracker{errs: map[string]error{}}
+	err := e.NotifyAnomaly(WithTracker(context.Background(), tr), testReport(10))
+	if err == nil {
+		t.Fatal("NotifyAnomaly succeeded while SMTP rejected the message")
+	}
+	if tr.Sent("email", "ops@example.com") {
+		t.Fatal("failed recipient recorded as sent")
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	srv := newFakeSMTP(t)
+	e, q := newTestEmail(t, srv, domain.EmailRecipient{UserID: 2, Email: "night@example.com", Name: "Night", Mode: domain.EmailAlertsDigest})
+
+	// Satu Tracker per notifikasi, seperti dispatcher outbox.
+	var tr *memTracker
+	for _, id := range []int64{11, 12} {
+		tr = &memTracker{errs: map[string]error{}}
+		if err := e.NotifyAnomaly(WithTracker(context.Background(), tr), testReport(id)); err != nil {
+			t.Fatalf("NotifyAnomaly(%d): %v", id, err)
+		}
+	}
+	if n := len(srv.messages()); n != 0 {
+		t.Fatalf("digest recipient got %d emails before flush", n)
+	}
+	if q.len() != 2 {
+		t.Fatalf("queued %d digest alerts, want 2", q.len())
+	}
+	if !tr.Sent("email", "night@example.com") {
+		t.Fatal("queued digest recipient not recorded as sent")
+	}
+
+	// SMTP menolak: antrean tetap utuh untuk putaran berikutnya.
+	srv.setReject(true)
+	if err := e.FlushDigest(context.Background()); err == nil {
+		t.Fatal("FlushDigest succeeded while SMTP rejected the message")
+	}
+	if q.len() != 2 {
+		t.Fatalf("failed flush left %d digest alerts, want 2", q.len())
+	}
+
+	srv.setReject(false)
+	if err := e.FlushDigest(context.Background()); err != nil {
+		t.Fatalf("FlushDigest: %v", err)
+	}
+	msgs := srv.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d digest emails, want 1", len(msgs))
+	}
+	if !strings.Contains(msgs[0].Data, "Subject: [CCTV] Ringkasan 2 anomali") {
+		t.Fatalf("unexpected digest:\n%s", msgs[0].Data)
+	}
+	if q.len() != 0 {
+		t.Fatalf("sent digest left %d alerts in the queue", q.len())
+	}
+}
+
+func TestEmailDigestQueueFailure(t *testing.T) {
+	srv := newFakeSMTP(t)
+	e, q := newTestEmail(t, srv, domain.EmailRecipient{UserID: 2, Email: "night@example.com", Mode: domain.EmailAlertsDigest})
+	q.addErr = errors.New("db down")
+
+	tr := &memTracker{errs: map[string]error{}}
+	if err := e.NotifyAnomaly(WithTracker(context.Background(), tr), testReport(11)); err == nil {
+		t.Fatal("NotifyAnomaly succeeded while the digest queue failed")
+	}
+	if tr.Sent("email", "night@example.com") {
+		t.Fatal("unqueued digest recipient recorded as sent")
+	}
+}
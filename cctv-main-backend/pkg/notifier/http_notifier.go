@@ -3,11 +3,14 @@ package notifier
 import (
 	"bytes"
 	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/push"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,23 +19,41 @@ type HTTPNotifier struct {
 	BaseURL string
 	Secret  string
 
-	// Hooks dari repo untuk ambil token dan company mapping
-	GetAdminTokens         func(ctx context.Context, companyID int64) ([]string, error)
+	// Hooks untuk token penerima (sesuai preferensi notifikasi user) dan company mapping
+	GetRecipientTargets    func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.PushTarget, error)
 	GetCompanyIDByCameraID func(ctx context.Context, cameraID int64) (int64, error)
+	// DeleteToken (opsional) menghapus token yang dilaporkan invalid oleh push-service.
+	DeleteToken func(ctx context.Context, token string) error
+	// GetCamera (opsional) mengisi nama dan lokasi kamera di isi notifikasi.
+	GetCamera func(ctx context.Context, cameraID int64) (*domain.Camera, error)
+
+	Templates *PushTemplates
+}
+
+// pushResult adalah hasil kirim per token dari push-service.
+type pushResult struct {
+	Token  string `json:"token"`
+	Status string `json:"status"` // success, invalid, retryable, failed
+	Error  string `json:"error"`
+}
+
+type pushResponse struct {
+	Sent    int          `json:"sent"`
+	Results []pushResult `json:"results"`
 }
 
 func NewHTTPNotifier(baseURL string) (*HTTPNotifier, error) { // kept for compatibility
 	if baseURL == "" {
 		return nil, errors.New("baseURL kosong untuk HTTPNotifier")
 	}
-	return &HTTPNotifier{BaseURL: baseURL}, nil
+	return &HTTPNotifier{BaseURL: baseURL, Templates: defaultPushTemplates()}, nil
 }
 
 func NewHTTPNotifierWithSecret(baseURL, secret string) (*HTTPNotifier, error) {
 	if baseURL == "" {
 		return nil, errors.New("baseURL kosong untuk HTTPNotifier")
 	}
-	return &HTTPNotifier{BaseURL: baseURL, Secret: secret}, nil
+	return &HTTPNotifier{BaseURL: baseURL, Secret: secret, Templates: defaultPushTemplates()}, nil
 }
 
 func (n *HTTPNotifier) Send(report *domain.AnomalyReport) error {
@@ -40,34 +61,60 @@ func (n *HTTPNotifier) Send(report *domain.AnomalyReport) error {
 }
 
 func (n *HTTPNotifier) NotifyAnomaly(ctx context.Context, r *domain.AnomalyReport) error {
-	if n.GetCompanyIDByCameraID == nil || n.GetAdminTokens == nil {
-		return errors.New("dependency GetCompanyIDByCameraID/GetAdminTokens nil")
+	if n.GetCompanyIDByCameraID == nil || n.GetRecipientTargets == nil {
+		return errors.New("dependency GetCompanyIDByCameraID/GetRecipientTargets nil")
 	}
 	companyID, err := n.GetCompanyIDByCameraID(ctx, r.CameraID)
 	if err != nil {
 		return fmt.Errorf("map camera->company: %w", err)
 	}
-	tokens, err := n.GetAdminTokens(ctx, companyID)
+	targets, err := n.GetRecipientTargets(ctx, companyID, r)
 	if err != nil {
-		return fmt.Errorf("get admin tokens: %w", err)
+		return fmt.Errorf("get recipient tokens: %w", err)
+	}
+	// Kelompokkan per bahasa (satu request per bahasa); token yang sudah
+	// terkirim pada percobaan sebelumnya dilewati.
+	groups := map[string][]domain.PushTarget{}
+	for _, t := range targets {
+		if !alreadySent(ctx, t.Token) {
+			groups[t.Locale] = append(groups[t.Locale], t)
+		}
 	}
-	if len(tokens) == 0 {
+	if len(groups) == 0 {
 		return nil
 	}
 
+	content := pushContent(ctx, r, n.GetCamera)
+	data := pushData(r)
+	var errs []error
+	for locale, group := range groups {
+		title, body := n.Templates.Render(locale, content)
+		if err := n.sendGroup(ctx, group, title, body, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendGroup mengirim satu pesan ke sekelompok token lewat push-service.
+func (n *HTTPNotifier) sendGroup(ctx context.Context, group []domain.PushTarget, title, body string, data map[string]string) error {
+	var tokens, fcmTokens, apnsTokens []string
+	for _, t := range group {
+		tokens = append(tokens, t.Token)
+		if t.Provider == domain.PushProviderAPNs {
+			apnsTokens = append(apnsTokens, t.Token)
+		} else {
+			fcmTokens = append(fcmTokens, t.Token)
+		}
+	}
 	payload := map[string]any{
-		"tokens": tokens,
-		"title":  "Anomali Terdeteksi",
-		"body":   fmt.Sprintf("Kamera %d • %.0f%%", r.CameraID, r.Confidence*100),
-		"data": map[string]string{
-			"type":         "anomaly",
-			"anomaly_id":   fmt.Sprintf("%d", r.ID),
-			"camera_id":    fmt.Sprintf("%d", r.CameraID),
-			"confidence":   fmt.Sprintf("%.3f", r.Confidence),
-			"video_url":    r.VideoClipURL,
-			"anomaly_type": r.AnomalyType,
-			"deeplink":     fmt.Sprintf("app://camera/%d/anomaly", r.CameraID),
-		},
+		"tokens": fcmTokens,
+		"title":  title,
+		"body":   body,
+		"data":   data,
+	}
+	if len(apnsTokens) > 0 {
+		payload["apns_tokens"] = apnsTokens
 	}
 	b, _ := json.Marshal(payload)
 
@@ -80,14 +127,71 @@ func (n *HTTPNotifier) NotifyAnomaly(ctx context.Context, r *domain.AnomalyRepor
 		req.Header.Set("X-Push-Secret", n.Secret)
 	}
 
-	httpClient := &http.Client{Timeout: 5 * time.Second}
+	resp, err := n.post(req)
+	if err != nil || resp.Results == nil {
+		// push-service versi lama tidak mengirim hasil per token.
+		for _, t := range tokens {
+			record(ctx, t, err)
+		}
+		return err
+	}
+	return n.applyResults(ctx, tokens, resp.Results)
+}
+
+// applyResults mencatat hasil per token, menghapus token invalid, dan
+// mengembalikan error bila masih ada token yang perlu dikirim ulang.
+func (n *HTTPNotifier) applyResults(ctx context.Context, tokens []string, results []pushResult) error {
+	byToken := make(map[string]pushResult, len(results))
+	for _, res := range results {
+		byToken[res.Token] = res
+	}
+	retryable := 0
+	for _, t := range tokens {
+		res, ok := byToken[strings.TrimSpace(t)]
+		if !ok {
+			res = pushResult{Status: push.StatusRetryable, Error: "tidak ada hasil dari push-service"}
+		}
+		err := fmt.Errorf("%s: %s", res.Status, res.Error)
+		switch res.Status {
+		case push.StatusSuccess:
+			record(ctx, t, nil)
+		case push.StatusRetryable:
+			// Hanya gangguan sementara yang membuat outbox dicoba ulang.
+			record(ctx, t, err)
+			retryable++
+		case push.StatusInvalid:
+			record(ctx, t, Final(err))
+			if n.DeleteToken != nil {
+				if err := n.DeleteToken(ctx, t); err != nil {
+					log.Printf("push-service: gagal menghapus token invalid: %v", err)
+				}
+			}
+		default:
+			// failed: payload atau kredensial ditolak; mengulang tidak akan membantu.
+			record(ctx, t, Final(err))
+			log.Printf("push-service: token gagal permanen: %s", res.Error)
+		}
+	}
+	if retryable > 0 {
+		return fmt.Errorf("push-service: %d token gagal sementara", retryable)
+	}
+	return nil
+}
+
+func (n *HTTPNotifier) post(req *http.Request) (*pushResponse, error) {
+	// Batas waktu utama dari ctx (NOTIFY_CHANNEL_TIMEOUT); push-service sendiri me-retry sampai PUSH_SEND_TIMEOUT.
+	httpClient := &http.Client{Timeout: 15 * time.Second}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("push-service non-2xx: %s", resp.Status)
+		return nil, fmt.Errorf("push-service non-2xx: %s", resp.Status)
 	}
-	return nil
+	var out pushResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode respons push-service: %w", err)
+	}
+	return &out, nil
 }
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newTestHTTPNotifier menjalankan push-service palsu yang menjawab status per
+// token dari statuses (token yang tidak ada di map dianggap success).
+func newTestHTTPNotifier(t *testing.T, statuses map[string]string, tokens ...string) (*HTTPNotifier, *[]string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Push-Secret") != "s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Tokens []string `json:"tokens"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results := []pushResult{}
+		for _, tok := range req.Tokens {
+			st := statuses[tok]
+			if st == "" {
+				st = "success"
+			}
+			results = append(results, pushResult{Token: tok, Status: st, Error: st + " error"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+	t.Cleanup(srv.Close)
+
+	n, err := NewHTTPNotifierWithSecret(srv.URL, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.GetCompanyIDByCameraID = func(ctx context.Context, cameraID int64) (int64, error) { return 1, nil }
+	n.GetRecipientTargets = func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.PushTarget, error) {
+		var targets []domain.PushTarget
+		for _, tok := range tokens {
+			targets = append(targets, domain.PushTarget{Token: tok, Provider: domain.PushProviderFCM})
+		}
+		return targets, nil
+	}
+	var mu sync.Mutex
+	deleted := &[]string{}
+	n.DeleteToken = func(ctx context.Context, token string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*deleted = append(*deleted, token)
+		return nil
+	}
+	return n, deleted
+}
+
+func TestHTTPNotifierResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  map[string]string
+		wantErr   bool
+		wantFinal []string // dicatat gagal permanen
+		wantRetry []string // dicatat gagal sementara
+	}{
+		{"semua berhasil", nil, false, nil, nil},
+		{"invalid dihapus, tidak diulang", map[string]string{"b": "invalid"}, false, []string{"b"}, nil},
+		{"failed final, tidak diulang", map[string]string{"b": "failed"}, false, []string{"b"}, nil},
+		{"retryable diulang", map[string]string{"b": "retryable"}, true, nil, []string{"b"}},
+		{"campuran", map[string]string{"a": "failed", "b": "retryable", "c": "invalid"}, true, []string{"a", "c"}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, deleted := newTestHTTPNotifier(t, tt.statuses, "a", "b", "c")
+			tr := &memTracker{errs: map[string]error{}}
+			err := n.NotifyAnomaly(WithTracker(context.Background(), tr), testReport(1))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NotifyAnomaly err = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, tok := range []string{"a", "b", "c"} {
+				got, ok := tr.errs[tok]
+				if !ok {
+					t.Fatalf("token %s tidak dicatat", tok)
+				}
+				switch {
+				case contains(tt.wantFinal, tok):
+					if got == nil || !IsFinal(got) {
+						t.Errorf("token %s: err = %v, want final", tok, got)
+					}
+				case contains(tt.wantRetry, tok):
+					if got == nil || IsFinal(got) {
+						t.Errorf("token %s: err = %v, want retryable", tok, got)
+					}
+				default:
+					if got != nil {
+						t.Errorf("token %s: err = %v, want success", tok, got)
+					}
+				}
+			}
+			for _, tok := range *deleted {
+				if tt.statuses[tok] != "invalid" {
+					t.Errorf("token %s (%s) dihapus", tok, tt.statuses[tok])
+				}
+			}
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
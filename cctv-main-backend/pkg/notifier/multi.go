@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ChannelError membungkus error dari satu channel MultiNotifier.
+type ChannelError struct {
+	Channel string
+	Err     error
+}
+
+func (e *ChannelError) Error() string { return e.Channel + ": " + e.Err.Error() }
+func (e *ChannelError) Unwrap() error { return e.Err }
+
+type channel struct {
+	name     string
+	notifier Notifier
+}
+
+// MultiNotifier meneruskan anomali ke beberapa channel secara paralel, masing-masing
+// dengan batas waktu sendiri, sehingga gangguan satu channel tidak menahan yang lain.
+type MultiNotifier struct {
+	channels []channel
+	Timeout  time.Duration // batas waktu per channel
+
+	// Route memilih nama channel untuk laporan; nil (atau hasil nil) = semua channel.
+	// Bila Route error, semua channel dipakai agar alert tidak hilang.
+	Route func(ctx context.Context, r *domain.AnomalyReport) ([]string, error)
+}
+
+func NewMultiNotifier(timeout time.Duration) *MultiNotifier {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &MultiNotifier{Timeout: timeout}
+}
+
+// Add mendaftarkan channel; nama dipakai oleh aturan routing.
+func (m *MultiNotifier) Add(name string, n Notifier) {
+	m.channels = append(m.channels, channel{name: name, notifier: n})
+}
+
+// Channels mengembalikan nama channel sesuai urutan pendaftaran.
+func (m *MultiNotifier) Channels() []string {
+	names := make([]string, len(m.channels))
+	for i, c := range m.channels {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (m *MultiNotifier) Send(report *domain.AnomalyReport) error {
+	return m.NotifyAnomaly(context.Background(), report)
+}
+
+// NotifyAnomaly menunggu semua channel terpilih selesai (atau habis waktu) dan
+// menggabungkan error-nya sebagai *ChannelError.
+func (m *MultiNotifier) NotifyAnomaly(ctx context.Context, r *domain.AnomalyReport) error {
+	selected := m.channels
+	if m.Route != nil {
+		names, err := m.Route(ctx, r)
+		if err != nil {
+			log.Printf("MultiNotifier: routing gagal, kirim ke semua channel: %v", err)
+		} else if names != nil {
+			selected = m.pick(names)
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(selected))
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func(i int, c channel) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(withChannel(ctx, c.name), m.Timeout)
+			defer cancel()
+			errs[i] = m.notify(cctx, c, r)
+		}(i, c)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// notify menjalankan satu channel; hasilnya diabaikan bila melewati batas waktu,
+// sehingga channel yang tidak menghormati ctx tetap tidak menahan pemanggil.
+func (m *MultiNotifier) notify(ctx context.Context, c channel, r *domain.AnomalyReport) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- c.notifier.NotifyAnomaly(ctx, r)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return &ChannelError{Channel: c.name, Err: err}
+		}
+		return nil
+	case <-ctx.Done():
+		return &ChannelError{Channel: c.name, Err: ctx.Err()}
+	}
+}
+
+func (m *MultiNotifier) pick(names []string) []channel {
+	var out []channel
+	for _, c := range m.channels {
+		for _, n := range names {
+			if c.name == n {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
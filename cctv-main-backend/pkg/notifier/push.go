@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"fmt"
+	"time"
+)
+
+// pushData menyusun data push (FCM, APNs, maupun push-service) untuk laporan;
+// judul dan isi disusun PushTemplates sesuai bahasa penerima.
+func pushData(r *domain.AnomalyReport) map[string]string {
+	data := map[string]string{
+		"type":         "anomaly",
+		"anomaly_id":   fmt.Sprintf("%d", r.ID),
+		"camera_id":    fmt.Sprintf("%d", r.CameraID),
+		"confidence":   fmt.Sprintf("%.3f", r.Confidence),
+		"video_url":    r.VideoClipURL,
+		"anomaly_type": r.AnomalyType,
+		"deeplink":     fmt.Sprintf("app://camera/%d/anomaly", r.CameraID),
+	}
+	if r.AnomalyType == domain.AnomalyTypeCameraOffline {
+		data["type"] = "camera_offline"
+		data["deeplink"] = fmt.Sprintf("app://camera/%d", r.CameraID)
+		delete(data, "anomaly_id")
+		delete(data, "confidence")
+		delete(data, "video_url")
+		data["since"] = r.ReportedAt.UTC().Format(time.RFC3339)
+	}
+	return data
+}
@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/push"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Push mengirim langsung ke perangkat lewat transport per provider
+// (push.FCM, push.APNs, atau push.Sink untuk pengujian).
+type Push struct {
+	Senders map[string]push.Sender // key: domain.PushProviderFCM / PushProviderAPNs
+	// UseTopic mengirim ke topic FCM <TopicPrefix>-camera-<id>. Topic sampai ke semua
+	// subscriber tanpa melihat preferensi, jam tenang, akses kamera, maupun bahasa,
+	// jadi hanya dipakai bila GetRecipientTargets tidak diisi.
+	UseTopic    bool
+	TopicPrefix string // ex: "alerts"
+
+	// GetRecipientTargets memilih token perangkat sesuai preferensi notifikasi tiap user.
+	GetRecipientTargets    func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.PushTarget, error)
+	GetCompanyIDByCameraID func(ctx context.Context, cameraID int64) (int64, error)
+	DeleteToken            func(ctx context.Context, token string) error
+	// GetCamera (opsional) mengisi nama dan lokasi kamera di isi notifikasi.
+	GetCamera func(ctx context.Context, cameraID int64) (*domain.Camera, error)
+
+	Templates *PushTemplates
+}
+
+func NewPush(senders map[string]push.Sender) *Push {
+	return &Push{Senders: senders, TopicPrefix: "alerts", Templates: defaultPushTemplates()}
+}
+
+func (p *Push) Send(report *domain.AnomalyReport) error {
+	return p.NotifyAnomaly(context.Background(), report)
+}
+
+func (p *Push) NotifyAnomaly(ctx context.Context, r *domain.AnomalyReport) error {
+	content := pushContent(ctx, r, p.GetCamera)
+	data := pushData(r)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Topic mode (opsional, hanya transport yang mendukung topic)
+	topics, _ := p.Senders[domain.PushProviderFCM].(push.TopicSender)
+	if p.UseTopic && topics != nil && p.GetRecipientTargets == nil {
+		// Topic tidak tahu bahasa penerimanya: pakai bahasa default.
+		title, body := p.Templates.Render(domain.DefaultLocale, content)
+		topic := fmt.Sprintf("%s-camera-%d", p.TopicPrefix, r.CameraID)
+		err := topics.SendTopic(ctx, topic, push.Message{Title: title, Body: body, Data: data})
+		record(ctx, "topic:"+topic, err)
+		return err
+	}
+	if p.GetRecipientTargets == nil {
+		return errors.New("GetRecipientTargets nil")
+	}
+
+	// Map camera -> company; bila gagal outbox mencoba ulang, tidak jatuh ke topic.
+	companyID := r.CompanyID
+	if companyID == 0 && p.GetCompanyIDByCameraID != nil {
+		id, err := p.GetCompanyIDByCameraID(ctx, r.CameraID)
+		if err != nil {
+			return fmt.Errorf("map camera->company: %w", err)
+		}
+		companyID = id
+	}
+	targets, err := p.GetRecipientTargets(ctx, companyID, r)
+	if err != nil {
+		return err
+	}
+
+	// Kelompokkan per provider dan bahasa; token yang sudah terkirim pada percobaan sebelumnya dilewati.
+	type group struct{ provider, locale string }
+	groups := map[group][]string{}
+	for _, t := range targets {
+		if !alreadySent(ctx, t.Token) {
+			g := group{t.Provider, t.Locale}
+			groups[g] = append(groups[g], t.Token)
+		}
+	}
+
+	success, failure, retryable := 0, 0, 0
+	for g, tokens := range groups {
+		provider := g.provider
+		sender := p.Senders[provider]
+		if sender == nil {
+			// Tanpa transport untuk provider ini mengulang tidak akan membantu.
+			log.Printf("push: transport %q tidak dikonfigurasi, %d token dilewati", provider, len(tokens))
+			for _, t := range tokens {
+				record(ctx, t, Final(fmt.Errorf("transport %s tidak dikonfigurasi", provider)))
+			}
+			continue
+		}
+		title, body := p.Templates.Render(g.locale, content)
+		msg := push.Message{Title: title, Body: body, Data: data}
+		for _, res := range sender.Send(ctx, tokens, msg) {
+			if res.Status == push.StatusSuccess {
+				record(ctx, res.Token, nil)
+				success++
+				continue
+			}
+			failure++
+			log.Printf("push: %s token gagal (%s): %v", provider, res.Status, res.Err)
+			switch res.Status {
+			case push.StatusRetryable:
+				// Hanya gangguan sementara yang membuat outbox dicoba ulang.
+				record(ctx, res.Token, res.Err)
+				retryable++
+			case push.StatusInvalid:
+				record(ctx, res.Token, Final(res.Err))
+				// Clean invalid/unregistered tokens
+				if p.DeleteToken != nil {
+					if delErr := p.DeleteToken(ctx, res.Token); delErr != nil {
+						log.Printf("push: failed to clean token: %v", delErr)
+					} else {
+						log.Printf("push: token cleaned")
+					}
+				}
+			default:
+				// failed: payload atau kredensial ditolak.
+				record(ctx, res.Token, Final(res.Err))
+			}
+		}
+	}
+	if success+failure > 0 {
+		log.Printf("push: sent to %d tokens → success=%d failure=%d", success+failure, success, failure)
+	}
+	if retryable > 0 {
+		return fmt.Errorf("push: %d token gagal dikirim", retryable)
+	}
+	return nil
+}
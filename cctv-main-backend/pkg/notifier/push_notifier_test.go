@@ -0,0 +1,47 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/push"
+	"context"
+	"testing"
+)
+
+func TestPushDirectSink(t *testing.T) {
+	sink := push.NewSink("")
+	p := NewPush(map[string]push.Sender{domain.PushProviderFCM: sink})
+	p.UseTopic = true // diabaikan karena penerima dipilih per user
+	p.GetRecipientTargets = func(ctx context.Context, companyID int64, r *domain.AnomalyReport) ([]domain.PushTarget, error) {
+		return []domain.PushTarget{
+			{Token: "android-1", Provider: domain.PushProviderFCM, Locale: "en"},
+			{Token: "invalid-1", Provider: domain.PushProviderFCM, Locale: "id"},
+			{Token: "iphone-1", Provider: domain.PushProviderAPNs, Locale: "id"},
+		}, nil
+	}
+	var deleted []string
+	p.DeleteToken = func(ctx context.Context, token string) error {
+		deleted = append(deleted, token)
+		return nil
+	}
+
+	tr := &memTracker{errs: map[string]error{}}
+	if err := p.NotifyAnomaly(WithTracker(context.Background(), tr), testReport(10)); err != nil {
+		t.Fatalf("NotifyAnomaly: %v", err)
+	}
+
+	msgs := sink.Messages()
+	if len(msgs) != 1 || msgs[0].Token != "android-1" {
+		t.Fatalf("sink messages = %+v, want only android-1", msgs)
+	}
+	if len(deleted) != 1 || deleted[0] != "invalid-1" {
+		t.Fatalf("deleted tokens = %v, want [invalid-1]", deleted)
+	}
+	if err := tr.errs["android-1"]; err != nil {
+		t.Fatalf("android-1 recorded error %v", err)
+	}
+	for _, token := range []string{"invalid-1", "iphone-1"} {
+		if err := tr.errs[token]; !IsFinal(err) {
+			t.Fatalf("%s recorded %v, want a final error", token, err)
+		}
+	}
+}
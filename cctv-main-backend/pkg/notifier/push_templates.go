@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"bytes"
+	"cctv-main-backend/internal/domain"
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PushContent adalah data satu laporan yang tersedia di template push.
+type PushContent struct {
+	AnomalyID   int64
+	CameraID    int64
+	CameraName  string // kosong bila kamera tidak ditemukan
+	Location    string
+	AnomalyType string
+	TypeLabel   string // label tipe sesuai bahasa penerima
+	Confidence  float64
+	Time        time.Time // waktu kejadian di zona APP_TZ
+}
+
+// PushTemplates menyusun judul dan isi push per bahasa dari template
+// templates/push/<locale>.tmpl. Tiap file mendefinisikan anomaly.title,
+// anomaly.body, camera_offline.title, camera_offline.body, dan label
+// type.<anomaly_type> (opsional).
+type PushTemplates struct {
+	loc  *time.Location
+	sets map[string]*texttemplate.Template // key: domain.Locale*
+}
+
+// NewPushTemplates memuat template bawaan, atau <dir>/id.tmpl dan <dir>/en.tmpl bila dir diisi.
+func NewPushTemplates(dir string, loc *time.Location) (*PushTemplates, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	fsys := os.DirFS(dir)
+	if dir == "" {
+		fsys, _ = fs.Sub(defaultTemplates, "templates/push")
+	}
+	funcs := map[string]any{
+		"percent": func(c float64) string { return fmt.Sprintf("%.0f%%", c*100) },
+	}
+	p := &PushTemplates{loc: loc, sets: map[string]*texttemplate.Template{}}
+	sample := PushContent{CameraID: 1, CameraName: "CAM", Location: "Lobi", TypeLabel: "X", Time: time.Now().In(loc)}
+	for _, locale := range domain.Locales {
+		t, err := texttemplate.New(locale).Funcs(funcs).ParseFS(fsys, locale+".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("parse template push %s: %w", locale, err)
+		}
+		// Periksa sekali saat start agar kesalahan template tidak baru ketahuan saat ada alert.
+		for _, name := range []string{"anomaly.title", "anomaly.body", "camera_offline.title", "camera_offline.body"} {
+			if t.Lookup(name) == nil {
+				return nil, fmt.Errorf("template push %s: %q tidak didefinisikan", locale, name)
+			}
+			if err := t.ExecuteTemplate(&bytes.Buffer{}, name, sample); err != nil {
+				return nil, fmt.Errorf("template push %s: %w", locale, err)
+			}
+		}
+		p.sets[locale] = t
+	}
+	return p, nil
+}
+
+// defaultPushTemplates dipakai notifier yang belum diberi template (zona UTC).
+func defaultPushTemplates() *PushTemplates {
+	p, err := NewPushTemplates("", time.UTC)
+	if err != nil {
+		panic(err) // template bawaan di-embed; error berarti bug
+	}
+	return p
+}
+
+// Render mengembalikan judul dan isi push untuk bahasa locale; bahasa yang
+// tidak dikenal memakai domain.DefaultLocale.
+func (p *PushTemplates) Render(locale string, c PushContent) (title, body string) {
+	t, ok := p.sets[locale]
+	if !ok {
+		t = p.sets[domain.DefaultLocale]
+	}
+	c.Time = c.Time.In(p.loc)
+	c.TypeLabel = typeLabel(t, c.AnomalyType)
+
+	kind := "anomaly"
+	if c.AnomalyType == domain.AnomalyTypeCameraOffline {
+		kind = "camera_offline"
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, kind+".title", c); err != nil {
+		log.Printf("template push %s: %v", t.Name(), err)
+	}
+	title = buf.String()
+	buf.Reset()
+	if err := t.ExecuteTemplate(&buf, kind+".body", c); err != nil {
+		log.Printf("template push %s: %v", t.Name(), err)
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(buf.String())
+}
+
+// typeLabel memakai template type.<tipe> bila ada, selain itu tipe dengan
+// garis bawah diganti spasi dan huruf pertama kapital.
+func typeLabel(t *texttemplate.Template, anomalyType string) string {
+	if lt := t.Lookup("type." + anomalyType); lt != nil {
+		var buf bytes.Buffer
+		if lt.Execute(&buf, nil) == nil && buf.Len() > 0 {
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	s := strings.ReplaceAll(anomalyType, "_", " ")
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// pushContent mengisi data template dari laporan dan (bila ada) data kamera.
+func pushContent(ctx context.Context, r *domain.AnomalyReport, getCamera func(ctx context.Context, cameraID int64) (*domain.Camera, error)) PushContent {
+	c := PushContent{
+		AnomalyID:   r.ID,
+		CameraID:    r.CameraID,
+		AnomalyType: r.AnomalyType,
+		Confidence:  r.Confidence,
+		Time:        r.ReportedAt,
+	}
+	if c.Time.IsZero() {
+		c.Time = time.Now()
+	}
+	if getCamera != nil {
+		if cam, err := getCamera(ctx, r.CameraID); err == nil && cam != nil {
+			c.CameraName = cam.Name
+			c.Location = cam.Location
+		}
+	}
+	return c
+}
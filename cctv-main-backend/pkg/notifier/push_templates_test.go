@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestPushTemplatesRender(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60) // APP_TZ=Asia/Jakarta
+	p, err := NewPushTemplates("", wib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 5, 3, 30, 0, 0, time.UTC) // 10:30 WIB
+	named := PushContent{CameraID: 7, CameraName: "Gerbang Utama", Location: "Lobi", AnomalyType: "fire", Confidence: 0.87, Time: at}
+	unnamed := PushContent{CameraID: 7, AnomalyType: "fire", Confidence: 0.87, Time: at}
+	withType := func(c PushContent, anomalyType string) PushContent {
+		c.AnomalyType = anomalyType
+		return c
+	}
+
+	tests := []struct {
+		name      string
+		locale    string
+		content   PushContent
+		wantTitle string
+		wantBody  string
+	}{
+		{"indonesia", domain.LocaleID, named, "Api terdeteksi", "Gerbang Utama (Lobi) • 87% • 05/01 10:30 WIB"},
+		{"english", domain.LocaleEN, named, "Fire detected", "Gerbang Utama (Lobi) • 87% • Jan 5 10:30 WIB"},
+		{"bahasa tidak dikenal", "fr", named, "Api terdeteksi", "Gerbang Utama (Lobi) • 87% • 05/01 10:30 WIB"},
+		{"bahasa kosong", "", named, "Api terdeteksi", "Gerbang Utama (Lobi) • 87% • 05/01 10:30 WIB"},
+		{"kamera tanpa nama", domain.LocaleID, unnamed, "Api terdeteksi", "Kamera 7 • 87% • 05/01 10:30 WIB"},
+		{"camera without name", domain.LocaleEN, unnamed, "Fire detected", "Camera 7 • 87% • Jan 5 10:30 WIB"},
+		{"tipe tanpa label", domain.LocaleEN, withType(named, "weird_thing"), "Weird thing detected", "Gerbang Utama (Lobi) • 87% • Jan 5 10:30 WIB"},
+		{"kamera offline", domain.LocaleID, withType(unnamed, domain.AnomalyTypeCameraOffline), "Kamera Offline", "Kamera 7 tidak mengirim stream sejak 05/01 10:30 WIB"},
+		{"camera offline", domain.LocaleEN, withType(named, domain.AnomalyTypeCameraOffline), "Camera Offline", "Gerbang Utama (Lobi) has not sent a stream since Jan 5 10:30 WIB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, body := p.Render(tt.locale, tt.content)
+			if title != tt.wantTitle || body != tt.wantBody {
+				t.Fatalf("Render() = %q, %q; want %q, %q", title, body, tt.wantTitle, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPushTypeLabel(t *testing.T) {
+	p := defaultPushTemplates()
+	tests := []struct {
+		locale      string
+		anomalyType string
+		want        string
+	}{
+		{domain.LocaleID, "intrusion", "Penyusup"},
+		{domain.LocaleEN, "intrusion", "Intruder"},
+		{domain.LocaleID, "model_detected", "Anomali"},
+		{domain.LocaleEN, "forced_by_filename", "Test anomaly"},
+		{domain.LocaleID, "weird_thing", "Weird thing"},
+		{domain.LocaleEN, "vandalism", "Vandalism"},
+		{domain.LocaleEN, "", ""},
+	}
+	for _, tt := range tests {
+		if got := typeLabel(p.sets[tt.locale], tt.anomalyType); got != tt.want {
+			t.Errorf("typeLabel(%s, %q) = %q, want %q", tt.locale, tt.anomalyType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+)
+
+// Tracker mencatat hasil kirim per penerima untuk satu notifikasi. Dipasang ke
+// context oleh dispatcher outbox; notifier memanggil Record untuk setiap penerima
+// dan melewati penerima yang Sent-nya true ketika notifikasi dicoba ulang.
+// Penerima yang dicatat dengan error Final juga dianggap selesai (Sent true).
+// Implementasi harus aman dipakai dari beberapa goroutine.
+type Tracker interface {
+	Sent(channel, recipient string) bool
+	Record(channel, recipient string, err error)
+}
+
+type trackerKey struct{}
+type channelKey struct{}
+
+// WithTracker memasang t ke ctx.
+func WithTracker(ctx context.Context, t Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// withChannel menandai nama channel yang sedang berjalan (diisi MultiNotifier).
+func withChannel(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, channelKey{}, name)
+}
+
+func channelOf(ctx context.Context) string {
+	if name, ok := ctx.Value(channelKey{}).(string); ok {
+		return name
+	}
+	return "default"
+}
+
+// finalError menandai kegagalan permanen untuk satu penerima.
+type finalError struct{ err error }
+
+func (e finalError) Error() string { return e.err.Error() }
+func (e finalError) Unwrap() error { return e.err }
+
+// Final menandai err sebagai kegagalan permanen (mis. token invalid, payload
+// ditolak): dicatat sebagai gagal tetapi penerimanya tidak dicoba lagi.
+func Final(err error) error {
+	if err == nil {
+		return nil
+	}
+	return finalError{err}
+}
+
+// IsFinal melaporkan apakah err ditandai dengan Final.
+func IsFinal(err error) bool {
+	var f finalError
+	return errors.As(err, &f)
+}
+
+// alreadySent melaporkan apakah recipient sudah selesai (berhasil atau gagal
+// permanen) pada percobaan sebelumnya.
+func alreadySent(ctx context.Context, recipient string) bool {
+	t, ok := ctx.Value(trackerKey{}).(Tracker)
+	return ok && t.Sent(channelOf(ctx), recipient)
+}
+
+// record meneruskan hasil kirim ke Tracker bila ada.
+func record(ctx context.Context, recipient string, err error) {
+	if t, ok := ctx.Value(trackerKey{}).(Tracker); ok {
+		t.Record(channelOf(ctx), recipient, err)
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"cctv-main-backend/internal/domain"
+	"cctv-main-backend/pkg/events"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// webhookQueueRecipient adalah penerima tunggal channel webhook di Tracker;
+// pengiriman ke tiap endpoint dicatat di log webhook.
+const webhookQueueRecipient = "queue"
+
+// WebhookNotifier mengantrekan event anomaly.created ke webhook perusahaan.
+// Pengiriman HTTP (tanda tangan HMAC, retry, dead-letter) dilakukan asinkron
+// oleh dispatcher webhook, sehingga NotifyAnomaly hanya menulis ke antrean.
+type WebhookNotifier struct {
+	// Hooks dari service webhook dan repo kamera
+	Enqueue                func(ctx context.Context, companyID, cameraID int64, event string, data any) (int, error)
+	GetCompanyIDByCameraID func(ctx context.Context, cameraID int64) (int64, error)
+}
+
+func (n *WebhookNotifier) Send(report *domain.AnomalyReport) error {
+	return n.NotifyAnomaly(context.Background(), report)
+}
+
+func (n *WebhookNotifier) NotifyAnomaly(ctx context.Context, r *domain.AnomalyReport) error {
+	if n.Enqueue == nil {
+		return errors.New("dependency Enqueue nil")
+	}
+	// camera.offline sudah diantrekan ke webhook oleh monitor kamera.
+	if r.AnomalyType == domain.AnomalyTypeCameraOffline {
+		return nil
+	}
+	if alreadySent(ctx, webhookQueueRecipient) {
+		return nil
+	}
+	companyID := r.CompanyID
+	if companyID == 0 && n.GetCompanyIDByCameraID != nil {
+		id, err := n.GetCompanyIDByCameraID(ctx, r.CameraID)
+		if err != nil {
+			return fmt.Errorf("map camera->company: %w", err)
+		}
+		companyID = id
+	}
+	count, err := n.Enqueue(ctx, companyID, r.CameraID, events.AnomalyCreated, r)
+	record(ctx, webhookQueueRecipient, err)
+	if err != nil {
+		return fmt.Errorf("enqueue webhook: %w", err)
+	}
+	if count > 0 {
+		log.Printf("Webhook: anomaly %d diantrekan ke %d endpoint", r.ID, count)
+	}
+	return nil
+}
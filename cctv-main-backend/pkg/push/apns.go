@@ -0,0 +1,219 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+	// Apple menerima provider token hingga 1 jam dan menolak pembaruan lebih sering dari 20 menit.
+	apnsTokenTTL = 50 * time.Minute
+)
+
+// APNsConfig adalah konfigurasi token-based auth APNs (kunci .p8 dari Apple Developer).
+type APNsConfig struct {
+	KeyFile string
+	KeyID   string
+	TeamID  string
+	Topic   string // bundle id aplikasi iOS
+	Sandbox bool   // build development memakai gateway sandbox
+}
+
+// APNs mengirim langsung ke Apple Push Notification service lewat HTTP/2.
+// APNs menerima satu token per request, jadi token dikirim paralel sebanyak
+// Concurrency request sekaligus di atas satu koneksi HTTP/2.
+type APNs struct {
+	cfg     APNsConfig
+	key     *ecdsa.PrivateKey
+	baseURL string
+	HTTP    *http.Client
+
+	Concurrency int
+
+	mu      sync.Mutex
+	jwt     string
+	jwtTime time.Time
+}
+
+func NewAPNs(cfg APNsConfig) (*APNs, error) {
+	if cfg.KeyFile == "" || cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, errors.New("APNs butuh key file, key id, team id, dan topic")
+	}
+	raw, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("kunci APNs bukan PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse kunci APNs: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("kunci APNs harus ECDSA P-256")
+	}
+	baseURL := apnsProductionURL
+	if cfg.Sandbox {
+		baseURL = apnsSandboxURL
+	}
+	return &APNs{
+		cfg:     cfg,
+		key:     key,
+		baseURL: baseURL,
+		HTTP: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				ForceAttemptHTTP2: true,
+				TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12},
+			},
+		},
+		Concurrency: 10,
+	}, nil
+}
+
+func (a *APNs) Send(ctx context.Context, tokens []string, msg Message) []Result {
+	results := make([]Result, len(tokens))
+	payload, err := apnsPayload(msg)
+	if err != nil {
+		for i, t := range tokens {
+			results[i] = Result{Token: t, Status: StatusFailed, Err: err}
+		}
+		return results
+	}
+	sem := make(chan struct{}, max(a.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, t := range tokens {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.sendOne(ctx, t, payload)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// apnsPayload: notifikasi di "aps", data aplikasi sebagai key tingkat atas.
+func apnsPayload(msg Message) ([]byte, error) {
+	body := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			body[k] = v
+		}
+	}
+	return json.Marshal(body)
+}
+
+func (a *APNs) sendOne(ctx context.Context, token string, payload []byte) Result {
+	res := Result{Token: token}
+	bearer, err := a.providerToken()
+	if err != nil {
+		res.Status, res.Err = StatusFailed, err
+		return res
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+url.PathEscape(token), bytes.NewReader(payload))
+	if err != nil {
+		res.Status, res.Err = StatusInvalid, err
+		return res
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := a.HTTP.Do(req)
+	if err != nil {
+		res.Status, res.Err = StatusRetryable, err
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		res.Status, res.MessageID = StatusSuccess, resp.Header.Get("apns-id")
+		return res
+	}
+	var apiErr struct {
+		Reason string `json:"reason"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	_ = json.Unmarshal(b, &apiErr)
+	res.Err = fmt.Errorf("apns %d: %s", resp.StatusCode, apiErr.Reason)
+	res.Status = classifyAPNs(resp.StatusCode, apiErr.Reason)
+	if apiErr.Reason == "ExpiredProviderToken" {
+		a.resetToken(bearer)
+	}
+	return res
+}
+
+// classifyAPNs memetakan status HTTP dan reason APNs ke status token.
+func classifyAPNs(code int, reason string) string {
+	switch {
+	case code == http.StatusGone, reason == "BadDeviceToken", reason == "DeviceTokenNotForTopic":
+		return StatusInvalid
+	case reason == "ExpiredProviderToken", code == http.StatusTooManyRequests, code >= 500:
+		return StatusRetryable
+	}
+	return StatusFailed
+}
+
+// providerToken mengembalikan JWT ES256 untuk header authorization, dibuat ulang setiap apnsTokenTTL.
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.jwt != "" && time.Since(a.jwtTime) < apnsTokenTTL {
+		return a.jwt, nil
+	}
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": a.cfg.KeyID})
+	claims, _ := json.Marshal(map[string]any{"iss": a.cfg.TeamID, "iat": now.Unix()})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, sum[:])
+	if err != nil {
+		return "", err
+	}
+	// Signature JWS ES256: r || s, masing-masing 32 byte.
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	a.jwt, a.jwtTime = unsigned+"."+enc.EncodeToString(sig), now
+	return a.jwt, nil
+}
+
+// resetToken membuang JWT yang ditolak agar request berikutnya membuat yang baru.
+func (a *APNs) resetToken(rejected string) {
+	a.mu.Lock()
+	if a.jwt == rejected {
+		a.jwt = ""
+	}
+	a.mu.Unlock()
+}
@@ -0,0 +1,97 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"google.golang.org/api/option"
+)
+
+// fcmMaxBatch adalah batas token per multicast FCM.
+const fcmMaxBatch = 500
+
+// FCM mengirim lewat Firebase Cloud Messaging (multicast per 500 token).
+type FCM struct {
+	client *messaging.Client
+}
+
+func NewFCM(ctx context.Context, credPath string) (*FCM, error) {
+	if credPath == "" {
+		credPath = os.Getenv("FIREBASE_CREDENTIALS")
+	}
+	if credPath == "" {
+		return nil, errors.New("FIREBASE_CREDENTIALS tidak diset")
+	}
+	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(credPath))
+	if err != nil {
+		return nil, err
+	}
+	mc, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &FCM{client: mc}, nil
+}
+
+func (f *FCM) Send(ctx context.Context, tokens []string, msg Message) []Result {
+	results := make([]Result, 0, len(tokens))
+	for start := 0; start < len(tokens); start += fcmMaxBatch {
+		batch := tokens[start:min(start+fcmMaxBatch, len(tokens))]
+		resp, err := f.client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+			Tokens:       batch,
+			Notification: &messaging.Notification{Title: msg.Title, Body: msg.Body},
+			Data:         msg.Data,
+			Android:      &messaging.AndroidConfig{Priority: "high"},
+		})
+		for i, t := range batch {
+			res := Result{Token: t}
+			switch {
+			case err != nil:
+				res.Err = err
+			case i >= len(resp.Responses) || resp.Responses[i] == nil:
+				res.Err = errors.New("respons FCM tidak lengkap")
+			case resp.Responses[i].Success:
+				res.Status = StatusSuccess
+				res.MessageID = resp.Responses[i].MessageID
+			default:
+				res.Err = resp.Responses[i].Error
+			}
+			if res.Err != nil {
+				res.Status = classifyFCM(res.Err)
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+func (f *FCM) SendTopic(ctx context.Context, topic string, msg Message) error {
+	_, err := f.client.Send(ctx, &messaging.Message{
+		Topic:        topic,
+		Notification: &messaging.Notification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	})
+	return err
+}
+
+// classifyFCM memetakan error FCM ke status token.
+func classifyFCM(err error) string {
+	switch {
+	case messaging.IsUnregistered(err), messaging.IsSenderIDMismatch(err):
+		return StatusInvalid
+	case messaging.IsInvalidArgument(err):
+		// INVALID_ARGUMENT juga dipakai untuk payload salah; hanya token rusak yang invalid.
+		if strings.Contains(strings.ToLower(err.Error()), "registration token") {
+			return StatusInvalid
+		}
+		return StatusFailed
+	case messaging.IsThirdPartyAuthError(err), messaging.IsMismatchedCredential(err):
+		return StatusFailed
+	}
+	// unavailable, internal, quota, atau error jaringan tanpa kode FCM
+	return StatusRetryable
+}
@@ -0,0 +1,40 @@
+// Package push berisi transport push notification yang bisa ditukar: FCM,
+// APNs langsung (HTTP/2), dan Sink yang hanya merekam pesan untuk pengujian
+// dan pengembangan lokal tanpa kredensial Firebase/Apple.
+package push
+
+import "context"
+
+// Status hasil kirim per token (sama dengan respons cctv-push-service).
+const (
+	StatusSuccess   = "success"
+	StatusInvalid   = "invalid"   // token tidak terdaftar/rusak: hapus dari database
+	StatusRetryable = "retryable" // gangguan sementara, boleh dikirim ulang
+	StatusFailed    = "failed"    // error permanen yang bukan karena token (payload, kredensial)
+)
+
+// Message adalah isi satu push notification.
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Result adalah hasil kirim ke satu token.
+type Result struct {
+	Token     string
+	Status    string
+	MessageID string
+	Err       error
+}
+
+// Sender mengirim pesan ke sekumpulan token perangkat.
+type Sender interface {
+	// Send mengembalikan satu Result per token dengan urutan yang sama.
+	Send(ctx context.Context, tokens []string, msg Message) []Result
+}
+
+// TopicSender diimplementasikan transport yang mendukung kirim ke topic (FCM).
+type TopicSender interface {
+	SendTopic(ctx context.Context, topic string, msg Message) error
+}
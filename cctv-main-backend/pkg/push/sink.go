@@ -0,0 +1,108 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SinkMessage adalah satu pesan yang direkam Sink.
+type SinkMessage struct {
+	ID     string            `json:"id"`
+	Token  string            `json:"token"` // "topic:<nama>" untuk kirim ke topic
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Data   map[string]string `json:"data,omitempty"`
+	SentAt time.Time         `json:"sent_at"`
+}
+
+// Sink pengganti FCM/APNs tanpa jaringan: pesan disimpan di memori dan, bila
+// Path diisi, ditambahkan ke file JSON Lines. Token berawalan "invalid"
+// dilaporkan StatusInvalid untuk menguji pembersihan token.
+type Sink struct {
+	Path string
+	Max  int // jumlah pesan di memori; yang terlama dibuang
+
+	mu   sync.Mutex
+	seq  int
+	msgs []SinkMessage
+}
+
+func NewSink(path string) *Sink {
+	return &Sink{Path: path, Max: 1000}
+}
+
+func (s *Sink) Send(ctx context.Context, tokens []string, msg Message) []Result {
+	results := make([]Result, len(tokens))
+	for i, t := range tokens {
+		if strings.HasPrefix(t, "invalid") {
+			results[i] = Result{Token: t, Status: StatusInvalid, Err: fmt.Errorf("sink: token %q tidak terdaftar", t)}
+			continue
+		}
+		id, err := s.record(t, msg)
+		if err != nil {
+			results[i] = Result{Token: t, Status: StatusRetryable, Err: err}
+			continue
+		}
+		results[i] = Result{Token: t, Status: StatusSuccess, MessageID: id}
+	}
+	return results
+}
+
+func (s *Sink) SendTopic(ctx context.Context, topic string, msg Message) error {
+	_, err := s.record("topic:"+topic, msg)
+	return err
+}
+
+// Messages mengembalikan salinan pesan yang terekam, terlama lebih dulu.
+func (s *Sink) Messages() []SinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SinkMessage(nil), s.msgs...)
+}
+
+// Reset menghapus pesan di memori (file tidak diubah).
+func (s *Sink) Reset() {
+	s.mu.Lock()
+	s.msgs = nil
+	s.mu.Unlock()
+}
+
+func (s *Sink) record(token string, msg Message) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	m := SinkMessage{
+		ID:     fmt.Sprintf("sink-%d", s.seq),
+		Token:  token,
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Data:   msg.Data,
+		SentAt: time.Now().UTC(),
+	}
+	if s.Path != "" {
+		if err := appendJSONLine(s.Path, m); err != nil {
+			return "", err
+		}
+	}
+	s.msgs = append(s.msgs, m)
+	if s.Max > 0 && len(s.msgs) > s.Max {
+		s.msgs = s.msgs[len(s.msgs)-s.Max:]
+	}
+	log.Printf("push sink: %s → %q", m.ID, m.Title)
+	return m.ID, nil
+}
+
+func appendJSONLine(path string, v any) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(v)
+}
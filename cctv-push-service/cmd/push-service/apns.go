@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+	// Apple accepts a provider token for up to an hour and rejects refreshing it more often than every 20 minutes.
+	apnsTokenTTL = 50 * time.Minute
+)
+
+type apnsConfig struct {
+	KeyFile string // .p8 signing key from the Apple Developer account
+	KeyID   string
+	TeamID  string
+	Topic   string // bundle id of the iOS app
+	Sandbox bool   // development builds use the sandbox gateway
+}
+
+// apnsTransport sends directly to the Apple Push Notification service over
+// HTTP/2 with token-based auth. APNs takes one device token per request, so
+// the tokens of a batch are sent as parallel streams on one connection.
+type apnsTransport struct {
+	cfg     apnsConfig
+	key     *ecdsa.PrivateKey
+	baseURL string
+	client  *http.Client
+
+	mu      sync.Mutex
+	jwt     string
+	jwtTime time.Time
+}
+
+func newAPNsTransport(cfg apnsConfig) (*apnsTransport, error) {
+	if cfg.KeyFile == "" || cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, errors.New("APNs needs APNS_KEY_FILE, APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC")
+	}
+	raw, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("APNs key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("APNs key must be an ECDSA P-256 key")
+	}
+	baseURL := apnsProductionURL
+	if cfg.Sandbox {
+		baseURL = apnsSandboxURL
+	}
+	return &apnsTransport{
+		cfg:     cfg,
+		key:     key,
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				ForceAttemptHTTP2: true,
+				TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12},
+			},
+		},
+	}, nil
+}
+
+// maxBatch only bounds the number of parallel streams; APNs has no batch API.
+func (a *apnsTransport) maxBatch() int { return 100 }
+
+func (a *apnsTransport) sendBatch(ctx context.Context, tokens []string, n notification) []tokenResult {
+	results := make([]tokenResult, len(tokens))
+	payload, err := apnsPayload(n)
+	if err != nil {
+		for i, t := range tokens {
+			results[i] = tokenResult{Token: t, Status: statusFailed, Error: err.Error()}
+		}
+		return results
+	}
+	var wg sync.WaitGroup
+	for i, t := range tokens {
+		wg.Add(1)
+		go func(i int, t string) {
+			defer wg.Done()
+			results[i] = a.sendOne(ctx, t, payload)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// apnsPayload puts the alert under "aps" and the app data as top-level keys.
+func apnsPayload(n notification) ([]byte, error) {
+	body := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": n.Title, "body": n.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range n.Data {
+		if k != "aps" {
+			body[k] = v
+		}
+	}
+	return json.Marshal(body)
+}
+
+func (a *apnsTransport) sendOne(ctx context.Context, token string, payload []byte) tokenResult {
+	res := tokenResult{Token: token}
+	bearer, err := a.providerToken()
+	if err != nil {
+		res.Status, res.Error = statusFailed, err.Error()
+		return res
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+url.PathEscape(token), bytes.NewReader(payload))
+	if err != nil {
+		res.Status, res.Error = statusInvalid, err.Error()
+		return res
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		res.Status, res.Error = statusRetryable, err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		res.Status, res.MessageID = statusSuccess, resp.Header.Get("apns-id")
+		return res
+	}
+	var apiErr struct {
+		Reason string `json:"reason"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	_ = json.Unmarshal(b, &apiErr)
+	res.Error = fmt.Sprintf("apns %d: %s", resp.StatusCode, apiErr.Reason)
+	res.Status = classifyAPNs(resp.StatusCode, apiErr.Reason)
+	if apiErr.Reason == "ExpiredProviderToken" {
+		a.resetToken(bearer)
+	}
+	return res
+}
+
+// classifyAPNs maps an APNs HTTP status and reason to a token status.
+func classifyAPNs(code int, reason string) string {
+	switch {
+	case code == http.StatusGone, reason == "BadDeviceToken", reason == "DeviceTokenNotForTopic":
+		return statusInvalid
+	case reason == "ExpiredProviderToken", code == http.StatusTooManyRequests, code >= 500:
+		return statusRetryable
+	}
+	return statusFailed
+}
+
+// providerToken returns the ES256 JWT for the authorization header, re-signed every apnsTokenTTL.
+func (a *apnsTransport) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.jwt != "" && time.Since(a.jwtTime) < apnsTokenTTL {
+		return a.jwt, nil
+	}
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": a.cfg.KeyID})
+	claims, _ := json.Marshal(map[string]any{"iss": a.cfg.TeamID, "iat": now.Unix()})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, sum[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS ES256 signature: r || s, 32 bytes each.
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	a.jwt, a.jwtTime = unsigned+"."+enc.EncodeToString(sig), now
+	return a.jwt, nil
+}
+
+// resetToken drops a rejected JWT so the next request signs a new one.
+func (a *apnsTransport) resetToken(rejected string) {
+	a.mu.Lock()
+	if a.jwt == rejected {
+		a.jwt = ""
+	}
+	a.mu.Unlock()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Per-token outcome reported back to the backend.
+const (
+	statusSuccess   = "success"
+	statusInvalid   = "invalid"   // token unregistered/malformed: the backend should delete it
+	statusRetryable = "retryable" // transient error still failing after all retries
+	statusFailed    = "failed"    // permanent error unrelated to the token (payload, credentials)
+)
+
+type tokenResult struct {
+	Token     string `json:"token"`
+	Status    string `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// notification is the content sent to every token of a request.
+type notification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// transport delivers one batch of tokens to a push provider (FCM, APNs, sink).
+type transport interface {
+	// sendBatch returns one classified result per token, in order.
+	sendBatch(ctx context.Context, tokens []string, n notification) []tokenResult
+	// maxBatch is the largest batch the provider accepts in one call.
+	maxBatch() int
+}
+
+// batchSender splits tokens into batches, sends up to Concurrency batches at
+// once and retries transient failures with exponential backoff. Transports
+// send the tokens of a batch in parallel, so at most BatchSize*Concurrency
+// requests are in flight.
+type batchSender struct {
+	t           transport
+	BatchSize   int
+	Concurrency int
+	MaxRetries  int
+	Backoff     time.Duration // first retry delay, doubled per attempt
+	MaxBackoff  time.Duration
+}
+
+func newBatchSender(t transport) *batchSender {
+	return &batchSender{
+		t:           t,
+		BatchSize:   100,
+		Concurrency: 4,
+		MaxRetries:  2,
+		Backoff:     500 * time.Millisecond,
+		MaxBackoff:  4 * time.Second,
+	}
+}
+
+// send delivers n to every token and returns one result per token, in order.
+func (b *batchSender) send(ctx context.Context, tokens []string, n notification) []tokenResult {
+	results := make([]tokenResult, len(tokens))
+	size := b.BatchSize
+	if limit := b.t.maxBatch(); size <= 0 || size > limit {
+		size = limit
+	}
+	workers := b.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for start := 0; start < len(tokens); start += size {
+		end := min(start+size, len(tokens))
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(out []tokenResult, batch []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.sendBatch(ctx, batch, n, out)
+		}(results[start:end], tokens[start:end])
+	}
+	wg.Wait()
+	return results
+}
+
+// sendBatch sends one batch and resends the tokens that failed with a
+// transient error until they succeed, MaxRetries is reached or ctx expires.
+func (b *batchSender) sendBatch(ctx context.Context, batch []string, n notification, out []tokenResult) {
+	pending := make([]int, len(batch))
+	for i := range batch {
+		pending[i] = i
+	}
+	delay := b.Backoff
+	for attempt := 0; ; attempt++ {
+		tokens := make([]string, len(pending))
+		for i, idx := range pending {
+			tokens[i] = batch[idx]
+		}
+		results := b.t.sendBatch(ctx, tokens, n)
+
+		var retry []int
+		for i, idx := range pending {
+			if i >= len(results) {
+				out[idx] = tokenResult{Token: batch[idx], Status: statusRetryable, Error: "missing result from transport"}
+			} else {
+				out[idx] = results[i]
+			}
+			if out[idx].Status == statusRetryable {
+				retry = append(retry, idx)
+			}
+		}
+
+		if len(retry) == 0 || attempt >= b.MaxRetries || !sleep(ctx, delay) {
+			return
+		}
+		pending = retry
+		delay = min(delay*2, b.MaxBackoff)
+	}
+}
+
+// sleep waits d unless ctx ends first or its deadline would pass during the wait.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport answers each token with status(token, attempt), where attempt
+// counts how many times that token has been sent (starting at 1).
+type fakeTransport struct {
+	limit  int
+	status func(token string, attempt int) string
+	drop   map[string]bool // tokens whose result is omitted from the response
+
+	mu       sync.Mutex
+	batches  [][]string
+	attempts map[string]int
+}
+
+func newFakeTransport(limit int, status func(token string, attempt int) string) *fakeTransport {
+	return &fakeTransport{limit: limit, status: status, attempts: map[string]int{}}
+}
+
+func (f *fakeTransport) maxBatch() int { return f.limit }
+
+func (f *fakeTransport) sendBatch(ctx context.Context, tokens []string, n notification) []tokenResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]string(nil), tokens...))
+	var out []tokenResult
+	for _, t := range tokens {
+		f.attempts[t]++
+		if f.drop[t] {
+			continue
+		}
+		out = append(out, tokenResult{Token: t, Status: f.status(t, f.attempts[t]), MessageID: "m-" + t})
+	}
+	return out
+}
+
+func newTestSender(t transport) *batchSender {
+	b := newBatchSender(t)
+	b.Backoff = time.Millisecond
+	b.MaxBackoff = 2 * time.Millisecond
+	return b
+}
+
+func tokenList(n int) []string {
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("tok-%03d", i)
+	}
+	return tokens
+}
+
+func TestBatchSenderKeepsOrder(t *testing.T) {
+	tr := newFakeTransport(40, func(string, int) string { return statusSuccess })
+	b := newTestSender(tr)
+	b.BatchSize = 100 // capped at the transport's maxBatch
+	b.Concurrency = 3
+
+	tokens := tokenList(250)
+	results := b.send(context.Background(), tokens, notification{Title: "t"})
+	if len(results) != len(tokens) {
+		t.Fatalf("got %d results, want %d", len(results), len(tokens))
+	}
+	for i, res := range results {
+		if res.Token != tokens[i] || res.Status != statusSuccess || res.MessageID != "m-"+tokens[i] {
+			t.Fatalf("results[%d] = %+v, want token %s", i, res, tokens[i])
+		}
+	}
+	if len(tr.batches) != 7 {
+		t.Fatalf("sent %d batches, want 7", len(tr.batches))
+	}
+	for _, batch := range tr.batches {
+		if len(batch) > 40 {
+			t.Fatalf("batch of %d tokens exceeds maxBatch 40", len(batch))
+		}
+	}
+}
+
+func TestBatchSenderRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       func(token string, attempt int) string
+		drop         map[string]bool
+		wantStatus   map[string]string
+		wantAttempts map[string]int
+	}{
+		{
+			name: "transient error recovers",
+			status: func(token string, attempt int) string {
+				if token == "b" && attempt < 3 {
+					return statusRetryable
+				}
+				return statusSuccess
+			},
+			wantStatus:   map[string]string{"a": statusSuccess, "b": statusSuccess, "c": statusSuccess},
+			wantAttempts: map[string]int{"a": 1, "b": 3, "c": 1},
+		},
+		{
+			name: "retries exhausted",
+			status: func(token string, attempt int) string {
+				if token == "b" {
+					return statusRetryable
+				}
+				return statusSuccess
+			},
+			wantStatus:   map[string]string{"a": statusSuccess, "b": statusRetryable, "c": statusSuccess},
+			wantAttempts: map[string]int{"a": 1, "b": 3, "c": 1},
+		},
+		{
+			name: "invalid and failed are not retried",
+			status: func(token string, attempt int) string {
+				switch token {
+				case "a":
+					return statusInvalid
+				case "b":
+					return statusFailed
+				}
+				return statusSuccess
+			},
+			wantStatus:   map[string]string{"a": statusInvalid, "b": statusFailed, "c": statusSuccess},
+			wantAttempts: map[string]int{"a": 1, "b": 1, "c": 1},
+		},
+		{
+			name:         "missing result is retried",
+			status:       func(string, int) string { return statusSuccess },
+			drop:         map[string]bool{"c": true},
+			wantStatus:   map[string]string{"a": statusSuccess, "b": statusSuccess, "c": statusRetryable},
+			wantAttempts: map[string]int{"a": 1, "b": 1, "c": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newFakeTransport(100, tt.status)
+			tr.drop = tt.drop
+			b := newTestSender(tr)
+			b.MaxRetries = 2
+
+			tokens := []string{"a", "b", "c"}
+			results := b.send(context.Background(), tokens, notification{})
+			for i, res := range results {
+				if res.Token != tokens[i] {
+					t.Fatalf("results[%d].Token = %s, want %s", i, res.Token, tokens[i])
+				}
+				if want := tt.wantStatus[res.Token]; res.Status != want {
+					t.Errorf("%s: status %s, want %s", res.Token, res.Status, want)
+				}
+				if got, want := tr.attempts[res.Token], tt.wantAttempts[res.Token]; got != want {
+					t.Errorf("%s: sent %d times, want %d", res.Token, got, want)
+				}
+			}
+			// Retries only resend the tokens that are still pending.
+			for _, batch := range tr.batches[1:] {
+				for _, tok := range batch {
+					if tt.wantAttempts[tok] == 1 {
+						t.Errorf("token %s resent although it was done", tok)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBatchSenderStopsBeforeDeadline(t *testing.T) {
+	tr := newFakeTransport(100, func(string, int) string { return statusRetryable })
+	b := newTestSender(tr)
+	b.MaxRetries = 5
+	b.Backoff = time.Second // longer than the remaining deadline
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := b.send(ctx, []string{"a"}, notification{})
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("send waited %s for a backoff past the deadline", elapsed)
+	}
+	if results[0].Status != statusRetryable || tr.attempts["a"] != 1 {
+		t.Fatalf("result %+v after %d attempts, want retryable after 1", results[0], tr.attempts["a"])
+	}
+}
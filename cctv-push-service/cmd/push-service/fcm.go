@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"google.golang.org/api/option"
+)
+
+// fcmMaxBatch is the FCM limit for tokens in one multicast request.
+const fcmMaxBatch = 500
+
+// multicastClient is the part of messaging.Client used by fcmTransport.
+type multicastClient interface {
+	SendEachForMulticast(ctx context.Context, msg *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+}
+
+// fcmTransport sends through Firebase Cloud Messaging multicast.
+type fcmTransport struct {
+	mc multicastClient
+}
+
+func newFCMTransport(credPath string) (*fcmTransport, error) {
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(credPath))
+	if err != nil {
+		return nil, err
+	}
+	mc, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &fcmTransport{mc: mc}, nil
+}
+
+func (f *fcmTransport) maxBatch() int { return fcmMaxBatch }
+
+func (f *fcmTransport) sendBatch(ctx context.Context, tokens []string, n notification) []tokenResult {
+	resp, err := f.mc.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens:       tokens,
+		Notification: &messaging.Notification{Title: n.Title, Body: n.Body},
+		Data:         n.Data,
+		Android: &messaging.AndroidConfig{
+			Priority: "high",
+		},
+	})
+	results := make([]tokenResult, len(tokens))
+	for i, t := range tokens {
+		var sendErr error
+		switch {
+		case err != nil:
+			sendErr = err
+		case i >= len(resp.Responses) || resp.Responses[i] == nil:
+			sendErr = errors.New("missing response from FCM")
+		case resp.Responses[i].Success:
+			results[i] = tokenResult{Token: t, Status: statusSuccess, MessageID: resp.Responses[i].MessageID}
+			continue
+		default:
+			sendErr = resp.Responses[i].Error
+		}
+		results[i] = tokenResult{Token: t, Status: classifyFCM(sendErr), Error: sendErr.Error()}
+	}
+	return results
+}
+
+// classifyFCM maps an FCM send error to a token status.
+func classifyFCM(err error) string {
+	switch {
+	case messaging.IsUnregistered(err), messaging.IsSenderIDMismatch(err):
+		return statusInvalid
+	case messaging.IsInvalidArgument(err):
+		// INVALID_ARGUMENT is also returned for bad payloads; only a bad token makes it invalid.
+		if strings.Contains(strings.ToLower(err.Error()), "registration token") {
+			return statusInvalid
+		}
+		return statusFailed
+	case messaging.IsUnavailable(err), messaging.IsInternal(err),
+		messaging.IsQuotaExceeded(err), messaging.IsUnknown(err):
+		return statusRetryable
+	case messaging.IsThirdPartyAuthError(err), messaging.IsMismatchedCredential(err):
+		return statusFailed
+	}
+	// Network errors and timeouts carry no FCM error code.
+	return statusRetryable
+}
@@ -3,51 +3,55 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+)
 
-	firebase "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/messaging"
-	"google.golang.org/api/option"
+// Providers a token can be sent through; "tokens" in the request are FCM tokens.
+const (
+	providerFCM  = "fcm"
+	providerAPNs = "apns"
 )
 
 type requestPayload struct {
-	Tokens []string          `json:"tokens"`
-	Title  string            `json:"title"`
-	Body   string            `json:"body"`
-	Data   map[string]string `json:"data"`
+	Tokens     []string          `json:"tokens"`
+	APNsTokens []string          `json:"apns_tokens"` // raw APNs device tokens (iOS without Firebase)
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Data       map[string]string `json:"data"`
+}
+
+type sendResponse struct {
+	Sent      int           `json:"sent"`
+	Invalid   int           `json:"invalid"`
+	Retryable int           `json:"retryable"`
+	Failed    int           `json:"failed"`
+	Results   []tokenResult `json:"results"`
 }
 
 type server struct {
-	mc     *messaging.Client
-	secret string
+	senders map[string]*batchSender // per provider
+	secret  string
+	timeout time.Duration // batas waktu satu request /send, termasuk retry
 }
 
-func newServer(credPath, secret string) (*server, error) {
-	ctx := context.Background()
-	if credPath == "" {
-		credPath = os.Getenv("FIREBASE_CREDENTIALS")
-	}
-	app, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(credPath))
-	if err != nil {
-		return nil, err
-	}
-	mc, err := app.Messaging(ctx)
-	if err != nil {
-		return nil, err
+func newServer(transports map[string]transport, secret string) *server {
+	s := &server{senders: map[string]*batchSender{}, secret: secret, timeout: 8 * time.Second}
+	for provider, t := range transports {
+		s.senders[provider] = newBatchSender(t)
 	}
-	return &server{mc: mc, secret: secret}, nil
+	return s
 }
 
 func (s *server) handleSend(w http.ResponseWriter, r *http.Request) {
-	if s.secret != "" {
-		if r.Header.Get("X-Push-Secret") != s.secret {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+	if !s.authorized(w, r) {
+		return
 	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -58,59 +62,191 @@ func (s *server) handleSend(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-	if len(req.Tokens) == 0 {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"sent":0}`))
-		return
+
+	seen := map[string]bool{}
+	byProvider := map[string][]string{
+		providerFCM:  sanitizeTokens(req.Tokens, seen),
+		providerAPNs: sanitizeTokens(req.APNsTokens, seen),
 	}
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	n := notification{Title: req.Title, Body: req.Body, Data: req.Data}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
 	defer cancel()
-	// sanitize tokens: trim spaces/newlines
-	sent := 0
-	for i, t := range req.Tokens {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		resp = sendResponse{Results: []tokenResult{}}
+	)
+	total := 0
+	for provider, tokens := range byProvider {
+		if len(tokens) == 0 {
+			continue
+		}
+		total += len(tokens)
+		sender := s.senders[provider]
+		if sender == nil {
+			mu.Lock()
+			for _, t := range tokens {
+				resp.Results = append(resp.Results, tokenResult{Token: t, Status: statusFailed, Error: provider + " transport not configured"})
+			}
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(sender *batchSender, tokens []string) {
+			defer wg.Done()
+			results := sender.send(ctx, tokens, n)
+			mu.Lock()
+			resp.Results = append(resp.Results, results...)
+			mu.Unlock()
+		}(sender, tokens)
+	}
+	wg.Wait()
+
+	for _, res := range resp.Results {
+		switch res.Status {
+		case statusSuccess:
+			resp.Sent++
+		case statusInvalid:
+			resp.Invalid++
+		case statusRetryable:
+			resp.Retryable++
+		default:
+			resp.Failed++
+		}
+		if res.Status != statusSuccess {
+			log.Printf("send token failed (prefix=%q, %s): %s", prefix(res.Token), res.Status, res.Error)
+		}
+	}
+	log.Printf("sent %d/%d tokens (invalid=%d retryable=%d failed=%d)",
+		resp.Sent, total, resp.Invalid, resp.Retryable, resp.Failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.secret != "" {
+		if r.Header.Get("X-Push-Secret") != s.secret {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return false
+		}
+	}
+	return true
+}
+
+func (s *server) withSecret(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authorized(w, r) {
+			h(w, r)
+		}
+	}
+}
+
+// sanitizeTokens trims spaces/newlines and drops empty tokens and tokens already in seen.
+func sanitizeTokens(in []string, seen map[string]bool) []string {
+	var out []string
+	for i, t := range in {
 		t = strings.TrimSpace(t)
 		if t == "" {
 			log.Printf("skip empty token at index %d", i)
 			continue
 		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
 		if len(t) < 50 { // coarse check to catch obvious copy mistakes
 			log.Printf("token too short (%d chars), idx=%d, prefix=%q", len(t), i, t)
-		} else {
-			log.Printf("sending to token[%d] len=%d prefix=%q", i, len(t), t[:12])
 		}
-    msg := &messaging.Message{
-        Token:        t,
-        Notification: &messaging.Notification{Title: req.Title, Body: req.Body},
-        Data:         req.Data,
-        Android: &messaging.AndroidConfig{
-            Priority: "high",
-        },
-    }
-    id, err := s.mc.Send(ctx, msg)
-    if err != nil {
-        log.Printf("send token failed (idx=%d): %v", i, err)
-        continue
-    }
-    log.Printf("sent msg id=%s", id)
-		sent++
+		out = append(out, t)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]int{"sent": sent})
+	return out
+}
+
+func prefix(token string) string {
+	if len(token) > 12 {
+		return token[:12]
+	}
+	return token
+}
+
+func getEnvInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// transports builds the push transports from env. PUSH_SINK ("memory" or a
+// JSON Lines file path) replaces FCM and APNs for tests and local development.
+func transports() (map[string]transport, *sink, error) {
+	if path := os.Getenv("PUSH_SINK"); path != "" {
+		if path == "memory" {
+			path = ""
+		}
+		sk := newSink(path)
+		return map[string]transport{
+			providerFCM:  sinkTransport{s: sk, provider: providerFCM},
+			providerAPNs: sinkTransport{s: sk, provider: providerAPNs},
+		}, sk, nil
+	}
+	ts := map[string]transport{}
+	if cred := os.Getenv("FIREBASE_CREDENTIALS"); cred != "" {
+		t, err := newFCMTransport(cred)
+		if err != nil {
+			return nil, nil, err
+		}
+		ts[providerFCM] = t
+	}
+	if keyFile := os.Getenv("APNS_KEY_FILE"); keyFile != "" {
+		t, err := newAPNsTransport(apnsConfig{
+			KeyFile: keyFile,
+			KeyID:   os.Getenv("APNS_KEY_ID"),
+			TeamID:  os.Getenv("APNS_TEAM_ID"),
+			Topic:   os.Getenv("APNS_TOPIC"),
+			Sandbox: os.Getenv("APNS_SANDBOX") == "true",
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		ts[providerAPNs] = t
+	}
+	if len(ts) == 0 {
+		return nil, nil, errors.New("isi FIREBASE_CREDENTIALS, APNS_KEY_FILE, atau PUSH_SINK untuk push-service")
+	}
+	return ts, nil, nil
 }
 
 func main() {
 	addr := ":8090"
-	cred := os.Getenv("FIREBASE_CREDENTIALS")
-	if cred == "" {
-		log.Fatal("FIREBASE_CREDENTIALS wajib diisi untuk push-service")
-	}
-	s, err := newServer(cred, os.Getenv("PUSH_SERVICE_SECRET"))
+	ts, sk, err := transports()
 	if err != nil {
 		log.Fatal(err)
 	}
+	s := newServer(ts, os.Getenv("PUSH_SERVICE_SECRET"))
+	s.timeout = getEnvDuration("PUSH_SEND_TIMEOUT", s.timeout)
+	for provider, sender := range s.senders {
+		sender.BatchSize = getEnvInt("PUSH_BATCH_SIZE", sender.BatchSize)
+		sender.Concurrency = getEnvInt("PUSH_CONCURRENCY", sender.Concurrency)
+		sender.MaxRetries = getEnvInt("PUSH_MAX_RETRIES", sender.MaxRetries)
+		sender.Backoff = getEnvDuration("PUSH_RETRY_BACKOFF", sender.Backoff)
+		log.Printf("transport %s enabled", provider)
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/send", s.handleSend)
+	if sk != nil {
+		mux.HandleFunc("/messages", s.withSecret(sk.handleMessages))
+		log.Printf("sink mode: messages are recorded, not delivered (GET/DELETE /messages)")
+	}
 
 	log.Printf("push-service listen %s\n", addr)
 	if err := http.ListenAndServe(addr, mux); err != nil {
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type sinkMessage struct {
+	ID       string            `json:"id"`
+	Provider string            `json:"provider"`
+	Token    string            `json:"token"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+	SentAt   time.Time         `json:"sent_at"`
+}
+
+// sink is an offline stand-in for FCM and APNs: messages are kept in memory
+// (last max) and, when path is set, appended to a JSON Lines file. Tokens
+// starting with "invalid" are reported as invalid so token cleanup can be tested.
+type sink struct {
+	path string
+	max  int
+
+	mu   sync.Mutex
+	seq  int
+	msgs []sinkMessage
+}
+
+func newSink(path string) *sink {
+	return &sink{path: path, max: 1000}
+}
+
+// sinkTransport records the messages of one provider into a shared sink.
+type sinkTransport struct {
+	s        *sink
+	provider string
+}
+
+func (t sinkTransport) maxBatch() int { return fcmMaxBatch }
+
+func (t sinkTransport) sendBatch(ctx context.Context, tokens []string, n notification) []tokenResult {
+	results := make([]tokenResult, len(tokens))
+	for i, tok := range tokens {
+		if strings.HasPrefix(tok, "invalid") {
+			results[i] = tokenResult{Token: tok, Status: statusInvalid, Error: "sink: token not registered"}
+			continue
+		}
+		id, err := t.s.record(t.provider, tok, n)
+		if err != nil {
+			results[i] = tokenResult{Token: tok, Status: statusRetryable, Error: err.Error()}
+			continue
+		}
+		results[i] = tokenResult{Token: tok, Status: statusSuccess, MessageID: id}
+	}
+	return results
+}
+
+func (s *sink) record(provider, token string, n notification) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	m := sinkMessage{
+		ID:       fmt.Sprintf("sink-%d", s.seq),
+		Provider: provider,
+		Token:    token,
+		Title:    n.Title,
+		Body:     n.Body,
+		Data:     n.Data,
+		SentAt:   time.Now().UTC(),
+	}
+	if s.path != "" {
+		f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return "", err
+		}
+		err = json.NewEncoder(f).Encode(m)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	s.msgs = append(s.msgs, m)
+	if len(s.msgs) > s.max {
+		s.msgs = s.msgs[len(s.msgs)-s.max:]
+	}
+	log.Printf("sink %s: %s → %q", provider, m.ID, m.Title)
+	return m.ID, nil
+}
+
+// handleMessages lists recorded messages (GET) or clears them (DELETE).
+func (s *sink) handleMessages(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(append([]sinkMessage{}, s.msgs...))
+	case http.MethodDelete:
+		s.msgs = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}